@@ -3,10 +3,16 @@ package document
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 type Passage struct {
 	Text string `json:"text"`
+	// Score is the source's relevance score for the passage when it provides one, e.g. Exa's highlight scores
+	Score float64 `json:"score,omitempty"`
+	// Metadata locates the passage within its document when the loader knows more than the text, e.g. the symbol and
+	// line range of a chunk of source code
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type Document struct {
@@ -14,7 +20,9 @@ type Document struct {
 	Passages     []Passage     `json:"passages"`
 	Title        string        `json:"title"`
 	Corpus       Corpus        `json:"corpus"`
-	WebReference *WebReference `json:"webReference"` // Not present when Corpus is Personal
+	WebReference *WebReference `json:"webReference"` // Not present when Corpus is Personal, unless crawled from a site
+	// FileReference is the local file a Personal document was loaded from, when it came from one
+	FileReference *FileReference `json:"fileReference,omitempty"`
 }
 
 // WebReference represents where the document came from, so it can be referenced or cited later
@@ -28,6 +36,19 @@ type WebReference struct {
 	Favicon       string `json:"favicon"`
 	Thumbnail     string `json:"thumbnail"`
 	APISource     string
+	// PDF links to the document's full text as a PDF, e.g. for academic papers
+	PDF string `json:"pdf,omitempty"`
+	// ResultType is the kind of search result the document came from when the source returns more than one, e.g.
+	// SerpApi's "answer_box" or "organic"
+	ResultType string `json:"resultType,omitempty"`
+}
+
+// FileReference represents the local file a document was loaded from, so it can be opened or cited later
+type FileReference struct {
+	Path     string    `json:"path"`
+	Modified time.Time `json:"modified"`
+	// Format is the kind of file the document was read from, e.g. "markdown" or "pdf"
+	Format string `json:"format"`
 }
 
 // Corpus represents where the document came from
@@ -6,8 +6,12 @@ toolchain go1.22.1
 
 require (
 	github.com/anthropics/anthropic-sdk-go v0.2.0-alpha.4
+	github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/qdrant/go-client v1.8.0
 	github.com/sashabaranov/go-openai v1.24.0
+	golang.org/x/net v0.27.0
 )
 
 require (
@@ -15,7 +19,6 @@ require (
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
-	golang.org/x/net v0.27.0 // indirect
 	golang.org/x/sys v0.22.0 // indirect
 	golang.org/x/text v0.16.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240722135656-d784300faade // indirect
@@ -0,0 +1,498 @@
+package loaders
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"github.com/coopslarhette/raglib/lib/retrieval/robots"
+	"github.com/coopslarhette/raglib/lib/retrieval/urls"
+	"golang.org/x/net/html/charset"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCrawlMaxPages     = 1000
+	defaultCrawlConcurrency  = 4
+	defaultCrawlTimeout      = 10 * time.Second
+	defaultCrawlMaxBodyBytes = 5 << 20
+	// maxSitemaps bounds how many sitemaps are read through nested sitemap indexes
+	maxSitemaps = 100
+)
+
+// skippedExtensions are linked files that are never HTML pages, so they aren't requested at all
+var skippedExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".css": true, ".js": true, ".json": true, ".xml": true, ".pdf": true, ".zip": true, ".gz": true, ".tar": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".woff": true, ".woff2": true, ".ttf": true,
+}
+
+// errSkipped marks pages that were deliberately not loaded, e.g. because robots.txt disallows them, as opposed to
+// pages that failed
+var errSkipped = errors.New("page skipped")
+
+// CrawlConfig configures a Crawler. Zero values fall back to defaults.
+type CrawlConfig struct {
+	// UserAgent identifies the crawler to sites and is matched against robots.txt rules
+	UserAgent string
+	// AllowedDomains are the registrable domains the crawl stays within, e.g. "example.com", which includes
+	// subdomains like docs.example.com. Defaults to the domains of the start URLs.
+	AllowedDomains []string
+	// MaxPages caps how many pages are fetched, default 1000
+	MaxPages int
+	// MaxDepth is how many links away from the start pages the crawl goes, 0 for no limit
+	MaxDepth int
+	// NoFollow only loads the start pages and the pages listed by sitemaps, without following any links
+	NoFollow bool
+	// Sitemaps also starts from the sitemaps the start URLs' robots.txt files advertise
+	Sitemaps bool
+	// Concurrency is how many pages are fetched at once, default 4
+	Concurrency int
+	// Delay is the minimum time between requests to the same host. A longer Crawl-delay in robots.txt wins.
+	Delay time.Duration
+	// Timeout bounds fetching each page, default 10s
+	Timeout time.Duration
+	// MaxBodyBytes is how much of each page is read, default 5MB
+	MaxBodyBytes int64
+	// MaxPassageChars is the maximum size of each passage
+	MaxPassageChars int
+}
+
+func (c CrawlConfig) withDefaults() CrawlConfig {
+	if c.UserAgent == "" {
+		c.UserAgent = retrieval.DefaultUserAgent
+	}
+	if c.MaxPages <= 0 {
+		c.MaxPages = defaultCrawlMaxPages
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultCrawlConcurrency
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultCrawlTimeout
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = defaultCrawlMaxBodyBytes
+	}
+	if c.MaxPassageChars <= 0 {
+		c.MaxPassageChars = defaultMaxPassageChars
+	}
+	return c
+}
+
+// Crawler loads the pages of a website, e.g. a documentation site, as Personal documents. Pages are crawled breadth
+// first from start pages or sitemaps, within the allowed domains, following robots.txt rules and crawl delays. Pages
+// naming the same canonical URL are loaded once.
+type Crawler struct {
+	client *http.Client
+	robots *robots.Cache
+	config CrawlConfig
+
+	mu sync.Mutex
+	// nextRequest is the earliest time the next request may be sent to each host
+	nextRequest map[string]time.Time
+}
+
+// page is the outcome of fetching one page
+type page struct {
+	doc       *document.Document
+	canonical string
+	links     []string
+	err       error
+}
+
+// Crawl loads the pages reachable from start, which are page URLs or sitemap URLs ending in .xml or .xml.gz, in the
+// order they were crawled. Pages that fail to load are skipped and reported together in the returned error, along
+// with every page that did load.
+func (c *Crawler) Crawl(ctx context.Context, start ...string) ([]document.Document, error) {
+	domains := make(map[string]bool)
+	for _, d := range c.config.AllowedDomains {
+		domains[registrableDomain(d)] = true
+	}
+
+	var errs []error
+	var starts []*url.URL
+	for _, s := range start {
+		u, err := normalizeURL(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error parsing start url %s: %v", s, err))
+			continue
+		}
+		starts = append(starts, u)
+		if len(c.config.AllowedDomains) == 0 {
+			domains[registrableDomain(u.Host)] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var frontier []string
+	enqueue := func(link string) {
+		u, err := normalizeURL(link)
+		if err != nil || !domains[registrableDomain(u.Host)] || seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+		frontier = append(frontier, u.String())
+	}
+
+	var sitemaps []string
+	for _, u := range starts {
+		if isSitemapURL(u.Path) {
+			sitemaps = append(sitemaps, u.String())
+		} else {
+			enqueue(u.String())
+		}
+	}
+	if c.config.Sitemaps {
+		origins := make(map[string]bool)
+		for _, u := range starts {
+			origin := u.Scheme + "://" + u.Host
+			if origins[origin] {
+				continue
+			}
+			origins[origin] = true
+			if r, err := c.robots.Get(ctx, origin); err == nil {
+				sitemaps = append(sitemaps, r.Sitemaps...)
+			}
+		}
+	}
+	pages, err := c.sitemapPages(ctx, sitemaps, domains)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, p := range pages {
+		enqueue(p)
+	}
+
+	var docs []document.Document
+	canonicals := make(map[string]bool)
+	fetched := 0
+	for depth := 0; len(frontier) > 0 && fetched < c.config.MaxPages; depth++ {
+		if len(frontier) > c.config.MaxPages-fetched {
+			frontier = frontier[:c.config.MaxPages-fetched]
+		}
+		fetched += len(frontier)
+
+		results := make([]page, len(frontier))
+		sem := make(chan struct{}, c.config.Concurrency)
+		var wg sync.WaitGroup
+		for i, link := range frontier {
+			wg.Add(1)
+			go func(i int, link string) {
+				defer wg.Done()
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-ctx.Done():
+					return
+				}
+				results[i] = c.fetchPage(ctx, link, domains)
+			}(i, link)
+		}
+		wg.Wait()
+
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		follow := !c.config.NoFollow && (c.config.MaxDepth == 0 || depth < c.config.MaxDepth)
+		crawled := frontier
+		frontier = nil
+		for i, p := range results {
+			if p.err != nil {
+				if !errors.Is(p.err, errSkipped) {
+					errs = append(errs, fmt.Errorf("error crawling %s: %w", crawled[i], p.err))
+				}
+				continue
+			}
+
+			// A page reached through another URL, e.g. with tracking parameters, is only loaded once
+			seen[p.canonical] = true
+			if p.doc != nil && !canonicals[p.canonical] {
+				canonicals[p.canonical] = true
+				docs = append(docs, *p.doc)
+			}
+			if follow {
+				for _, link := range p.links {
+					enqueue(link)
+				}
+			}
+		}
+	}
+
+	return docs, errors.Join(errs...)
+}
+
+// fetchPage downloads and extracts the page at link
+func (c *Crawler) fetchPage(ctx context.Context, link string, domains map[string]bool) page {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	p := page{canonical: link}
+
+	r, err := c.robots.Get(ctx, link)
+	if err != nil {
+		p.err = fmt.Errorf("error checking robots.txt: %v", err)
+		return p
+	}
+	u, _ := url.Parse(link)
+	if !r.Allowed(c.config.UserAgent, u.RequestURI()) {
+		p.err = errSkipped
+		return p
+	}
+
+	body, final, err := c.get(ctx, link, r.CrawlDelay(c.config.UserAgent), "text/html,application/xhtml+xml")
+	if err != nil {
+		p.err = err
+		return p
+	}
+	// Redirects may leave the site
+	if !domains[registrableDomain(final.Host)] {
+		p.err = errSkipped
+		return p
+	}
+
+	article, err := extract.FromHTML(bytes.NewReader(body))
+	if err != nil {
+		p.err = err
+		return p
+	}
+
+	p.canonical = final.String()
+	if article.Canonical != "" {
+		if canonical, err := resolveLink(final, article.Canonical); err == nil && domains[registrableDomain(canonical.Host)] {
+			p.canonical = canonical.String()
+		}
+	}
+
+	robotsMeta := strings.ToLower(article.Robots)
+	if !strings.Contains(robotsMeta, "nofollow") && !strings.Contains(robotsMeta, "none") {
+		for _, href := range article.Links {
+			if target, err := resolveLink(final, href); err == nil && !skippedExtensions[strings.ToLower(path.Ext(target.Path))] {
+				p.links = append(p.links, target.String())
+			}
+		}
+	}
+	if strings.Contains(robotsMeta, "noindex") || strings.Contains(robotsMeta, "none") {
+		return p
+	}
+
+	passages := extract.Chunk(article.Sections, c.config.MaxPassageChars)
+	if len(passages) == 0 {
+		return p
+	}
+
+	title := article.Title
+	if title == "" {
+		title = final.Path
+	}
+	canonical, _ := url.Parse(p.canonical)
+	p.doc = &document.Document{
+		Passages: passages,
+		Title:    title,
+		Corpus:   document.Personal,
+		WebReference: &document.WebReference{
+			Title:         title,
+			Link:          p.canonical,
+			DisplayedLink: canonical.Host + canonical.Path,
+			Blurb:         article.Description,
+			Date:          article.Published,
+			Author:        article.Author,
+			APISource:     "crawler",
+		},
+	}
+	return p
+}
+
+// sitemapPages reads the pages listed by sitemaps, following sitemap indexes. Like pages, sitemaps outside the
+// allowed domains or disallowed by robots.txt are skipped.
+func (c *Crawler) sitemapPages(ctx context.Context, sitemaps []string, domains map[string]bool) ([]string, error) {
+	var pages []string
+	var errs []error
+	read := make(map[string]bool)
+	for len(sitemaps) > 0 && len(read) < maxSitemaps {
+		link := sitemaps[0]
+		sitemaps = sitemaps[1:]
+		if read[link] {
+			continue
+		}
+		read[link] = true
+
+		u, err := url.Parse(link)
+		if err != nil || !domains[registrableDomain(u.Host)] {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+		r, err := c.robots.Get(ctx, link)
+		if err != nil {
+			cancel()
+			errs = append(errs, fmt.Errorf("error checking robots.txt for sitemap %s: %v", link, err))
+			continue
+		}
+		if !r.Allowed(c.config.UserAgent, u.RequestURI()) {
+			cancel()
+			continue
+		}
+		body, _, err := c.get(ctx, link, r.CrawlDelay(c.config.UserAgent), "application/xml,text/xml")
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reading sitemap %s: %w", link, err))
+			continue
+		}
+
+		listed, nested, err := parseSitemap(body, c.config.MaxBodyBytes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reading sitemap %s: %w", link, err))
+			continue
+		}
+		pages = append(pages, listed...)
+		sitemaps = append(sitemaps, nested...)
+	}
+	return pages, errors.Join(errs...)
+}
+
+// get downloads link once the host's crawl delay has passed, returning the decoded body and the URL it was finally
+// served from after redirects
+func (c *Crawler) get(ctx context.Context, link string, crawlDelay time.Duration, accept string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error constructing request: %v", err)
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	req.Header.Set("Accept", accept)
+
+	if err = c.wait(ctx, req.URL.Host, max(c.config.Delay, crawlDelay)); err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while fetching: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("response status code is not OK; received code: %v", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(accept, "text/html") {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+			mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return nil, nil, errSkipped
+		}
+	}
+
+	body := io.LimitReader(resp.Body, c.config.MaxBodyBytes)
+	if strings.HasPrefix(contentType, "text/html") {
+		if body, err = charset.NewReader(body, contentType); err != nil {
+			return nil, nil, fmt.Errorf("error decoding page: %v", err)
+		}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading response: %v", err)
+	}
+	return data, resp.Request.URL, nil
+}
+
+// wait blocks until a request may be sent to host, spacing requests to the same host at least delay apart
+func (c *Crawler) wait(ctx context.Context, host string, delay time.Duration) error {
+	c.mu.Lock()
+	at := time.Now()
+	if next := c.nextRequest[host]; next.After(at) {
+		at = next
+	}
+	c.nextRequest[host] = at.Add(delay)
+	c.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NewCrawler creates a Crawler that fetches pages with client
+func NewCrawler(client *http.Client, config CrawlConfig) *Crawler {
+	config = config.withDefaults()
+	return &Crawler{
+		client:      client,
+		robots:      robots.NewCache(client, config.UserAgent, time.Hour),
+		config:      config,
+		nextRequest: make(map[string]time.Time),
+	}
+}
+
+// normalizeURL parses an absolute http(s) URL into the form used to recognize pages already seen: without its
+// fragment, with a lowercase host, without a default port and with at least a / path
+func normalizeURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("url has no host")
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u, nil
+}
+
+// resolveLink resolves href, as written on the page at base, into a normalized absolute URL
+func resolveLink(base *url.URL, href string) (*url.URL, error) {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return nil, err
+	}
+	return normalizeURL(base.ResolveReference(ref).String())
+}
+
+// registrableDomain returns the domain a host belongs to, e.g. example.com for docs.example.com, falling back to the
+// host itself for names urls.Parse can't split, like localhost. IP addresses have no registrable domain, so they're
+// returned exactly, without any port.
+func registrableDomain(host string) string {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if ip := net.ParseIP(strings.Trim(hostname, "[]")); ip != nil {
+		return ip.String()
+	}
+
+	if parts, err := urls.Parse(host); err == nil {
+		return strings.ToLower(parts.FullDomain())
+	}
+	return strings.ToLower(host)
+}
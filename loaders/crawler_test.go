@@ -0,0 +1,207 @@
+package loaders
+
+import (
+	"compress/gzip"
+	"context"
+	"github.com/coopslarhette/raglib/lib/document"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"HTTPS://Example.COM:443#top", "https://example.com/"},
+		{"http://example.com:80/a?b=1#c", "http://example.com/a?b=1"},
+		{"http://example.com:8080/a", "http://example.com:8080/a"},
+	}
+	for _, tt := range tests {
+		got, err := normalizeURL(tt.in)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("normalizeURL(%q) = %v, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"mailto:a@example.com", "/relative", "javascript:void(0)"} {
+		if _, err := normalizeURL(in); err == nil {
+			t.Errorf("normalizeURL(%q) error = nil, want an error", in)
+		}
+	}
+}
+
+func TestParseSitemap(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc> https://example.com/a </loc><lastmod>2024-01-01</lastmod></url>
+	<url><loc>https://example.com/b</loc></url>
+</urlset>`
+
+	var gzipped strings.Builder
+	w := gzip.NewWriter(&gzipped)
+	w.Write([]byte(data))
+	w.Close()
+
+	for name, in := range map[string]string{"plain": data, "gzipped": gzipped.String()} {
+		pages, sitemaps, err := parseSitemap([]byte(in), 1<<20)
+		if err != nil {
+			t.Fatalf("parseSitemap(%s) error = %v", name, err)
+		}
+		if want := []string{"https://example.com/a", "https://example.com/b"}; !reflect.DeepEqual(pages, want) || sitemaps != nil {
+			t.Errorf("parseSitemap(%s) = %q, %q, want %q", name, pages, sitemaps, want)
+		}
+	}
+
+	if _, _, err := parseSitemap([]byte("<html></html>"), 1<<20); err == nil {
+		t.Errorf("parseSitemap() of an HTML page error = nil, want an error")
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"docs.example.com":     "example.com",
+		"Docs.Example.com:443": "example.com",
+		"localhost":            "localhost",
+		"127.0.0.1:8080":       "127.0.0.1",
+		"10.20.0.1":            "10.20.0.1",
+		"[::1]:8080":           "::1",
+	}
+	for host, want := range tests {
+		if got := registrableDomain(host); got != want {
+			t.Errorf("registrableDomain(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestCrawler_CrawlStaysOnIP(t *testing.T) {
+	// 127.1.0.1 shares its last two octets with 127.0.0.1, which a domain suffix split would treat as the same domain
+	listener, err := net.Listen("tcp", "127.1.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen on a second loopback address: %v", err)
+	}
+	var otherRequests atomic.Int32
+	other := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherRequests.Add(1)
+		w.Write([]byte("<html><body><article><p>Another machine on the network.</p></article></body></html>"))
+	}))
+	other.Listener.Close()
+	other.Listener = listener
+	other.Start()
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<html><head><title>Start</title></head><body><article><p>The start page of the crawl.</p>` +
+			`<a href="` + other.URL + `/page">Other</a></article></body></html>`))
+	}))
+	defer server.Close()
+
+	docs, err := NewCrawler(server.Client(), CrawlConfig{}).Crawl(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	if len(docs) != 1 || docs[0].Title != "Start" {
+		t.Errorf("Crawl() = %+v, want only the start page", docs)
+	}
+	if n := otherRequests.Load(); n != 0 {
+		t.Errorf("Crawl() sent %d requests to %s, want it to stay on %s", n, other.URL, server.URL)
+	}
+}
+
+func TestCrawler_Crawl(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	page := func(head, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><head>" + head + "</head><body><article>" + body + "</article></body></html>"))
+		}
+	}
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n\nSitemap: " + server.URL + "/sitemap.xml\n"))
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		// Sitemaps on other domains or disallowed by robots.txt are skipped like pages
+		w.Write([]byte(`<sitemapindex><sitemap><loc>` + server.URL + `/sitemap-docs.xml</loc></sitemap>` +
+			`<sitemap><loc>` + server.URL + `/private/sitemap.xml</loc></sitemap>` +
+			`<sitemap><loc>https://other.invalid/sitemap.xml</loc></sitemap></sitemapindex>`))
+	})
+	mux.HandleFunc("/sitemap-docs.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<urlset><url><loc>` + server.URL + `/docs/a</loc></url><url><loc>` + server.URL + `/docs/b</loc></url></urlset>`))
+	})
+	mux.HandleFunc("/docs/a", page(
+		`<title>Install</title><meta name="description" content="How to install">`,
+		`<h2>Setup</h2><p>Run the installer and follow the prompts until it finishes.</p>`+
+			`<a href="b">B</a> <a href="/docs/a#top">Top</a> <a href="/docs/c?ref=nav">C</a>`+
+			`<a href="/private/keys">Keys</a> <a href="https://other.org/docs">Other</a>`+
+			`<a href="mailto:help@example.com">Mail</a> <a href="/logo.png">Logo</a>`,
+	))
+	mux.HandleFunc("/docs/b", page(
+		`<title>Index</title><meta name="robots" content="noindex">`,
+		`<p>A listing page that links onward to the configuration guide.</p><a href="/docs/d">D</a>`,
+	))
+	mux.HandleFunc("/docs/c", page(
+		`<title>Install copy</title><link rel="canonical" href="/docs/a">`,
+		`<p>The same install page served under another URL.</p>`,
+	))
+	mux.HandleFunc("/docs/d", page(
+		`<title>Configure</title>`,
+		`<p>Edit the config file to change the listening port.</p><a href="/missing">Missing</a>`,
+	))
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	crawler := NewCrawler(server.Client(), CrawlConfig{Sitemaps: true})
+	docs, err := crawler.Crawl(context.Background(), server.URL+"/docs/a")
+	if err == nil || !strings.Contains(err.Error(), "/missing") {
+		t.Errorf("Crawl() error = %v, want the missing page reported", err)
+	}
+
+	var got []string
+	for _, d := range docs {
+		if d.WebReference == nil || d.WebReference.APISource != "crawler" {
+			t.Fatalf("Crawl() document = %+v, want a web reference", d)
+		}
+		got = append(got, d.Title+" "+strings.TrimPrefix(d.WebReference.Link, server.URL))
+	}
+	if want := []string{"Install /docs/a", "Configure /docs/d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Crawl() = %q, want %q", got, want)
+	}
+	if docs[0].WebReference.Blurb != "How to install" || docs[0].Corpus != document.Personal {
+		t.Errorf("Crawl() first document = %+v, want the description as the blurb", docs[0])
+	}
+
+	for _, path := range requested {
+		if strings.HasPrefix(path, "/private") || path == "/logo.png" {
+			t.Errorf("Crawl() requested %s, want it skipped", path)
+		}
+	}
+
+	// Without following links only the start page and sitemap pages load
+	crawler = NewCrawler(server.Client(), CrawlConfig{NoFollow: true})
+	docs, err = crawler.Crawl(context.Background(), server.URL+"/sitemap.xml")
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	if len(docs) != 1 || docs[0].Title != "Install" {
+		t.Errorf("Crawl() with NoFollow = %+v, want only the indexable sitemap page", docs)
+	}
+}
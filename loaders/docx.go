@@ -0,0 +1,180 @@
+package loaders
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// wordNamespace is the namespace of the WordprocessingML elements in word/document.xml
+const wordNamespace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// DOCX loads a Word document as one document with a section per heading. Headings are paragraphs styled "Heading 1"
+// through "Heading 6", and the title comes from the document's properties or else a paragraph styled "Title".
+func DOCX(f File, maxPassageChars int) ([]document.Document, error) {
+	archive, err := zip.NewReader(bytes.NewReader(f.Data), int64(len(f.Data)))
+	if err != nil {
+		return nil, fmt.Errorf("error opening DOCX: %v", err)
+	}
+
+	body, err := readZipFile(archive, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	paragraphs, err := docxParagraphs(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var title string
+	if core, err := readZipFile(archive, "docProps/core.xml"); err == nil {
+		title = docxTitle(core)
+	}
+
+	var sections []extract.Section
+	current := extract.Section{}
+	var texts []string
+	flush := func() {
+		current.Text = strings.Join(texts, "\n\n")
+		if current.Heading != "" || current.Text != "" {
+			sections = append(sections, current)
+		}
+		texts = nil
+	}
+
+	for _, p := range paragraphs {
+		switch {
+		case p.style == "title":
+			if title == "" {
+				title = p.text
+			}
+		case p.level > 0:
+			flush()
+			current = extract.Section{Heading: p.text, Level: p.level}
+		default:
+			texts = append(texts, p.text)
+		}
+	}
+	flush()
+
+	return []document.Document{newDocument(title, sections, maxPassageChars)}, nil
+}
+
+type docxParagraph struct {
+	text  string
+	style string
+	// level is the heading level, 0 when the paragraph isn't a heading
+	level int
+}
+
+// docxParagraphs reads the non-empty paragraphs of word/document.xml in order, including those in tables
+func docxParagraphs(data []byte) ([]docxParagraph, error) {
+	var paragraphs []docxParagraph
+	var current *docxParagraph
+	var text strings.Builder
+	inText := false
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing DOCX: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space != wordNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "p":
+				current = &docxParagraph{}
+				text.Reset()
+			case "pStyle":
+				if current != nil {
+					current.style, current.level = docxStyle(attrValue(t, "val"))
+				}
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteByte('\t')
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if t.Name.Space != wordNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if current != nil {
+					if current.text = strings.TrimSpace(text.String()); current.text != "" {
+						paragraphs = append(paragraphs, *current)
+					}
+				}
+				current = nil
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+	return paragraphs, nil
+}
+
+// docxStyle normalizes a paragraph style ID like "Heading2" or "heading 2", returning the heading level for headings
+func docxStyle(id string) (style string, level int) {
+	style = strings.ToLower(strings.ReplaceAll(id, " ", ""))
+	if n, ok := strings.CutPrefix(style, "heading"); ok {
+		if level, err := strconv.Atoi(n); err == nil && level >= 1 && level <= 6 {
+			return style, level
+		}
+	}
+	return style, 0
+}
+
+// docxTitle reads the dc:title of docProps/core.xml
+func docxTitle(data []byte) string {
+	var core struct {
+		Title string `xml:"http://purl.org/dc/elements/1.1/ title"`
+	}
+	if xml.Unmarshal(data, &core) != nil {
+		return ""
+	}
+	return strings.TrimSpace(core.Title)
+}
+
+func attrValue(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s in DOCX: %v", name, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s in DOCX: %v", name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,356 @@
+package loaders
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"golang.org/x/net/html/charset"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// wordDecoder decodes RFC 2047 encoded header words like =?ISO-8859-1?Q?Ren=E9?= in any charset
+	wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+	// messageIDPattern matches the <id@host> message IDs of Message-ID, In-Reply-To and References headers
+	messageIDPattern = regexp.MustCompile(`<[^<>\s]+>`)
+	// replyPrefixPattern matches the reply and forward prefixes of a subject, like "Re: " or "[list] Fwd: "
+	replyPrefixPattern = regexp.MustCompile(`(?i)^\s*((re|fwd?|aw|sv|wg)(\[\d+\])?\s*:|\[[^\]]*\])\s*`)
+	// mboxFromPattern matches body lines starting with "From " that the mbox format escaped with >
+	mboxFromPattern = regexp.MustCompile(`^>+From `)
+)
+
+// email is a parsed email message
+type email struct {
+	id         string
+	references []string
+	subject    string
+	message
+}
+
+// EML loads a single email message as one document titled by its subject, with its quoted replies and signature
+// stripped
+func EML(f File, maxPassageChars int) ([]document.Document, error) {
+	e, err := parseEmail(f.Data)
+	if err != nil {
+		return nil, err
+	}
+	return []document.Document{newThread(threadSubject(e.subject), []message{e.message}, maxPassageChars)}, nil
+}
+
+// Mbox loads a mailbox file as one document per thread, in the order each thread started. Messages are threaded by
+// their Message-ID, In-Reply-To and References headers, falling back to the subject for replies without them, and
+// ordered by date within their thread. Quoted replies and signatures are stripped, so each message only adds what its
+// author wrote. Passages hold whole messages where they fit. Messages that fail to parse are skipped and reported
+// together in the returned error, along with the threads of every other message.
+func Mbox(f File, maxPassageChars int) ([]document.Document, error) {
+	var emails []*email
+	var errs []error
+	for i, raw := range splitMbox(f.Data) {
+		e, err := parseEmail(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error parsing message %d: %v", i+1, err))
+			continue
+		}
+		emails = append(emails, e)
+	}
+
+	var docs []document.Document
+	for _, thread := range threadEmails(emails) {
+		messages := make([]message, len(thread))
+		for i, e := range thread {
+			messages[i] = e.message
+		}
+		docs = append(docs, newThread(threadSubject(thread[0].subject), messages, maxPassageChars))
+	}
+	return docs, errors.Join(errs...)
+}
+
+// splitMbox splits a mailbox into its messages, which each start with a "From " line, unescaping body lines that
+// start with >From
+func splitMbox(data []byte) [][]byte {
+	var messages [][]byte
+	var current []byte
+	inMessage, blank := false, true
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// A From line only starts a message at the start of the file or after a blank line
+		if blank && bytes.HasPrefix(line, []byte("From ")) {
+			if inMessage {
+				messages = append(messages, current)
+			}
+			current, inMessage = nil, true
+			continue
+		}
+		blank = len(bytes.TrimRight(line, "\r")) == 0
+
+		if !inMessage {
+			continue
+		}
+		if mboxFromPattern.Match(line) {
+			line = line[1:]
+		}
+		current = append(current, line...)
+		current = append(current, '\n')
+	}
+	if inMessage {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// parseEmail reads a message's headers and text body, without quoted replies and signatures
+func parseEmail(data []byte) (*email, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing email: %v", err)
+	}
+	h := msg.Header
+
+	e := &email{subject: decodeHeader(h.Get("Subject"))}
+	if ids := messageIDPattern.FindAllString(h.Get("Message-ID"), 1); len(ids) > 0 {
+		e.id = ids[0]
+	}
+	// In-Reply-To comes last, as References lists the thread from its root
+	e.references = messageIDPattern.FindAllString(h.Get("References")+" "+h.Get("In-Reply-To"), -1)
+
+	from := h.Get("From")
+	e.author = decodeHeader(from)
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	if addresses, err := parser.ParseList(from); err == nil && len(addresses) > 0 {
+		if e.author = addresses[0].Name; e.author == "" {
+			e.author = addresses[0].Address
+		}
+	}
+	e.date, _ = h.Date()
+
+	text, _, err := emailBody(h.Get("Content-Type"), h.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	e.text = stripReply(text)
+	return e, nil
+}
+
+// emailBody reads the text of a message or MIME part, preferring plain text to HTML and skipping attachments
+func emailBody(contentType, encoding string, body io.Reader) (text string, isHTML bool, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		var plain, html []string
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", false, fmt.Errorf("error reading MIME part: %v", err)
+			}
+			if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+				continue
+			}
+
+			text, isHTML, err := emailBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", false, err
+			}
+			if text = strings.TrimSpace(text); text == "" {
+				continue
+			}
+			if isHTML {
+				html = append(html, text)
+			} else {
+				plain = append(plain, text)
+			}
+		}
+		if len(plain) > 0 {
+			return strings.Join(plain, "\n\n"), false, nil
+		}
+		return strings.Join(html, "\n\n"), len(html) > 0, nil
+	case mediaType == "text/plain" || mediaType == "text/html":
+		if label := params["charset"]; label != "" && !strings.EqualFold(label, "utf-8") {
+			if body, err = charset.NewReaderLabel(label, body); err != nil {
+				return "", false, fmt.Errorf("error decoding %s text: %v", label, err)
+			}
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", false, fmt.Errorf("error reading email body: %v", err)
+		}
+		if mediaType == "text/plain" {
+			return normalizeText(data), false, nil
+		}
+		article, err := extract.FromHTML(bytes.NewReader(data))
+		if err != nil {
+			return "", false, err
+		}
+		return article.Text(), true, nil
+	}
+	return "", false, nil
+}
+
+// stripReply removes the quoted text of earlier messages and the signature from a message's text. Quotes are lines
+// starting with > along with the "On ... wrote:" line introducing them, and everything after an Outlook style
+// "Original Message" separator or From:/Sent: header block. The signature is everything after a "-- " line.
+func stripReply(text string) string {
+	lines := strings.Split(text, "\n")
+	var kept []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "--" || strings.HasPrefix(line, "-----Original Message-----") || strings.HasPrefix(line, "________________") {
+			break
+		}
+		if strings.HasPrefix(line, "From:") && hasHeaderLine(lines[i+1:min(i+4, len(lines))], "Sent:", "Date:") {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		if strings.HasSuffix(line, "wrote:") && nextQuoted(lines[i+1:]) {
+			// Attributions are often wrapped, e.g. "On Mon, Ana <\nana@example.com> wrote:"
+			if n := len(kept); n > 0 && strings.HasPrefix(strings.TrimSpace(kept[n-1]), "On ") && !strings.HasPrefix(line, "On ") {
+				kept = kept[:n-1]
+			}
+			continue
+		}
+		kept = append(kept, lines[i])
+	}
+
+	// Mobile clients sign every message
+	for len(kept) > 0 {
+		last := strings.TrimSpace(kept[len(kept)-1])
+		if last != "" && !strings.HasPrefix(last, "Sent from my ") && !strings.HasPrefix(last, "Get Outlook for ") {
+			break
+		}
+		kept = kept[:len(kept)-1]
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+func hasHeaderLine(lines []string, prefixes ...string) bool {
+	for _, line := range lines {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextQuoted reports whether the first non-empty line of lines is quoted
+func nextQuoted(lines []string) bool {
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			return strings.HasPrefix(line, ">")
+		}
+	}
+	return false
+}
+
+// threadEmails groups emails into threads, ordered by when they started, each ordered by date
+func threadEmails(emails []*email) [][]*email {
+	parent := make(map[string]string)
+	var find func(key string) string
+	find = func(key string) string {
+		p, ok := parent[key]
+		if !ok || p == key {
+			parent[key] = key
+			return key
+		}
+		root := find(p)
+		parent[key] = root
+		return root
+	}
+	union := func(a, b string) {
+		if ra, rb := find(a), find(b); ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	keys := make([]string, len(emails))
+	subjects := make(map[string]string)
+	for i, e := range emails {
+		// Messages without a Message-ID can still be threaded by their references
+		if keys[i] = e.id; keys[i] == "" {
+			keys[i] = "#" + strconv.Itoa(i)
+		}
+		find(keys[i])
+		for _, ref := range e.references {
+			union(keys[i], ref)
+		}
+
+		subject := strings.ToLower(threadSubject(e.subject))
+		if first, ok := subjects[subject]; ok && len(e.references) == 0 && threadSubject(e.subject) != strings.TrimSpace(e.subject) {
+			union(first, keys[i])
+		} else if !ok && subject != "" {
+			subjects[subject] = keys[i]
+		}
+	}
+
+	byRoot := make(map[string][]*email)
+	var roots []string
+	for i, e := range emails {
+		root := find(keys[i])
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], e)
+	}
+
+	threads := make([][]*email, len(roots))
+	for i, root := range roots {
+		thread := byRoot[root]
+		sort.SliceStable(thread, func(a, b int) bool { return thread[a].date.Before(thread[b].date) })
+		threads[i] = thread
+	}
+	sort.SliceStable(threads, func(a, b int) bool { return threads[a][0].date.Before(threads[b][0].date) })
+	return threads
+}
+
+// threadSubject removes the reply and forward prefixes from a subject
+func threadSubject(subject string) string {
+	subject = strings.TrimSpace(subject)
+	for {
+		trimmed := replyPrefixPattern.ReplaceAllString(subject, "")
+		if trimmed == subject || trimmed == "" {
+			return subject
+		}
+		subject = trimmed
+	}
+}
+
+// decodeHeader decodes the RFC 2047 encoded words of a header value
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
@@ -0,0 +1,196 @@
+package loaders
+
+import (
+	"context"
+	"github.com/coopslarhette/raglib/lib/document"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mbox = `From ana@example.com Mon Mar  4 09:30:00 2024
+Message-ID: <1@example.com>
+From: Ana Lopez <ana@example.com>
+Subject: Deploy window
+Date: Mon, 04 Mar 2024 09:30:00 +0000
+Content-Type: text/plain; charset=utf-8
+
+Can we deploy on Thursday?
+>From what I saw the queue is empty.
+
+--
+Ana Lopez | Platform
+
+From bo@example.com Mon Mar  4 10:00:00 2024
+Message-ID: <2@example.com>
+In-Reply-To: <1@example.com>
+References: <1@example.com>
+From: =?ISO-8859-1?Q?Bo_J=F6rg?= <bo@example.com>
+Subject: RE: Deploy window
+Date: Mon, 04 Mar 2024 10:00:00 +0000
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+Thursday works, after the freeze ends.
+
+On Mon, Mar 4, 2024 at 9:30 AM Ana Lopez <
+ana@example.com> wrote:
+> Can we deploy on Thursday?
+
+Sent from my iPhone
+--b1
+Content-Type: text/html
+
+<p>Thursday works, after the freeze ends.</p>
+--b1--
+
+From cy@example.com Tue Mar  5 08:00:00 2024
+Message-ID: <3@example.com>
+From: cy@example.com
+Subject: Lunch
+Date: Tue, 05 Mar 2024 08:00:00 +0000
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: base64
+
+PHA+VGFjb3MgYXQgbm9vbj88L3A+
+
+From ana@example.com Tue Mar  5 11:00:00 2024
+Message-ID: <4@example.com>
+From: Ana Lopez <ana@example.com>
+Subject: Re: Deploy window
+Date: Tue, 05 Mar 2024 11:00:00 +0000
+
+Done, it's out.
+
+-----Original Message-----
+From: Bo
+Thursday works.
+`
+
+func TestMbox(t *testing.T) {
+	docs, err := Mbox(File{Data: []byte(mbox)}, 0)
+	if err != nil {
+		t.Fatalf("Mbox() error = %v", err)
+	}
+
+	type thread struct {
+		title, text, authors, date string
+	}
+	var got []thread
+	for _, d := range docs {
+		for _, p := range d.Passages {
+			got = append(got, thread{d.Title, p.Text, p.Metadata[MetadataAuthors], p.Metadata[MetadataDate]})
+		}
+	}
+	want := []thread{
+		{
+			title: "Deploy window",
+			text: "Ana Lopez (2024-03-04 09:30):\n\nCan we deploy on Thursday?\nFrom what I saw the queue is empty.\n\n" +
+				"Bo Jörg (2024-03-04 10:00):\n\nThursday works, after the freeze ends.\n\n" +
+				"Ana Lopez (2024-03-05 11:00):\n\nDone, it's out.",
+			authors: "Ana Lopez, Bo Jörg",
+			date:    "2024-03-04T09:30:00Z",
+		},
+		{title: "Lunch", text: "cy@example.com (2024-03-05 08:00):\n\nTacos at noon?", authors: "cy@example.com", date: "2024-03-05T08:00:00Z"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Mbox() =\n%q\nwant\n%q", got, want)
+	}
+
+	// Small passages split the thread between messages
+	docs, err = Mbox(File{Data: []byte(mbox)}, 100)
+	if err != nil {
+		t.Fatalf("Mbox() error = %v", err)
+	}
+	var dates []string
+	for _, p := range docs[0].Passages {
+		dates = append(dates, p.Metadata[MetadataDate])
+	}
+	if want := []string{"2024-03-04T09:30:00Z", "2024-03-04T10:00:00Z", "2024-03-05T11:00:00Z"}; !reflect.DeepEqual(dates, want) {
+		t.Errorf("Mbox() passage dates = %q, want %q", dates, want)
+	}
+
+	// A corrupt message is reported without losing the rest of the mailbox
+	corrupt := "From nobody Mon Mar  4 08:00:00 2024\nthis is not a header\n\nbody\n\n" + mbox
+	docs, err = Mbox(File{Data: []byte(corrupt)}, 0)
+	if err == nil || !strings.Contains(err.Error(), "message 1") {
+		t.Errorf("Mbox() error = %v, want the corrupt message reported", err)
+	}
+	if len(docs) != 2 {
+		t.Errorf("Mbox() with a corrupt message returned %d threads, want 2", len(docs))
+	}
+}
+
+func TestThreadSubject(t *testing.T) {
+	for in, want := range map[string]string{
+		"Re: RE: Fwd: Deploy window": "Deploy window",
+		"[ops] Re[2]: Outage":        "Outage",
+		"Re:":                        "Re:",
+		"Regarding the plan":         "Regarding the plan",
+	} {
+		if got := threadSubject(in); got != want {
+			t.Errorf("threadSubject(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoader_LoadSlackExport(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"users.json":    `[{"id": "U1", "name": "ana", "profile": {"real_name": "Ana Lopez"}}, {"id": "U2", "name": "bo"}]`,
+		"channels.json": `[{"id": "C1", "name": "ops"}, {"id": "C2", "name": "general"}]`,
+		"ops/2024-03-04.json": `[
+			{"type": "message", "subtype": "channel_join", "user": "U2", "text": "<@U2> has joined the channel", "ts": "1709544000.000100"},
+			{"type": "message", "user": "U1", "text": "Deploy is blocked, see <#C2> &amp; <https://ci.example.com/1|the build>", "ts": "1709544600.000200", "thread_ts": "1709544600.000200", "reply_count": 1},
+			{"type": "message", "user": "U2", "text": "Morning <!here>", "ts": "1709550000.000300"}
+		]`,
+		"ops/2024-03-05.json": `[
+			{"type": "message", "user": "U2", "text": "Fixed it, <@U1>", "ts": "1709629200.000400", "thread_ts": "1709544600.000200"},
+			{"type": "message", "bot_id": "B1", "username": "deploybot", "text": "", "ts": "1709630000.000500", "files": [{"name": "log.txt"}]}
+		]`,
+	})
+
+	docs, err := NewLoader(Config{}).LoadSlackExport(context.Background(), root)
+	if err != nil {
+		t.Fatalf("LoadSlackExport() error = %v", err)
+	}
+
+	var got []string
+	for _, d := range docs {
+		if d.Corpus != document.Personal || d.FileReference == nil || d.FileReference.Path != root || d.FileReference.Format != "slack" {
+			t.Fatalf("LoadSlackExport() document = %+v, want a personal document referencing the export", d)
+		}
+		got = append(got, d.Title+" | "+d.Passages[0].Metadata[MetadataAuthors]+" | "+d.Passages[0].Text)
+	}
+	want := []string{
+		"#ops: Deploy is blocked, see #general & the build (https://ci.example.com/1) | Ana Lopez, bo | " +
+			"Ana Lopez (2024-03-04 09:30):\n\nDeploy is blocked, see #general & the build (https://ci.example.com/1)\n\n" +
+			"bo (2024-03-05 09:00):\n\nFixed it, @Ana Lopez",
+		"#ops 2024-03-04 | bo | bo (2024-03-04 11:00):\n\nMorning @here",
+		"#ops 2024-03-05 | deploybot | deploybot (2024-03-05 09:13):\n\n[file: log.txt]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadSlackExport() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestLoader_LoadFile_EML(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "note.eml")
+	eml := "From: Ana <ana@example.com>\r\nSubject: Fwd: Budget\r\nDate: Mon, 04 Mar 2024 09:30:00 +0000\r\n\r\nApproved.\r\n"
+	if err := os.WriteFile(path, []byte(eml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := NewLoader(Config{}).LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if docs[0].Title != "Budget" || !strings.HasSuffix(docs[0].Passages[0].Text, "Approved.") || docs[0].FileReference.Format != "eml" {
+		t.Errorf("LoadFile() = %+v, want the email without its forward prefix", docs[0])
+	}
+}
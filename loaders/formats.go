@@ -0,0 +1,162 @@
+package loaders
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"sort"
+	"strings"
+)
+
+// jsonlTitleFields and jsonlTextFields are the fields of a JSONL record tried, in order, for its title and text
+var (
+	jsonlTitleFields = []string{"title", "name", "subject"}
+	jsonlTextFields  = []string{"text", "content", "body"}
+)
+
+// Text loads a plain text file as one document, with its blank line separated paragraphs packed into passages
+func Text(f File, maxPassageChars int) ([]document.Document, error) {
+	text := normalizeText(f.Data)
+	return []document.Document{newDocument("", []extract.Section{{Text: text}}, maxPassageChars)}, nil
+}
+
+// HTML loads the main content of an HTML page as one document, with a section per heading
+func HTML(f File, maxPassageChars int) ([]document.Document, error) {
+	article, err := extract.FromHTML(bytes.NewReader(f.Data))
+	if err != nil {
+		return nil, err
+	}
+	return []document.Document{newDocument(article.Title, article.Sections, maxPassageChars)}, nil
+}
+
+// PDF loads the text layer of a PDF as one document, with a section per page
+func PDF(f File, maxPassageChars int) ([]document.Document, error) {
+	article, err := extract.FromPDF(bytes.NewReader(f.Data), int64(len(f.Data)))
+	if err != nil {
+		return nil, err
+	}
+	return []document.Document{newDocument(article.Title, article.Sections, maxPassageChars)}, nil
+}
+
+// CSV loads a CSV file with a header row as one document. Each row becomes a paragraph of "column: value" lines, so
+// passages hold whole rows and every value keeps its column name.
+func CSV(f File, maxPassageChars int) ([]document.Document, error) {
+	reader := csv.NewReader(strings.NewReader(normalizeText(f.Data)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		var lines []string
+		for i, value := range record {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			column := fmt.Sprintf("column %d", i+1)
+			if i < len(header) && header[i] != "" {
+				column = header[i]
+			}
+			lines = append(lines, column+": "+value)
+		}
+		if len(lines) > 0 {
+			rows = append(rows, strings.Join(lines, "\n"))
+		}
+	}
+
+	text := strings.Join(rows, "\n\n")
+	return []document.Document{newDocument("", []extract.Section{{Text: text}}, maxPassageChars)}, nil
+}
+
+// JSONL loads a file of JSON objects, one per line, as one document per object. The title comes from the object's
+// title, name or subject field and the text from its text, content or body field. Objects with none of the text
+// fields are written out as "key: value" lines of their other scalar fields. Lines that aren't valid JSON are reported
+// in the error while the rest are still loaded.
+func JSONL(f File, maxPassageChars int) ([]document.Document, error) {
+	var docs []document.Document
+	var errs []error
+
+	scanner := bufio.NewScanner(bytes.NewReader(f.Data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(f.Data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var record map[string]any
+		if err := json.Unmarshal(data, &record); err != nil {
+			errs = append(errs, fmt.Errorf("error parsing JSONL line %d: %v", line, err))
+			continue
+		}
+
+		title := firstField(record, jsonlTitleFields)
+		text := firstField(record, jsonlTextFields)
+		if text == "" {
+			text = scalarFields(record)
+		}
+		docs = append(docs, newDocument(title, []extract.Section{{Text: text}}, maxPassageChars))
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("error reading JSONL: %v", err))
+	}
+	return docs, errors.Join(errs...)
+}
+
+func firstField(record map[string]any, fields []string) string {
+	for _, field := range fields {
+		if s, ok := record[field].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// scalarFields writes the string, number and boolean fields of record as "key: value" lines in key order
+func scalarFields(record map[string]any) string {
+	keys := make([]string, 0, len(record))
+	for k := range record {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		switch v := record[k].(type) {
+		case string:
+			if v != "" {
+				lines = append(lines, k+": "+v)
+			}
+		case float64, bool:
+			lines = append(lines, fmt.Sprintf("%s: %v", k, v))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newDocument(title string, sections []extract.Section, maxPassageChars int) document.Document {
+	return document.Document{
+		Title:    strings.TrimSpace(title),
+		Passages: extract.Chunk(sections, maxPassageChars),
+	}
+}
+
+// normalizeText converts text to valid UTF-8 with \n line endings
+func normalizeText(data []byte) string {
+	text := strings.ToValidUTF8(string(data), "\uFFFD")
+	text = strings.TrimPrefix(text, "\uFEFF")
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+}
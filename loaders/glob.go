@@ -0,0 +1,49 @@
+package loaders
+
+import (
+	"path"
+	"strings"
+)
+
+// matchAny reports whether the slash separated relative path rel matches any of patterns
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if match(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether rel matches pattern. A pattern without a slash is matched against the last element of rel,
+// otherwise the pattern is matched element by element, where ** matches any number of elements, including none.
+func match(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchElements(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchElements(pattern, elements []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every number of elements for ** to absorb
+			for i := 0; i <= len(elements); i++ {
+				if matchElements(pattern[1:], elements[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elements) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elements[0]); !ok {
+			return false
+		}
+		pattern, elements = pattern[1:], elements[1:]
+	}
+	return len(elements) == 0
+}
@@ -0,0 +1,252 @@
+package loaders
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Passage metadata keys set by Go, so a citation can point to file:line
+const (
+	// MetadataPackage is the import path of the package, or its name outside a module
+	MetadataPackage = "package"
+	// MetadataSymbol is the declared name, e.g. "Server.Handle" for a method, or comma separated names for a group of
+	// constants or variables
+	MetadataSymbol = "symbol"
+	// MetadataKind is "package", "func", "method", "type", "const" or "var"
+	MetadataKind = "kind"
+	// MetadataStartLine and MetadataEndLine are the 1-based, inclusive line range of the passage in its file
+	MetadataStartLine = "startLine"
+	MetadataEndLine   = "endLine"
+)
+
+// Go loads a Go source file as one document with a passage per top-level declaration, including its doc comment:
+// each function, method, type, and const or var block, plus the package doc comment. Declarations longer than
+// maxPassageChars are split between lines. Imports are left out, as are generated files. The title is the package's
+// import path joined with the file name.
+func Go(f File, maxPassageChars int) ([]document.Document, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, f.Path, f.Data, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Go source: %v", err)
+	}
+	if ast.IsGenerated(file) {
+		return nil, nil
+	}
+
+	pkg := goPackagePath(f.Path, file.Name.Name, f.modules)
+	var passages []document.Passage
+	add := func(kind, symbol string, start, end token.Pos) {
+		// Line directives are ignored so the lines are the file's own, which is what a citation points to
+		startPos, endPos := fset.PositionFor(start, false), fset.PositionFor(end, false)
+		startLine := startPos.Line
+		text := string(f.Data[startPos.Offset:endPos.Offset])
+		for _, piece := range splitLines(text, maxPassageChars) {
+			passages = append(passages, document.Passage{
+				Text: piece.text,
+				Metadata: map[string]string{
+					MetadataPackage:   pkg,
+					MetadataSymbol:    symbol,
+					MetadataKind:      kind,
+					MetadataStartLine: strconv.Itoa(startLine + piece.line),
+					MetadataEndLine:   strconv.Itoa(startLine + piece.line + strings.Count(piece.text, "\n")),
+				},
+			})
+		}
+	}
+
+	if file.Doc != nil {
+		add("package", file.Name.Name, file.Doc.Pos(), file.Name.End())
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			kind, symbol := "func", d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				kind, symbol = "method", receiverType(d.Recv.List[0].Type)+"."+d.Name.Name
+			}
+			add(kind, symbol, withDoc(d.Doc, d.Pos()), d.End())
+		case *ast.GenDecl:
+			switch {
+			case d.Tok == token.IMPORT:
+			case d.Tok == token.TYPE && d.Lparen.IsValid():
+				// Grouped types are as distinct as separately declared ones
+				for _, spec := range d.Specs {
+					s := spec.(*ast.TypeSpec)
+					add("type", s.Name.Name, withDoc(s.Doc, s.Pos()), s.End())
+				}
+			default:
+				add(d.Tok.String(), strings.Join(declaredNames(d), ", "), withDoc(d.Doc, d.Pos()), d.End())
+			}
+		}
+	}
+
+	return []document.Document{{
+		Title:    path.Join(pkg, filepath.Base(f.Path)),
+		Passages: passages,
+	}}, nil
+}
+
+// withDoc returns where a declaration starts including its doc comment
+func withDoc(doc *ast.CommentGroup, pos token.Pos) token.Pos {
+	if doc != nil {
+		return doc.Pos()
+	}
+	return pos
+}
+
+// receiverType returns the name of a method's receiver type, without any pointer or type parameters
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.ParenExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// declaredNames returns the names declared by a const, var or type declaration
+func declaredNames(d *ast.GenDecl) []string {
+	var names []string
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				names = append(names, name.Name)
+			}
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		}
+	}
+	return names
+}
+
+// linePiece is part of a text split between lines. line is how many lines after the text's first line it starts.
+type linePiece struct {
+	text string
+	line int
+}
+
+// splitLines splits text between lines into pieces of at most maxChars, except for single lines longer than that. A
+// maxChars of 0 keeps the text whole.
+func splitLines(text string, maxChars int) []linePiece {
+	if maxChars <= 0 || len(text) <= maxChars {
+		return []linePiece{{text: text}}
+	}
+
+	var pieces []linePiece
+	var current strings.Builder
+	start := 0
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if current.Len() > 0 && current.Len()+len(line) > maxChars {
+			pieces = append(pieces, linePiece{strings.TrimSuffix(current.String(), "\n"), start})
+			current.Reset()
+			start = i
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		pieces = append(pieces, linePiece{strings.TrimSuffix(current.String(), "\n"), start})
+	}
+	return pieces
+}
+
+// goPackagePath returns the import path of the package in the directory of filePath by finding its module's go.mod,
+// falling back to the package's name outside a module. modules, if not nil, caches the lookup for each directory.
+func goPackagePath(filePath, name string, modules *modulePaths) string {
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if filePath == "" || err != nil {
+		return name
+	}
+
+	if pkg := modules.lookup(dir); pkg != "" {
+		return pkg
+	}
+	return name
+}
+
+// modulePaths caches the import path of the packages in each directory, so loading many files of a repository reads
+// each go.mod once per directory rather than once per file. A nil *modulePaths looks up every directory afresh. It is
+// safe for concurrent use.
+type modulePaths struct {
+	mu    sync.Mutex
+	byDir map[string]string
+}
+
+func newModulePaths() *modulePaths {
+	return &modulePaths{byDir: make(map[string]string)}
+}
+
+// lookup returns the import path of the package in the absolute directory dir, or "" outside a module
+func (m *modulePaths) lookup(dir string) string {
+	if m != nil {
+		m.mu.Lock()
+		pkg, ok := m.byDir[dir]
+		m.mu.Unlock()
+		if ok {
+			return pkg
+		}
+	}
+
+	pkg := findPackagePath(dir)
+	if m != nil {
+		m.mu.Lock()
+		m.byDir[dir] = pkg
+		m.mu.Unlock()
+	}
+	return pkg
+}
+
+// findPackagePath walks up from dir to the nearest go.mod and joins its module path with dir's path below it
+func findPackagePath(dir string) string {
+	for root := dir; ; root = filepath.Dir(root) {
+		if module := modulePath(filepath.Join(root, "go.mod")); module != "" {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return ""
+			}
+			return path.Join(module, filepath.ToSlash(rel))
+		}
+		if filepath.Dir(root) == root {
+			return ""
+		}
+	}
+}
+
+// modulePath reads the module path declared by a go.mod file, returning "" if there isn't one
+func modulePath(goMod string) string {
+	data, err := os.ReadFile(goMod)
+	if err != nil {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+				return unquoted
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package loaders
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goSource = `// Package store keeps orders.
+package store
+
+import "sync"
+
+// ErrNotFound is returned for unknown orders.
+var ErrNotFound = errors.New("not found")
+
+const (
+	MaxItems = 10
+	MinItems = 1
+)
+
+type (
+	// ID identifies an order.
+	ID string
+	Order struct{ ID ID }
+)
+
+// Store holds orders.
+type Store[K comparable] struct {
+	mu sync.Mutex
+}
+
+// Get returns an order.
+func (s *Store[K]) Get(id ID) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Order{}, ErrNotFound
+}
+
+func New() *Store[ID] { return &Store[ID]{} }
+`
+
+func TestGo(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":                  "module example.com/shop\n\ngo 1.21\n",
+		"internal/store/store.go": goSource,
+	})
+
+	docs, err := NewLoader(Config{}).LoadFile(filepath.Join(root, "internal/store/store.go"))
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if docs[0].Title != "example.com/shop/internal/store/store.go" || docs[0].FileReference.Format != "go" {
+		t.Errorf("LoadFile() title = %q, format = %q, want the import path and go", docs[0].Title, docs[0].FileReference.Format)
+	}
+
+	var got []string
+	for _, p := range docs[0].Passages {
+		if p.Metadata[MetadataPackage] != "example.com/shop/internal/store" {
+			t.Errorf("Go() package = %q, want example.com/shop/internal/store", p.Metadata[MetadataPackage])
+		}
+		got = append(got, p.Metadata[MetadataKind]+" "+p.Metadata[MetadataSymbol]+" "+
+			p.Metadata[MetadataStartLine]+"-"+p.Metadata[MetadataEndLine])
+	}
+	want := []string{
+		"package store 1-2",
+		"var ErrNotFound 6-7",
+		"const MaxItems, MinItems 9-12",
+		"type ID 15-16",
+		"type Order 17-17",
+		"type Store 20-23",
+		"method Store.Get 25-30",
+		"func New 32-32",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Go() passages =\n%q\nwant\n%q", got, want)
+	}
+	if text := docs[0].Passages[6].Text; !strings.HasPrefix(text, "// Get returns an order.\nfunc (s *Store[K]) Get") {
+		t.Errorf("Go() method passage = %q, want it to start with its doc comment", text)
+	}
+
+	// Long declarations are split between lines, keeping their line ranges
+	docs, err = Go(File{Data: []byte(goSource)}, 60)
+	if err != nil {
+		t.Fatalf("Go() error = %v", err)
+	}
+	var get []string
+	for _, p := range docs[0].Passages {
+		if p.Metadata[MetadataSymbol] == "Store.Get" {
+			get = append(get, p.Metadata[MetadataStartLine]+"-"+p.Metadata[MetadataEndLine])
+		}
+	}
+	if want := []string{"25-25", "26-27", "28-30"}; !reflect.DeepEqual(get, want) {
+		t.Errorf("Go() split method line ranges = %q, want %q", get, want)
+	}
+	if docs[0].Title != "store" {
+		t.Errorf("Go() title outside a module = %q, want the package name", docs[0].Title)
+	}
+
+	if docs, err = Go(File{Data: []byte("// Code generated by stringer. DO NOT EDIT.\n\npackage x\n")}, 0); err != nil || docs != nil {
+		t.Errorf("Go() of a generated file = %v, %v, want nothing", docs, err)
+	}
+
+	// Line directives don't change the lines a citation points to
+	docs, err = Go(File{Data: []byte("package x\n\n//line template.tmpl:100\n\n// A does a.\nfunc A() {}\n")}, 0)
+	if err != nil {
+		t.Fatalf("Go() error = %v", err)
+	}
+	if p := docs[0].Passages[0]; p.Metadata[MetadataStartLine] != "5" || p.Metadata[MetadataEndLine] != "6" {
+		t.Errorf("Go() with a line directive = lines %s-%s, want 5-6", p.Metadata[MetadataStartLine], p.Metadata[MetadataEndLine])
+	}
+}
+
+func TestLoader_LoadRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := writeFiles(t, map[string]string{
+		".gitignore":        "build/\n",
+		"go.mod":            "module example.com/shop\n",
+		"main.go":           "package main\n\nfunc main() {}\n",
+		"README.md":         "# Shop\n\nSells things.",
+		"build/out.md":      "# Ignored",
+		".github/ci.md":     "# Hidden",
+		"vendor/lib/lib.go": "package lib\n\nfunc Lib() {}\n",
+	})
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = root
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init error = %v: %s", err, out)
+	}
+
+	docs, err := NewLoader(Config{Exclude: []string{"vendor"}}).LoadRepo(context.Background(), root)
+	if err != nil {
+		t.Fatalf("LoadRepo() error = %v", err)
+	}
+	var titles []string
+	for _, d := range docs {
+		titles = append(titles, d.Title)
+	}
+	if got := strings.Join(titles, ","); got != "Shop,example.com/shop/main.go" {
+		t.Errorf("LoadRepo() = %s, want Shop,example.com/shop/main.go", got)
+	}
+
+	if _, err = NewLoader(Config{}).LoadRepo(context.Background(), t.TempDir()); err == nil {
+		t.Errorf("LoadRepo() outside a repository error = nil, want an error")
+	}
+}
+
+func TestModulePaths(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":         "module example.com/shop\n",
+		"cmd/shop/x.txt": "",
+	})
+	dir := filepath.Join(root, "cmd", "shop")
+
+	modules := newModulePaths()
+	if got := modules.lookup(dir); got != "example.com/shop/cmd/shop" {
+		t.Fatalf("lookup() = %q, want example.com/shop/cmd/shop", got)
+	}
+	// The cached path is returned without reading go.mod again
+	if err := os.Remove(filepath.Join(root, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	if got := modules.lookup(dir); got != "example.com/shop/cmd/shop" {
+		t.Errorf("cached lookup() = %q, want example.com/shop/cmd/shop", got)
+	}
+	if got := newModulePaths().lookup(dir); got != "" {
+		t.Errorf("lookup() without go.mod = %q, want \"\"", got)
+	}
+}
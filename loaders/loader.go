@@ -0,0 +1,291 @@
+package loaders
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxPassageChars = 1500
+	defaultMaxFileBytes    = 32 << 20
+)
+
+// File is a file being loaded
+type File struct {
+	// Path is the file's path as it was found, joined to the directory passed to LoadDir or LoadRepo
+	Path string
+	Info fs.FileInfo
+	// Data is the file's content
+	Data []byte
+
+	// modules caches the Go module each directory belongs to while a directory or repository is loaded
+	modules *modulePaths
+}
+
+// Func converts a file into documents. Most formats make one document per file, while formats holding many records,
+// like JSONL, make one per record. Returned documents are completed by the Loader: those without a Title get the
+// file's name, and every document gets Corpus Personal and a FileReference. A Func may return documents along with an
+// error for the records it couldn't read, and the Loader keeps both.
+type Func func(f File, maxPassageChars int) ([]document.Document, error)
+
+// Config configures a Loader
+type Config struct {
+	// Include limits loading to files matching any of these globs, e.g. "*.md" or "docs/**/*.pdf". Patterns without a
+	// slash match the file's name in any directory, other patterns match its slash separated path relative to the
+	// directory being loaded. ** matches any number of directories. Defaults to every file with a known extension.
+	Include []string
+	// Exclude skips files, and whole directories, matching any of these globs, e.g. "node_modules" or "drafts/**"
+	Exclude []string
+	// Hidden loads files and directories whose names start with a dot, like .github, which are skipped by default
+	Hidden bool
+	// MaxPassageChars is the maximum size of each passage
+	MaxPassageChars int
+	// MaxFileBytes skips larger files, default 32MB
+	MaxFileBytes int64
+	// Concurrency is how many files are loaded at once, default the number of CPUs
+	Concurrency int
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxPassageChars <= 0 {
+		c.MaxPassageChars = defaultMaxPassageChars
+	}
+	if c.MaxFileBytes <= 0 {
+		c.MaxFileBytes = defaultMaxFileBytes
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = runtime.NumCPU()
+	}
+	return c
+}
+
+// format is a registered file format
+type format struct {
+	name string
+	load Func
+}
+
+// Loader turns local files into Personal documents, choosing how to read each file by its extension
+type Loader struct {
+	config  Config
+	formats map[string]format
+}
+
+// NewLoader creates a Loader that reads Markdown (.md, .markdown), plain text (.txt), HTML (.html, .htm), PDF, DOCX,
+// CSV, JSONL (.jsonl, .ndjson), Go source, WebVTT and SRT transcript, and email (.eml, .mbox) files
+func NewLoader(config Config) *Loader {
+	l := &Loader{config: config.withDefaults(), formats: make(map[string]format)}
+	l.Register("markdown", Markdown, ".md", ".markdown")
+	l.Register("text", Text, ".txt")
+	l.Register("html", HTML, ".html", ".htm")
+	l.Register("pdf", PDF, ".pdf")
+	l.Register("docx", DOCX, ".docx")
+	l.Register("csv", CSV, ".csv")
+	l.Register("jsonl", JSONL, ".jsonl", ".ndjson")
+	l.Register("go", Go, ".go")
+	l.Register("vtt", VTT, ".vtt")
+	l.Register("srt", SRT, ".srt")
+	l.Register("eml", EML, ".eml")
+	l.Register("mbox", Mbox, ".mbox")
+	return l
+}
+
+// Register reads files with any of extensions, e.g. ".rst", with load. It replaces the format previously registered
+// for an extension. name becomes the Format of the documents' FileReference.
+func (l *Loader) Register(name string, load Func, extensions ...string) {
+	for _, ext := range extensions {
+		l.formats[strings.ToLower(ext)] = format{name, load}
+	}
+}
+
+// LoadDir loads every matching file under root, in the order of their paths. Files that fail to load are skipped and
+// reported together in the returned error, along with the documents of every other file, so one corrupt file doesn't
+// stop a whole directory from loading.
+func (l *Loader) LoadDir(ctx context.Context, root string) ([]document.Document, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if l.skipped(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() || !l.wanted(rel) {
+			return nil
+		}
+
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking %s: %v", root, err)
+	}
+	sort.Strings(paths)
+
+	return l.loadPaths(ctx, paths)
+}
+
+// LoadRepo loads every matching file of the git working tree at root: tracked files, plus untracked files that aren't
+// ignored by .gitignore, in the order of their paths. It needs git to be installed.
+func (l *Loader) LoadRepo(ctx context.Context, root string) ([]document.Document, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-files", "--cached", "--others", "--exclude-standard", "-z")
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("error listing files of %s: %v: %s", root, err, msg)
+		}
+		return nil, fmt.Errorf("error listing files of %s: %v", root, err)
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, rel := range strings.Split(string(out), "\x00") {
+		// Files with unresolved merge conflicts are listed once per stage
+		if rel == "" || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+
+		// Unlike walking, every file is listed, so its directories are checked against Hidden and Exclude here
+		skip := false
+		for dir := path.Dir(rel); dir != "." && !skip; dir = path.Dir(dir) {
+			skip = l.skipped(dir)
+		}
+		if skip || l.skipped(rel) || !l.wanted(rel) {
+			continue
+		}
+
+		file := filepath.Join(root, filepath.FromSlash(rel))
+		// Files deleted from the working tree but not yet from the index are still listed
+		if info, err := os.Lstat(file); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+
+	return l.loadPaths(ctx, paths)
+}
+
+// skipped reports whether the file or directory at the slash separated relative path rel is hidden or excluded
+func (l *Loader) skipped(rel string) bool {
+	return (!l.config.Hidden && strings.HasPrefix(path.Base(rel), ".")) || matchAny(l.config.Exclude, rel)
+}
+
+// wanted reports whether the file at the slash separated relative path rel has a known format and is included
+func (l *Loader) wanted(rel string) bool {
+	if _, ok := l.formats[extension(rel)]; !ok {
+		return false
+	}
+	return len(l.config.Include) == 0 || matchAny(l.config.Include, rel)
+}
+
+// loadPaths loads files concurrently, keeping their documents in the order of paths
+func (l *Loader) loadPaths(ctx context.Context, paths []string) ([]document.Document, error) {
+	docs := make([][]document.Document, len(paths))
+	errs := make([]error, len(paths))
+
+	modules := newModulePaths()
+	sem := make(chan struct{}, l.config.Concurrency)
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+			docs[i], errs[i] = l.loadFile(path, modules)
+		}(i, path)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var all []document.Document
+	for _, d := range docs {
+		all = append(all, d...)
+	}
+	return all, errors.Join(errs...)
+}
+
+// LoadFile loads a single file, whatever the Include and Exclude globs
+func (l *Loader) LoadFile(path string) ([]document.Document, error) {
+	return l.loadFile(path, nil)
+}
+
+// loadFile loads a single file, sharing modules with the other files loaded alongside it
+func (l *Loader) loadFile(path string, modules *modulePaths) ([]document.Document, error) {
+	format, ok := l.formats[extension(path)]
+	if !ok {
+		return nil, fmt.Errorf("error loading %s: unsupported file type", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %v", path, err)
+	}
+	if info.Size() > l.config.MaxFileBytes {
+		return nil, fmt.Errorf("error loading %s: file is larger than %d bytes", path, l.config.MaxFileBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %v", path, err)
+	}
+
+	docs, err := format.load(File{Path: path, Info: info, Data: data, modules: modules}, l.config.MaxPassageChars)
+	if err != nil {
+		err = fmt.Errorf("error loading %s: %v", path, err)
+	}
+
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for i := range docs {
+		if docs[i].Title == "" {
+			docs[i].Title = title
+		}
+		docs[i].Corpus = document.Personal
+		docs[i].FileReference = &document.FileReference{
+			Path:     path,
+			Modified: info.ModTime(),
+			Format:   format.name,
+		}
+	}
+	return docs, err
+}
+
+func extension(path string) string {
+	return strings.ToLower(filepath.Ext(path))
+}
@@ -0,0 +1,201 @@
+package loaders
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// buildDOCX writes a minimal Word document with the given core properties title and document body
+func buildDOCX(t *testing.T, title, body string) string {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	files := map[string]string{
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>` + title + `</dc:title></cp:coreProperties>`,
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + body + `</w:body></w:document>`,
+	}
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func passageTexts(doc document.Document) []string {
+	texts := make([]string, len(doc.Passages))
+	for i, p := range doc.Passages {
+		texts[i] = p.Text
+	}
+	return texts
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide/intro.md", true},
+		{"*.md", "docs/notes.txt", false},
+		{"node_modules", "web/node_modules", true},
+		{"docs/*.md", "docs/intro.md", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+		{"docs/**/*.md", "docs/intro.md", true},
+		{"docs/**/*.md", "docs/a/b/intro.md", true},
+		{"drafts/**", "drafts", true},
+		{"drafts/**", "published/drafts.md", false},
+		{"./docs/**", "docs/x.txt", true},
+	}
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	data := "---\ntitle: \"Runbook\"\ntags: [ops]\n---\nIntro with a [link](https://example.com).\n\n" +
+		"# Deploys\n\nShip on Tuesdays.\n\n```sh\n# not a heading\nmake deploy\n```\n\n" +
+		"Rollbacks\n---------\n\nRevert the tag.\n"
+
+	docs, err := Markdown(File{Data: []byte(data)}, 0)
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+
+	want := []string{
+		"Intro with a link.",
+		"Deploys\n\nShip on Tuesdays.\n\n```sh\n# not a heading\nmake deploy\n```",
+		"Rollbacks\n\nRevert the tag.",
+	}
+	if docs[0].Title != "Runbook" {
+		t.Errorf("Markdown() title = %q, want the front matter title", docs[0].Title)
+	}
+	if got := passageTexts(docs[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("Markdown() passages = %q, want %q", got, want)
+	}
+}
+
+func TestDOCX(t *testing.T) {
+	body := `<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Styled title</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t xml:space="preserve">Lead </w:t></w:r><w:r><w:t>paragraph</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Budget</w:t></w:r></w:p>` +
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Q1</w:t><w:tab/><w:t>$10</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`
+
+	tests := []struct {
+		name      string
+		coreTitle string
+		want      string
+	}{
+		{name: "properties title", coreTitle: "Plan 2025", want: "Plan 2025"},
+		{name: "title style", coreTitle: "", want: "Styled title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := DOCX(File{Data: []byte(buildDOCX(t, tt.coreTitle, body))}, 0)
+			if err != nil {
+				t.Fatalf("DOCX() error = %v", err)
+			}
+			if docs[0].Title != tt.want {
+				t.Errorf("DOCX() title = %q, want %q", docs[0].Title, tt.want)
+			}
+			want := []string{"Lead paragraph", "Budget\n\nQ1\t$10"}
+			if got := passageTexts(docs[0]); !reflect.DeepEqual(got, want) {
+				t.Errorf("DOCX() passages = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLoader_LoadDir(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"notes.txt":               "First paragraph.\r\n\r\nSecond paragraph.",
+		"guide/intro.md":          "# Intro\n\nWelcome.",
+		"guide/page.html":         "<html><head><title>Page</title></head><body><article><h2>Part</h2><p>Body text of the page.</p></article></body></html>",
+		"guide/plan.docx":         buildDOCX(t, "Plan", `<w:p><w:r><w:t>Goals</w:t></w:r></w:p>`),
+		"data/people.csv":         "\uFEFFname,role\nAna,eng\nBo,\n",
+		"data/tickets.jsonl":      `{"title": "Login broken", "body": "Users can't log in"}` + "\n\n" + `{"id": 7, "status": "open"}` + "\n",
+		"data/broken.jsonl":       "{not json}\n" + `{"title": "Kept", "text": "Lines after a bad one still load"}` + "\n",
+		"drafts/wip.md":           "# WIP",
+		".git/HEAD.txt":           "ref",
+		"guide/diagram.png":       "\x89PNG",
+		"guide/node_modules/x.md": "# vendored",
+	})
+
+	loader := NewLoader(Config{Exclude: []string{"drafts/**", "node_modules"}, Concurrency: 2})
+	docs, err := loader.LoadDir(context.Background(), root)
+	if err == nil || !strings.Contains(err.Error(), "broken.jsonl") {
+		t.Errorf("LoadDir() error = %v, want the broken JSONL line reported", err)
+	}
+
+	type loaded struct {
+		path, format, title string
+		passages            []string
+	}
+	var got []loaded
+	for _, d := range docs {
+		if d.Corpus != document.Personal || d.FileReference == nil || d.FileReference.Modified.IsZero() {
+			t.Fatalf("LoadDir() document = %+v, want a personal document with a file reference", d)
+		}
+		rel, _ := filepath.Rel(root, d.FileReference.Path)
+		got = append(got, loaded{filepath.ToSlash(rel), d.FileReference.Format, d.Title, passageTexts(d)})
+	}
+
+	want := []loaded{
+		{"data/broken.jsonl", "jsonl", "Kept", []string{"Lines after a bad one still load"}},
+		{"data/people.csv", "csv", "people", []string{"name: Ana\nrole: eng\n\nname: Bo"}},
+		{"data/tickets.jsonl", "jsonl", "Login broken", []string{"Users can't log in"}},
+		{"data/tickets.jsonl", "jsonl", "tickets", []string{"id: 7\nstatus: open"}},
+		{"guide/intro.md", "markdown", "Intro", []string{"Intro\n\nWelcome."}},
+		{"guide/page.html", "html", "Page", []string{"Part\n\nBody text of the page."}},
+		{"guide/plan.docx", "docx", "Plan", []string{"Goals"}},
+		{"notes.txt", "text", "notes", []string{"First paragraph.\n\nSecond paragraph."}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadDir() =\n%q\nwant\n%q", got, want)
+	}
+
+	// Include narrows loading down, and Register adds formats
+	loader = NewLoader(Config{Include: []string{"guide/**/*.md", "*.png"}})
+	loader.Register("image", func(f File, _ int) ([]document.Document, error) {
+		return []document.Document{{Passages: extract.Chunk([]extract.Section{{Text: "an image"}}, 0)}}, nil
+	}, ".PNG")
+	docs, err = loader.LoadDir(context.Background(), root)
+	if err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+	var titles []string
+	for _, d := range docs {
+		titles = append(titles, d.Title)
+	}
+	if got := strings.Join(titles, ","); got != "diagram,Intro,vendored" {
+		t.Errorf("LoadDir() with Include = %s, want diagram,Intro,vendored", got)
+	}
+}
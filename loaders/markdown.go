@@ -0,0 +1,99 @@
+package loaders
+
+import (
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"regexp"
+	"strings"
+)
+
+var (
+	atxHeading      = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextUnderline = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	codeFence       = regexp.MustCompile("^ {0,3}(```|~~~)")
+	// markdownLink matches inline links and images, whose text is kept and URL dropped
+	markdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// Markdown loads a Markdown file as one document with a section per heading. The title is the title in the file's
+// YAML front matter, or else its first level 1 heading. Headings inside fenced code blocks are left as text.
+func Markdown(f File, maxPassageChars int) ([]document.Document, error) {
+	text := normalizeText(f.Data)
+	title, text := frontMatter(text)
+
+	var sections []extract.Section
+	current := extract.Section{}
+	var lines []string
+	flush := func() {
+		current.Text = strings.TrimSpace(markdownLink.ReplaceAllString(strings.Join(lines, "\n"), "$1"))
+		if current.Heading != "" || current.Text != "" {
+			sections = append(sections, current)
+		}
+		lines = nil
+	}
+	startSection := func(heading string, level int) {
+		flush()
+		current = extract.Section{Heading: heading, Level: level}
+		if title == "" && level == 1 {
+			title = heading
+		}
+	}
+
+	var fence string
+	for _, line := range strings.Split(text, "\n") {
+		if m := codeFence.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[1]
+			} else if m[1] == fence {
+				fence = ""
+			}
+			lines = append(lines, line)
+			continue
+		}
+		if fence != "" {
+			lines = append(lines, line)
+			continue
+		}
+
+		if m := atxHeading.FindStringSubmatch(line); m != nil {
+			startSection(strings.TrimSpace(m[2]), len(m[1]))
+			continue
+		}
+
+		// A line of = or - under a line of text turns that line into a heading
+		if m := setextUnderline.FindStringSubmatch(line); m != nil && len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			heading := strings.TrimSpace(lines[len(lines)-1])
+			lines = lines[:len(lines)-1]
+			level := 2
+			if m[1][0] == '=' {
+				level = 1
+			}
+			startSection(heading, level)
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+	flush()
+
+	return []document.Document{newDocument(title, sections, maxPassageChars)}, nil
+}
+
+// frontMatter strips the YAML front matter from the start of a Markdown file, returning its title field
+func frontMatter(text string) (title, rest string) {
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text
+	}
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "---" || line == "..." {
+			return title, strings.Join(lines[i+1:], "\n")
+		}
+		if value, ok := strings.CutPrefix(line, "title:"); ok && title == "" {
+			title = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	// No closing delimiter, so the --- was a thematic break rather than front matter
+	return "", text
+}
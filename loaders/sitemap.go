@@ -0,0 +1,62 @@
+package loaders
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sitemap is either a <urlset> listing pages or a <sitemapindex> listing other sitemaps, per
+// https://www.sitemaps.org/protocol.html
+type sitemap struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// parseSitemap reads a sitemap, which may be gzipped, returning the pages it lists and the sitemaps it links to
+func parseSitemap(data []byte, maxBytes int64) (pages, sitemaps []string, err error) {
+	// Sitemaps ending in .xml.gz are often served without a Content-Encoding, so the client doesn't decompress them
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decompressing sitemap: %v", err)
+		}
+		if data, err = io.ReadAll(io.LimitReader(reader, maxBytes)); err != nil {
+			return nil, nil, fmt.Errorf("error decompressing sitemap: %v", err)
+		}
+	}
+
+	var s sitemap
+	if err = xml.Unmarshal(data, &s); err != nil {
+		return nil, nil, fmt.Errorf("error parsing sitemap: %v", err)
+	}
+	if s.XMLName.Local != "urlset" && s.XMLName.Local != "sitemapindex" {
+		return nil, nil, fmt.Errorf("error parsing sitemap: unexpected root element <%s>", s.XMLName.Local)
+	}
+
+	for _, u := range s.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			pages = append(pages, loc)
+		}
+	}
+	for _, sm := range s.Sitemaps {
+		if loc := strings.TrimSpace(sm.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+	return pages, sitemaps, nil
+}
+
+// isSitemapURL reports whether a start URL names a sitemap rather than a page
+func isSitemapURL(path string) bool {
+	path = strings.ToLower(path)
+	return strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz")
+}
@@ -0,0 +1,272 @@
+package loaders
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"html"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackSkippedSubtypes are the message subtypes Slack records for channel events rather than anything someone wrote
+var slackSkippedSubtypes = map[string]bool{
+	"channel_join": true, "channel_leave": true, "channel_topic": true, "channel_purpose": true,
+	"channel_name": true, "channel_archive": true, "channel_unarchive": true, "group_join": true,
+	"group_leave": true, "group_topic": true, "group_purpose": true, "group_name": true,
+	"pinned_item": true, "unpinned_item": true, "bot_add": true, "bot_remove": true,
+}
+
+// slackMarkupPattern matches Slack's markup for mentions and links, like <@U123>, <#C123|general> and
+// <https://example.com|label>
+var slackMarkupPattern = regexp.MustCompile(`<([^<>|]+)(?:\|([^<>]*))?>`)
+
+type slackUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+	Profile  struct {
+		RealName    string `json:"real_name"`
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+type slackChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type slackMessage struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	Username    string `json:"username"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+	UserProfile struct {
+		RealName string `json:"real_name"`
+	} `json:"user_profile"`
+	Files []struct {
+		Name string `json:"name"`
+	} `json:"files"`
+}
+
+// LoadSlackExport loads a Slack workspace export, either the .zip file Slack produces or its extracted directory.
+// Every thread with replies becomes a document titled by its channel and first message, and the remaining messages
+// of each channel become a document per day. Passages hold whole messages where they fit, each introduced by its
+// author's name and time, with mentions and links written out. Channel events like joins are left out.
+func (l *Loader) LoadSlackExport(ctx context.Context, exportPath string) ([]document.Document, error) {
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading Slack export %s: %v", exportPath, err)
+	}
+	var fsys fs.FS
+	if info.IsDir() {
+		fsys = os.DirFS(exportPath)
+	} else {
+		archive, err := zip.OpenReader(exportPath)
+		if err != nil {
+			return nil, fmt.Errorf("error loading Slack export %s: %v", exportPath, err)
+		}
+		defer archive.Close()
+		fsys = archive
+	}
+
+	users := make(map[string]string)
+	var userList []slackUser
+	if err = readSlackJSON(fsys, "users.json", &userList); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error loading Slack export %s: %v", exportPath, err)
+	}
+	for _, u := range userList {
+		users[u.ID] = firstNonEmpty(u.Profile.RealName, u.RealName, u.Profile.DisplayName, u.Name)
+	}
+
+	channels := make(map[string]string)
+	for _, name := range []string{"channels.json", "groups.json", "mpims.json"} {
+		var channelList []slackChannel
+		if err = readSlackJSON(fsys, name, &channelList); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("error loading Slack export %s: %v", exportPath, err)
+		}
+		for _, c := range channelList {
+			channels[c.ID] = c.Name
+		}
+	}
+
+	// Every channel's messages are stored in a directory named after it, in a file per day
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error loading Slack export %s: %v", exportPath, err)
+	}
+
+	var docs []document.Document
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		channelDocs, err := l.loadSlackChannel(fsys, exportPath, entry.Name(), users, channels)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error loading Slack channel %s: %v", entry.Name(), err))
+			continue
+		}
+		docs = append(docs, channelDocs...)
+	}
+	return docs, errors.Join(errs...)
+}
+
+// slackThread is a thread being reconstructed, or the standalone messages of a channel's day
+type slackThread struct {
+	title    string
+	messages []message
+}
+
+// loadSlackChannel loads the documents of the channel stored in the export's directory named channel
+func (l *Loader) loadSlackChannel(fsys fs.FS, exportPath, channel string, users, channels map[string]string) ([]document.Document, error) {
+	days, err := fs.Glob(fsys, path.Join(channel, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(days)
+
+	var messages []slackMessage
+	for _, day := range days {
+		var dayMessages []slackMessage
+		if err = readSlackJSON(fsys, day, &dayMessages); err != nil {
+			return nil, err
+		}
+		messages = append(messages, dayMessages...)
+	}
+	sort.SliceStable(messages, func(i, j int) bool { return slackTime(messages[i].TS).Before(slackTime(messages[j].TS)) })
+
+	// Replies are stored under the day they were sent, so threads are gathered across the channel's days
+	replies := make(map[string]int)
+	for _, m := range messages {
+		if m.ThreadTS != "" && m.ThreadTS != m.TS {
+			replies[m.ThreadTS]++
+		}
+	}
+
+	threads := make(map[string]*slackThread)
+	var order []string
+	for _, m := range messages {
+		if m.Type != "message" || slackSkippedSubtypes[m.Subtype] {
+			continue
+		}
+		text := slackText(m.Text, users, channels)
+		for _, f := range m.Files {
+			text = strings.TrimSpace(text + "\n[file: " + f.Name + "]")
+		}
+		if text == "" {
+			continue
+		}
+		sent := slackTime(m.TS)
+
+		var key, title string
+		if root := firstNonEmpty(m.ThreadTS, m.TS); replies[root] > 0 {
+			first, _, _ := strings.Cut(text, "\n")
+			key, title = root, "#"+channel+": "+truncate(first, 80)
+		} else {
+			day := sent.UTC().Format("2006-01-02")
+			key, title = day, "#"+channel+" "+day
+		}
+		thread, ok := threads[key]
+		if !ok {
+			thread = &slackThread{title: title}
+			threads[key] = thread
+			order = append(order, key)
+		}
+		author := firstNonEmpty(users[m.User], m.UserProfile.RealName, m.Username, m.User)
+		thread.messages = append(thread.messages, message{author: author, date: sent, text: text})
+	}
+
+	var docs []document.Document
+	for _, key := range order {
+		thread := threads[key]
+		doc := newThread(thread.title, thread.messages, l.config.MaxPassageChars)
+		doc.Corpus = document.Personal
+		doc.FileReference = &document.FileReference{
+			Path:     exportPath,
+			Modified: thread.messages[len(thread.messages)-1].date,
+			Format:   "slack",
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// slackText writes out the mentions and links of a Slack message's text and unescapes it
+func slackText(text string, users, channels map[string]string) string {
+	text = slackMarkupPattern.ReplaceAllStringFunc(text, func(markup string) string {
+		m := slackMarkupPattern.FindStringSubmatch(markup)
+		target, label := m[1], m[2]
+		switch {
+		case strings.HasPrefix(target, "@"):
+			return "@" + firstNonEmpty(label, users[target[1:]], target[1:])
+		case strings.HasPrefix(target, "#"):
+			return "#" + firstNonEmpty(label, channels[target[1:]], target[1:])
+		case strings.HasPrefix(target, "!"):
+			// Special mentions like <!here> and <!subteam^S123|@team>
+			return firstNonEmpty(label, "@"+strings.TrimPrefix(target, "!"))
+		case strings.HasPrefix(target, "mailto:"):
+			return firstNonEmpty(label, strings.TrimPrefix(target, "mailto:"))
+		case label != "" && label != target:
+			return label + " (" + target + ")"
+		}
+		return target
+	})
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+// slackTime reads a Slack message timestamp, seconds since the epoch with a microsecond suffix like 1700000000.000100
+func slackTime(ts string) time.Time {
+	seconds, micros, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	us, _ := strconv.ParseInt(micros, 10, 64)
+	return time.Unix(s, us*int64(time.Microsecond))
+}
+
+func readSlackJSON(fsys fs.FS, name string, v any) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error parsing %s: %v", name, err)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// truncate shortens s to at most n runes, ending it with an ellipsis when it's cut
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
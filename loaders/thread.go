@@ -0,0 +1,85 @@
+package loaders
+
+import (
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Passage metadata keys set for email and chat threads
+const (
+	// MetadataAuthors is the comma separated authors of the passage's messages in order of first appearance
+	MetadataAuthors = "authors"
+	// MetadataDate is when the passage's first message was sent, in RFC 3339 format
+	MetadataDate = "date"
+)
+
+// message is an email or chat message of a thread
+type message struct {
+	author string
+	date   time.Time
+	text   string
+}
+
+// header introduces a message in its thread's passages, e.g. "Ana Lopez (2024-03-01 09:30):"
+func (m message) header() string {
+	author := m.author
+	if author == "" {
+		author = "Unknown"
+	}
+	if m.date.IsZero() {
+		return author + ":"
+	}
+	return author + " (" + m.date.UTC().Format("2006-01-02 15:04") + "):"
+}
+
+// newThread makes a document of a thread's messages, in the order given, packing whole messages into passages of at
+// most maxPassageChars and splitting only messages too long on their own. Each passage's Metadata holds its authors
+// and the date of its first message.
+func newThread(title string, messages []message, maxPassageChars int) document.Document {
+	doc := document.Document{Title: strings.TrimSpace(title)}
+
+	var text strings.Builder
+	var authors []string
+	var date time.Time
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		metadata := make(map[string]string)
+		if len(authors) > 0 {
+			metadata[MetadataAuthors] = strings.Join(authors, ", ")
+		}
+		if !date.IsZero() {
+			metadata[MetadataDate] = date.UTC().Format(time.RFC3339)
+		}
+		doc.Passages = append(doc.Passages, document.Passage{Text: text.String(), Metadata: metadata})
+		text.Reset()
+		authors = nil
+	}
+
+	for _, m := range messages {
+		if strings.TrimSpace(m.text) == "" {
+			continue
+		}
+		for _, piece := range extract.Chunk([]extract.Section{{Heading: m.header(), Text: m.text}}, maxPassageChars) {
+			if maxPassageChars > 0 && text.Len() > 0 && text.Len()+2+len(piece.Text) > maxPassageChars {
+				flush()
+			}
+			if text.Len() == 0 {
+				date = m.date
+			} else {
+				text.WriteString("\n\n")
+			}
+			text.WriteString(piece.Text)
+			if m.author != "" && !slices.Contains(authors, m.author) {
+				authors = append(authors, m.author)
+			}
+		}
+	}
+	flush()
+
+	return doc
+}
@@ -0,0 +1,280 @@
+package loaders
+
+import (
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Passage metadata keys set by VTT and SRT, so a citation can jump to the moment in the recording
+const (
+	// MetadataStart and MetadataEnd are when the passage's first cue starts and its last cue ends, as HH:MM:SS.mmm
+	MetadataStart = "start"
+	MetadataEnd   = "end"
+	// MetadataSpeakers is the comma separated speakers of the passage in order of first appearance, when the
+	// transcript labels them
+	MetadataSpeakers = "speakers"
+)
+
+var (
+	// cueTagPattern matches cue markup like <b>, <c.yellow>, inline timestamps like <00:00:01.000> and SRT's
+	// positioning codes like {\an8}
+	cueTagPattern = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+	// voicePattern matches a WebVTT voice span's opening tag, <v Speaker> or <v.class Speaker>
+	voicePattern = regexp.MustCompile(`^<v(?:\.[^\s>]*)?\s+([^>]+)>`)
+	// speakerPattern matches a speaker name prefix of up to four capitalized words like "Ana Lopez: ", as written by
+	// many meeting tools
+	speakerPattern = regexp.MustCompile(`^([\p{Lu}\d][\p{L}\d.'\-]*(?: [\p{Lu}\d][\p{L}\d.'\-]*){0,3}):\s+`)
+)
+
+// defaultTranscriptWindow is the longest stretch of a recording a VTT or SRT passage covers, so that a citation's
+// start time stays close to what it cites
+const defaultTranscriptWindow = 2 * time.Minute
+
+// cue is a timed caption
+type cue struct {
+	start, end time.Duration
+	speaker    string
+	text       string
+}
+
+// VTT loads a WebVTT transcript as one document whose passages are consecutive cues merged up to maxPassageChars and
+// at most 2 minutes of the recording, with each speaker's turn on its own line. Each passage's Metadata holds its start
+// and end times and its speakers, which come from <v> voice spans or "Speaker: " prefixes. The title comes from the
+// WEBVTT header line, if it has one.
+func VTT(f File, maxPassageChars int) ([]document.Document, error) {
+	return vtt(f, maxPassageChars, defaultTranscriptWindow)
+}
+
+// VTTWindow returns a Func that loads WebVTT transcripts like VTT, but with passages covering at most window of the
+// recording, or any length when window is 0, e.g. loader.Register("vtt", loaders.VTTWindow(30*time.Second), ".vtt")
+func VTTWindow(window time.Duration) Func {
+	return func(f File, maxPassageChars int) ([]document.Document, error) {
+		return vtt(f, maxPassageChars, window)
+	}
+}
+
+func vtt(f File, maxPassageChars int, window time.Duration) ([]document.Document, error) {
+	text := normalizeText(f.Data)
+	header, _, _ := strings.Cut(text, "\n")
+	if !strings.HasPrefix(header, "WEBVTT") {
+		return nil, fmt.Errorf("error parsing WebVTT: missing WEBVTT header")
+	}
+	title := strings.TrimLeft(strings.TrimPrefix(header, "WEBVTT"), " \t-")
+
+	var cues []cue
+	// The header block may continue with metadata lines, and is never a cue
+	for _, block := range transcriptBlocks(text)[1:] {
+		first := block[0]
+		if first == "NOTE" || strings.HasPrefix(first, "NOTE ") || first == "STYLE" || first == "REGION" {
+			continue
+		}
+		// Cues may have an identifier line before their timings
+		if !strings.Contains(first, "-->") && len(block) > 1 {
+			block = block[1:]
+		}
+		parsed, err := parseCue(block)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing WebVTT: %v", err)
+		}
+		cues = append(cues, parsed...)
+	}
+
+	return []document.Document{newTranscript(title, cues, maxPassageChars, window)}, nil
+}
+
+// SRT loads a SubRip subtitle file as one document in the same way as VTT
+func SRT(f File, maxPassageChars int) ([]document.Document, error) {
+	return srt(f, maxPassageChars, defaultTranscriptWindow)
+}
+
+// SRTWindow returns a Func that loads SubRip files like SRT, but with passages covering at most window of the
+// recording, or any length when window is 0
+func SRTWindow(window time.Duration) Func {
+	return func(f File, maxPassageChars int) ([]document.Document, error) {
+		return srt(f, maxPassageChars, window)
+	}
+}
+
+func srt(f File, maxPassageChars int, window time.Duration) ([]document.Document, error) {
+	var cues []cue
+	for _, block := range transcriptBlocks(normalizeText(f.Data)) {
+		// Every cue starts with its sequence number, though some files leave it out
+		if !strings.Contains(block[0], "-->") && len(block) > 1 {
+			block = block[1:]
+		}
+		parsed, err := parseCue(block)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing SRT: %v", err)
+		}
+		cues = append(cues, parsed...)
+	}
+
+	return []document.Document{newTranscript("", cues, maxPassageChars, window)}, nil
+}
+
+// transcriptBlocks splits text into its blank line separated blocks of trimmed lines
+func transcriptBlocks(text string) [][]string {
+	var blocks [][]string
+	var current []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
+
+// parseCue reads a cue's timing line and text lines. A cue whose lines have different speakers becomes a cue per
+// speaker sharing its timings.
+func parseCue(block []string) ([]cue, error) {
+	from, to, ok := strings.Cut(block[0], "-->")
+	if !ok {
+		return nil, fmt.Errorf("expected cue timings, got %q", block[0])
+	}
+	start, err := parseTimestamp(from)
+	if err != nil {
+		return nil, err
+	}
+	// Cue settings like "align:start" may follow the end time
+	fields := strings.Fields(to)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("expected cue timings, got %q", block[0])
+	}
+	end, err := parseTimestamp(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []cue
+	for _, line := range block[1:] {
+		var speaker string
+		if m := voicePattern.FindStringSubmatch(line); m != nil {
+			speaker = strings.TrimSpace(m[1])
+		}
+		line = strings.Join(strings.Fields(cueTagPattern.ReplaceAllString(line, "")), " ")
+		if m := speakerPattern.FindStringSubmatch(line); speaker == "" && m != nil {
+			speaker, line = m[1], line[len(m[0]):]
+		}
+		if line == "" {
+			continue
+		}
+
+		if len(cues) > 0 && (speaker == "" || speaker == cues[len(cues)-1].speaker) {
+			cues[len(cues)-1].text += " " + line
+			continue
+		}
+		cues = append(cues, cue{start: start, end: end, speaker: speaker, text: line})
+	}
+	return cues, nil
+}
+
+// parseTimestamp reads a cue time, like 01:02:03.500 or 02:03.500 in WebVTT and 01:02:03,500 in SRT
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	d := time.Duration(seconds * float64(time.Second))
+	for i, unit := range []time.Duration{time.Minute, time.Hour}[:len(parts)-1] {
+		n, err := strconv.Atoi(parts[len(parts)-2-i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d.Round(time.Millisecond), nil
+}
+
+// formatTimestamp writes d as HH:MM:SS.mmm
+func formatTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, ms%1000)
+}
+
+// newTranscript merges consecutive cues into passages of at most maxPassageChars, spanning at most window from the
+// start of their first cue to the end of their last. A limit of 0 leaves that dimension unbounded. Captions that repeat
+// the previous cue, as rolling auto-generated captions do, are dropped.
+func newTranscript(title string, cues []cue, maxPassageChars int, window time.Duration) document.Document {
+	doc := document.Document{Title: strings.TrimSpace(title)}
+
+	var text strings.Builder
+	var start, end time.Duration
+	var speakers []string
+	lastSpeaker, lastText := "", ""
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		metadata := map[string]string{
+			MetadataStart: formatTimestamp(start),
+			MetadataEnd:   formatTimestamp(end),
+		}
+		if len(speakers) > 0 {
+			metadata[MetadataSpeakers] = strings.Join(speakers, ", ")
+		}
+		doc.Passages = append(doc.Passages, document.Passage{Text: text.String(), Metadata: metadata})
+		text.Reset()
+		speakers = nil
+	}
+
+	for _, c := range cues {
+		if c.text == lastText && c.speaker == lastSpeaker {
+			end = max(end, c.end)
+			continue
+		}
+		lastText = c.text
+
+		// A new speaker starts a new line, while the same speaker's cues run on
+		sameTurn := text.Len() > 0 && c.speaker == lastSpeaker
+		addition := c.text
+		if c.speaker != "" && !sameTurn {
+			addition = c.speaker + ": " + c.text
+		}
+		tooLong := maxPassageChars > 0 && text.Len()+1+len(addition) > maxPassageChars
+		tooWide := window > 0 && c.end-start > window
+		if text.Len() > 0 && (tooLong || tooWide) {
+			flush()
+			sameTurn = false
+			if c.speaker != "" {
+				addition = c.speaker + ": " + c.text
+			}
+		}
+
+		switch {
+		case text.Len() == 0:
+			start = c.start
+		case sameTurn:
+			text.WriteByte(' ')
+		default:
+			text.WriteByte('\n')
+		}
+		text.WriteString(addition)
+		end = c.end
+		lastSpeaker = c.speaker
+		if c.speaker != "" && !slices.Contains(speakers, c.speaker) {
+			speakers = append(speakers, c.speaker)
+		}
+	}
+	flush()
+
+	return doc
+}
@@ -0,0 +1,100 @@
+package loaders
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func transcriptPassages(t *testing.T, load Func, data string, maxPassageChars int) (string, []string) {
+	docs, err := load(File{Data: []byte(data)}, maxPassageChars)
+	if err != nil {
+		t.Fatalf("load error = %v", err)
+	}
+	var got []string
+	for _, p := range docs[0].Passages {
+		got = append(got, p.Metadata[MetadataStart]+"-"+p.Metadata[MetadataEnd]+" ["+p.Metadata[MetadataSpeakers]+"] "+p.Text)
+	}
+	return docs[0].Title, got
+}
+
+func TestVTT(t *testing.T) {
+	data := "WEBVTT - Weekly sync\nKind: captions\n\n" +
+		"NOTE recorded on Monday\n\n" +
+		"1\n00:00:01.000 --> 00:00:04.000 align:start\n<v Ana Lopez>Morning everyone,\nlet's <b>start</b>.</v>\n\n" +
+		"00:04.000 --> 00:00:06.500\n<v Ana Lopez>First the launch.\n\n" +
+		"00:00:06.500 --> 00:00:09.000\n<v.loud Bo>It slipped a week.\n<v Ana Lopez>Why?\n\n" +
+		"00:00:09.000 --> 00:00:12.000\nBo: The vendor was late.\n\n" +
+		"00:00:12.000 --> 00:00:13.000\nBo: The vendor was late.\n"
+
+	title, got := transcriptPassages(t, VTT, data, 80)
+	if title != "Weekly sync" {
+		t.Errorf("VTT() title = %q, want Weekly sync", title)
+	}
+	want := []string{
+		"00:00:01.000-00:00:06.500 [Ana Lopez] Ana Lopez: Morning everyone, let's start. First the launch.",
+		"00:00:06.500-00:00:13.000 [Bo, Ana Lopez] Bo: It slipped a week.\nAna Lopez: Why?\nBo: The vendor was late.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VTT() passages =\n%q\nwant\n%q", got, want)
+	}
+
+	// A shorter window splits passages by time regardless of their length
+	_, got = transcriptPassages(t, VTTWindow(5*time.Second), data, 0)
+	want = []string{
+		"00:00:01.000-00:00:04.000 [Ana Lopez] Ana Lopez: Morning everyone, let's start.",
+		"00:00:04.000-00:00:09.000 [Ana Lopez, Bo] Ana Lopez: First the launch.\nBo: It slipped a week.\nAna Lopez: Why?",
+		"00:00:09.000-00:00:13.000 [Bo] Bo: The vendor was late.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VTTWindow() passages =\n%q\nwant\n%q", got, want)
+	}
+
+	if _, err := VTT(File{Data: []byte("00:00:01.000 --> 00:00:02.000\nhi\n")}, 0); err == nil {
+		t.Errorf("VTT() without a header error = nil, want an error")
+	}
+}
+
+func TestSRT(t *testing.T) {
+	data := "1\r\n00:00:01,000 --> 00:00:02,500\r\n<i>Where were we?</i>\r\n\r\n" +
+		"2\r\n00:00:02,500 --> 00:00:05,000\r\n{\\an8}The bridge, I think.\r\n\r\n" +
+		"3\r\n01:00:00,000 --> 01:00:01,250\r\nThe end.\r\n"
+
+	_, got := transcriptPassages(t, SRT, data, 0)
+	want := []string{
+		"00:00:01.000-00:00:05.000 [] Where were we? The bridge, I think.",
+		"01:00:00.000-01:00:01.250 [] The end.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SRT() passages = %q, want %q", got, want)
+	}
+
+	_, got = transcriptPassages(t, SRTWindow(0), data, 0)
+	want = []string{"00:00:01.000-01:00:01.250 [] Where were we? The bridge, I think. The end."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SRTWindow(0) passages = %q, want %q", got, want)
+	}
+
+	if _, err := SRT(File{Data: []byte("1\n00:00:01,000 -> 00:00:02,000\nhi\n")}, 0); err == nil {
+		t.Errorf("SRT() with malformed timings error = nil, want an error")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"00:00:01.500", 1500 * time.Millisecond},
+		{"02:03.250", 2*time.Minute + 3250*time.Millisecond},
+		{"101:00:00,001", 101*time.Hour + time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got, err := parseTimestamp(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if got := formatTimestamp(101*time.Hour + time.Millisecond); got != "101:00:00.001" {
+		t.Errorf("formatTimestamp() = %q, want 101:00:00.001", got)
+	}
+}
@@ -0,0 +1,45 @@
+package modelproviders
+
+import (
+	"context"
+	"fmt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIEmbedder implements the retrieval.Embedder interface using OpenAI's embeddings endpoint.
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  openai.EmbeddingModel
+}
+
+func (e OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: texts,
+		Model: e.model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error making OpenAI embeddings request: %v", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings from OpenAI, got %d", len(texts), len(resp.Data))
+	}
+
+	// OpenAI reports each embedding's position in the input, which isn't guaranteed to match its position in Data
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("OpenAI returned embedding with out of range index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+
+	return vectors, nil
+}
+
+func NewOpenAIEmbedder(client *openai.Client, model openai.EmbeddingModel) OpenAIEmbedder {
+	return OpenAIEmbedder{client, model}
+}
@@ -0,0 +1,135 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBodyBytes is how much of an error response body is kept on the Error
+const maxBodyBytes = 2048
+
+// requestIDHeaders are the response headers providers commonly put their request IDs in
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id", "X-Amzn-Requestid", "Cf-Ray"}
+
+// Error is returned by API clients when a provider responds with an error. Callers can branch on it with errors.As.
+type Error struct {
+	// Provider is the API that returned the error, e.g. "exa" or "serp"
+	Provider   string
+	StatusCode int
+	// Message is the provider's own description of the error, when its response includes one
+	Message string
+	// RequestID identifies the request to the provider's support, when its response includes one
+	RequestID string
+	// Body is the start of the raw response body
+	Body string
+	// RetryAfter is how long the provider asked callers to wait before retrying, 0 if it didn't say
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s API returned status %d", e.Provider, e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	} else if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+	return msg
+}
+
+// Retryable reports whether the same request may succeed if sent again later, i.e. the provider was rate limiting
+// or had a server error
+func (e *Error) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// IsRetryable reports whether err is, or wraps, a retryable Error
+func IsRetryable(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Retryable()
+}
+
+// FromResponse builds an Error from a provider's response and its already read body, picking the provider's message
+// and request ID out of the body when it is JSON
+func FromResponse(provider string, resp *http.Response, body []byte) *Error {
+	e := &Error{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(truncate(string(body), maxBodyBytes)),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+
+	var parsed map[string]any
+	if json.Unmarshal(body, &parsed) != nil {
+		return e
+	}
+	e.Message = Message(parsed)
+	if e.RequestID == "" {
+		e.RequestID = firstString(parsed, "requestId", "request_id")
+	}
+	return e
+}
+
+// Message picks the error message out of a decoded JSON error body, handling the common shapes {"error": "..."},
+// {"error": {"message": "..."}}, Elasticsearch's {"error": {"reason": "..."}}, {"message": "..."}, {"detail": "..."} and
+// {"detail": {"error": "..."}}
+func Message(body map[string]any) string {
+	switch v := body["error"].(type) {
+	case string:
+		return v
+	case map[string]any:
+		if msg := firstString(v, "message", "msg", "detail", "reason"); msg != "" {
+			return msg
+		}
+	}
+	if detail, ok := body["detail"].(map[string]any); ok {
+		if msg := firstString(detail, "error", "message"); msg != "" {
+			return msg
+		}
+	}
+	return firstString(body, "message", "detail", "error_message")
+}
+
+// retryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+func firstString(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
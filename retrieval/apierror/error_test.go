@@ -0,0 +1,54 @@
+package apierror
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFromResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		header         http.Header
+		body           string
+		wantMessage    string
+		wantRequestID  string
+		wantRetryable  bool
+		wantRetryAfter time.Duration
+	}{
+		{name: "string error", status: 400, body: `{"error": "bad query"}`, wantMessage: "bad query"},
+		{name: "nested error", status: 401, body: `{"error": {"message": "invalid key", "type": "auth"}}`, wantMessage: "invalid key"},
+		{name: "request id in body", status: 500, body: `{"message": "internal", "requestId": "req-1"}`, wantMessage: "internal", wantRequestID: "req-1", wantRetryable: true},
+		{name: "request id header", status: 429, header: http.Header{"X-Request-Id": {"req-2"}, "Retry-After": {"3"}}, body: "slow down", wantRequestID: "req-2", wantRetryable: true, wantRetryAfter: 3 * time.Second},
+		{name: "nested detail", status: 432, body: `{"detail": {"error": "plan limit exceeded"}}`, wantMessage: "plan limit exceeded"},
+		{name: "elasticsearch error", status: 400, body: `{"error": {"type": "parsing_exception", "reason": "unknown query [mach]"}, "status": 400}`, wantMessage: "unknown query [mach]"},
+		{name: "not json", status: 502, body: "<html>Bad Gateway</html>", wantRetryable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: tt.header}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+
+			err := FromResponse("test", resp, []byte(tt.body))
+			if err.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", err.Message, tt.wantMessage)
+			}
+			if err.RequestID != tt.wantRequestID {
+				t.Errorf("RequestID = %q, want %q", err.RequestID, tt.wantRequestID)
+			}
+			if err.Retryable() != tt.wantRetryable {
+				t.Errorf("Retryable() = %v, want %v", err.Retryable(), tt.wantRetryable)
+			}
+			if err.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("RetryAfter = %v, want %v", err.RetryAfter, tt.wantRetryAfter)
+			}
+			if err.Body != tt.body {
+				t.Errorf("Body = %q, want %q", err.Body, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package bm25
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Analyzer turns text into the terms that are indexed and searched for
+type Analyzer func(text string) []string
+
+// englishStopWords are common English words that carry little meaning on their own
+var englishStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true, "by": true,
+	"for": true, "if": true, "in": true, "into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true, "their": true, "then": true, "there": true,
+	"these": true, "they": true, "this": true, "to": true, "was": true, "will": true, "with": true,
+}
+
+// English lowercases text, splits it on anything that isn't a letter or digit, drops stop words and reduces the
+// remaining words to their Porter stems.
+func English(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if englishStopWords[w] {
+			continue
+		}
+		if isASCIILower(w) {
+			w = stem(w)
+		}
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+func isASCIILower(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,259 @@
+package bm25
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/doctext"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+const (
+	defaultK1 = 1.2
+	defaultB  = 0.75
+)
+
+// Config controls BM25 scoring. Unset values fall back to the common defaults of K1 = 1.2 and B = 0.75.
+type Config struct {
+	// K1 controls how quickly repeated occurrences of a term stop adding to a document's score
+	K1 float64
+	// B controls how much longer documents are penalized, from not at all at 0 to fully at 1. It is a pointer so that
+	// 0, which turns length normalization off, can be told apart from unset.
+	B *float64
+	// Analyzer tokenizes documents and queries, defaults to English
+	Analyzer Analyzer
+}
+
+func (c Config) withDefaults() Config {
+	if c.K1 <= 0 {
+		c.K1 = defaultK1
+	}
+	if c.B == nil || *c.B < 0 {
+		b := defaultB
+		c.B = &b
+	}
+	if c.Analyzer == nil {
+		c.Analyzer = English
+	}
+	return c
+}
+
+// Result is a document matched by a search along with its BM25 score
+type Result struct {
+	ID       string
+	Document document.Document
+	Score    float64
+}
+
+type indexedDocument struct {
+	document  document.Document
+	termFreqs map[string]int
+	length    int
+}
+
+// Index is an in-process inverted index that ranks documents with BM25. It is safe for concurrent use.
+type Index struct {
+	mu     sync.RWMutex
+	config Config
+	docs   map[string]*indexedDocument
+	// postings maps each term to the ids of the documents containing it
+	postings    map[string]map[string]bool
+	totalLength int
+}
+
+func NewIndex(config Config) *Index {
+	return &Index{
+		config:   config.withDefaults(),
+		docs:     make(map[string]*indexedDocument),
+		postings: make(map[string]map[string]bool),
+	}
+}
+
+// Len returns the number of documents in the index
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// Add indexes doc under id, replacing any document already stored under it. A document's title and passages are
+// all searchable.
+func (ix *Index) Add(id string, doc document.Document) {
+	termFreqs := make(map[string]int)
+	terms := ix.config.Analyzer(doctext.Text(doc, 0))
+	for _, term := range terms {
+		termFreqs[term]++
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.delete(id)
+	ix.insert(id, &indexedDocument{doc, termFreqs, len(terms)})
+}
+
+// Delete removes the documents with the given ids, ids that aren't in the index are ignored
+func (ix *Index) Delete(ids ...string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, id := range ids {
+		ix.delete(id)
+	}
+}
+
+// Search returns the topK documents that best match query, highest score first. Documents sharing no terms with the
+// query are never returned.
+func (ix *Index) Search(query string, topK int) ([]Result, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	terms := ix.config.Analyzer(query)
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if len(ix.docs) == 0 {
+		return nil, nil
+	}
+
+	n := float64(len(ix.docs))
+	avgLength := float64(ix.totalLength) / n
+	b := *ix.config.B
+	scores := make(map[string]float64)
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := ix.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id := range postings {
+			d := ix.docs[id]
+			tf := float64(d.termFreqs[term])
+			norm := 1 - b + b*float64(d.length)/avgLength
+			scores[id] += idf * tf * (ix.config.K1 + 1) / (tf + ix.config.K1*norm)
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{id, ix.docs[id].document, score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+func (ix *Index) insert(id string, d *indexedDocument) {
+	ix.docs[id] = d
+	ix.totalLength += d.length
+	for term := range d.termFreqs {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[string]bool)
+		}
+		ix.postings[term][id] = true
+	}
+}
+
+func (ix *Index) delete(id string) {
+	d, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+
+	delete(ix.docs, id)
+	ix.totalLength -= d.length
+	for term := range d.termFreqs {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+}
+
+// snapshot is the on-disk representation of an Index. Term frequencies are stored rather than re-analyzed on load,
+// so a snapshot always reflects the analyzer it was built with.
+type snapshot struct {
+	K1        float64
+	B         float64
+	Documents []snapshotDocument
+}
+
+type snapshotDocument struct {
+	ID        string
+	Document  document.Document
+	TermFreqs map[string]int
+	Length    int
+}
+
+// Save writes the index to w so it can later be restored with Load
+func (ix *Index) Save(w io.Writer) error {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	s := snapshot{K1: ix.config.K1, B: *ix.config.B}
+	for id, d := range ix.docs {
+		s.Documents = append(s.Documents, snapshotDocument{id, d.document, d.termFreqs, d.length})
+	}
+
+	if err := gob.NewEncoder(w).Encode(s); err != nil {
+		return fmt.Errorf("error encoding index: %v", err)
+	}
+	return nil
+}
+
+// SaveFile writes the index to the file at path, replacing it if it exists
+func (ix *Index) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating index file: %v", err)
+	}
+
+	if err = ix.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads an index previously written with Index.Save. Analyzers can't be serialized, so analyzer must be the
+// one the index was built with, or nil for English.
+func Load(r io.Reader, analyzer Analyzer) (*Index, error) {
+	var s snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("error decoding index: %v", err)
+	}
+
+	ix := NewIndex(Config{K1: s.K1, B: &s.B, Analyzer: analyzer})
+	for _, sd := range s.Documents {
+		ix.insert(sd.ID, &indexedDocument{sd.Document, sd.TermFreqs, sd.Length})
+	}
+	return ix, nil
+}
+
+// LoadFile reads an index previously written with Index.SaveFile
+func LoadFile(path string, analyzer Analyzer) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening index file: %v", err)
+	}
+	defer f.Close()
+
+	return Load(f, analyzer)
+}
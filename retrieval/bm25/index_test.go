@@ -0,0 +1,141 @@
+package bm25
+
+import (
+	"bytes"
+	"context"
+	"github.com/coopslarhette/raglib/lib/document"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"cats":           "cat",
+		"agreed":         "agre",
+		"plastered":      "plaster",
+		"motoring":       "motor",
+		"hopping":        "hop",
+		"falling":        "fall",
+		"filing":         "file",
+		"happy":          "happi",
+		"relational":     "relat",
+		"conditional":    "condit",
+		"digitizer":      "digit",
+		"operator":       "oper",
+		"hopefulness":    "hope",
+		"formality":      "formal",
+		"triplicate":     "triplic",
+		"electrical":     "electr",
+		"adjustment":     "adjust",
+		"adoption":       "adopt",
+		"controlling":    "control",
+		"generalization": "gener",
+		"connections":    "connect",
+		"connected":      "connect",
+		"ies":            "i",
+		"go":             "go",
+	}
+
+	for word, want := range tests {
+		if got := stem(word); got != want {
+			t.Errorf("stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func newTestIndex() *Index {
+	ix := NewIndex(Config{})
+	ix.Add("go", document.Document{Title: "The Go programming language", Passages: []document.Passage{{Text: "Go is a statically typed, compiled language with goroutines and channels."}}})
+	ix.Add("rust", document.Document{Title: "Rust", Passages: []document.Passage{{Text: "Rust is a systems programming language focused on memory safety."}}})
+	ix.Add("coffee", document.Document{Title: "Brewing coffee", Passages: []document.Passage{{Text: "Pour-over coffee brewing needs freshly ground beans."}}})
+	return ix
+}
+
+func TestIndex_Search(t *testing.T) {
+	ix := newTestIndex()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "single term", query: "coffee", want: []string{"coffee"}},
+		{name: "stemmed match", query: "brewed beans", want: []string{"coffee"}},
+		{name: "rarer term ranks first", query: "memory programming", want: []string{"rust", "go"}},
+		{name: "stop words only", query: "the and of", want: nil},
+		{name: "no match", query: "kubernetes", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ix.Search(tt.query, 10)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("Search() returned %d results, want %d", len(results), len(tt.want))
+			}
+			for i, r := range results {
+				if r.ID != tt.want[i] {
+					t.Errorf("result %d = %v, want %v", i, r.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIndex_NoLengthNormalization(t *testing.T) {
+	b := 0.0
+	ix := NewIndex(Config{B: &b})
+	ix.Add("short", document.Document{Passages: []document.Passage{{Text: "gopher"}}})
+	ix.Add("long", document.Document{Passages: []document.Passage{{Text: "gopher burrows under meadows and gardens"}}})
+	ix.Add("other", document.Document{Passages: []document.Passage{{Text: "unrelated"}}})
+
+	results, err := ix.Search("gopher", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Score != results[1].Score {
+		t.Errorf("Search() with B = 0 should score documents regardless of length, got %+v", results)
+	}
+}
+
+func TestIndex_DeleteAndReplace(t *testing.T) {
+	ix := newTestIndex()
+
+	ix.Delete("coffee")
+	if results, _ := ix.Search("coffee", 10); len(results) != 0 {
+		t.Errorf("deleted document still returned: %v", results)
+	}
+
+	ix.Add("rust", document.Document{Title: "Rust", Passages: []document.Passage{{Text: "Iron oxide forms on steel."}}})
+	if results, _ := ix.Search("memory", 10); len(results) != 0 {
+		t.Errorf("replaced document still matched on old text: %v", results)
+	}
+	if ix.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", ix.Len())
+	}
+}
+
+func TestIndex_SaveLoad(t *testing.T) {
+	ix := newTestIndex()
+
+	var buf bytes.Buffer
+	if err := ix.Save(&buf); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(&buf, nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	r := NewRetriever(loaded)
+	docs, err := r.Query(context.Background(), "language programming", 1)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 1 || docs[0].Title != "The Go programming language" {
+		t.Errorf("Query() after Load() = %+v", docs)
+	}
+}
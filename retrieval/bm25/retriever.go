@@ -0,0 +1,29 @@
+package bm25
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+)
+
+// Retriever implements the retrieval.Retriever interface with keyword search over an in-process BM25 Index
+type Retriever struct {
+	index *Index
+}
+
+func (br Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	results, err := br.index.Search(query, topK)
+	if err != nil {
+		return nil, fmt.Errorf("error searching index: %v", err)
+	}
+
+	docs := make([]document.Document, len(results))
+	for i, r := range results {
+		docs[i] = r.Document
+	}
+	return docs, nil
+}
+
+func NewRetriever(index *Index) Retriever {
+	return Retriever{index}
+}
@@ -0,0 +1,290 @@
+package bm25
+
+// stem reduces an English word to its stem with the Porter stemming algorithm
+// (https://tartarus.org/martin/PorterStemmer/def.txt), so "connected", "connecting" and "connections" all index as
+// "connect". word must be lowercase ASCII.
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+
+	s := &stemmer{b: []byte(word)}
+	s.step1ab()
+	if s.k() > 0 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+	return string(s.b)
+}
+
+// stemmer holds the word being stemmed. The stem is always b, and j marks the end of the stem preceding the suffix
+// most recently matched by ends.
+type stemmer struct {
+	b []byte
+	j int
+}
+
+func (s *stemmer) k() int { return len(s.b) - 1 }
+
+func (s *stemmer) trim(n int) { s.b = s.b[:len(s.b)-n] }
+
+// cons reports whether b[i] is a consonant
+func (s *stemmer) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.cons(i-1)
+	default:
+		return true
+	}
+}
+
+// m measures the number of consonant sequences in b[0..j], written [C](VC)^m[V]
+func (s *stemmer) m() int {
+	n, i := 0, 0
+	for {
+		if i > s.j {
+			return n
+		}
+		if !s.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+	for {
+		for {
+			if i > s.j {
+				return n
+			}
+			if s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+		for {
+			if i > s.j {
+				return n
+			}
+			if !s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+// vowelInStem reports whether b[0..j] contains a vowel
+func (s *stemmer) vowelInStem() bool {
+	for i := 0; i <= s.j; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleC reports whether b[j-1..j] is a double consonant
+func (s *stemmer) doubleC(j int) bool {
+	return j >= 1 && s.b[j] == s.b[j-1] && s.cons(j)
+}
+
+// cvc reports whether b[i-2..i] is consonant-vowel-consonant where the last consonant isn't w, x or y. It's used to
+// restore an e at the end of short words, e.g. cav(e), lov(e), hop(e).
+func (s *stemmer) cvc(i int) bool {
+	if i < 2 || !s.cons(i) || s.cons(i-1) || !s.cons(i-2) {
+		return false
+	}
+	switch s.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends reports whether b ends with suffix, setting j to the end of the stem before it
+func (s *stemmer) ends(suffix string) bool {
+	if len(suffix) > len(s.b) || string(s.b[len(s.b)-len(suffix):]) != suffix {
+		return false
+	}
+	s.j = len(s.b) - len(suffix) - 1
+	return true
+}
+
+// setTo replaces b[j+1..] with replacement
+func (s *stemmer) setTo(replacement string) {
+	s.b = append(s.b[:s.j+1], replacement...)
+}
+
+// replace replaces the suffix matched by ends with replacement when the stem has a measure greater than zero
+func (s *stemmer) replace(replacement string) {
+	if s.m() > 0 {
+		s.setTo(replacement)
+	}
+}
+
+// step1ab removes plurals and -ed or -ing, e.g. caresses -> caress, ponies -> poni, meetings -> meet
+func (s *stemmer) step1ab() {
+	if s.b[s.k()] == 's' {
+		switch {
+		case s.ends("sses"):
+			s.trim(2)
+		case s.ends("ies"):
+			s.setTo("i")
+		case s.b[s.k()-1] != 's':
+			s.trim(1)
+		}
+	}
+
+	if s.ends("eed") {
+		if s.m() > 0 {
+			s.trim(1)
+		}
+		return
+	}
+
+	if (s.ends("ed") || s.ends("ing")) && s.vowelInStem() {
+		s.b = s.b[:s.j+1]
+		switch {
+		case s.ends("at"):
+			s.setTo("ate")
+		case s.ends("bl"):
+			s.setTo("ble")
+		case s.ends("iz"):
+			s.setTo("ize")
+		case s.doubleC(s.k()):
+			switch s.b[s.k()] {
+			case 'l', 's', 'z':
+			default:
+				s.trim(1)
+			}
+		default:
+			s.j = s.k()
+			if s.m() == 1 && s.cvc(s.k()) {
+				s.b = append(s.b, 'e')
+			}
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there is another vowel in the stem
+func (s *stemmer) step1c() {
+	if s.ends("y") && s.vowelInStem() {
+		s.b[s.k()] = 'i'
+	}
+}
+
+// replaceFirst applies replace for the first of the suffix, replacement pairs that b ends with
+func (s *stemmer) replaceFirst(pairs ...string) {
+	for i := 0; i < len(pairs); i += 2 {
+		if s.ends(pairs[i]) {
+			s.replace(pairs[i+1])
+			return
+		}
+	}
+}
+
+// step2 maps double suffixes to single ones, e.g. -ization -> -ize
+func (s *stemmer) step2() {
+	switch s.b[s.k()-1] {
+	case 'a':
+		s.replaceFirst("ational", "ate", "tional", "tion")
+	case 'c':
+		s.replaceFirst("enci", "ence", "anci", "ance")
+	case 'e':
+		s.replaceFirst("izer", "ize")
+	case 'l':
+		s.replaceFirst("bli", "ble", "alli", "al", "entli", "ent", "eli", "e", "ousli", "ous")
+	case 'o':
+		s.replaceFirst("ization", "ize", "ation", "ate", "ator", "ate")
+	case 's':
+		s.replaceFirst("alism", "al", "iveness", "ive", "fulness", "ful", "ousness", "ous")
+	case 't':
+		s.replaceFirst("aliti", "al", "iviti", "ive", "biliti", "ble")
+	case 'g':
+		s.replaceFirst("logi", "log")
+	}
+}
+
+// step3 handles -ic-, -full, -ness etc.
+func (s *stemmer) step3() {
+	switch s.b[s.k()] {
+	case 'e':
+		s.replaceFirst("icate", "ic", "ative", "", "alize", "al")
+	case 'i':
+		s.replaceFirst("iciti", "ic")
+	case 'l':
+		s.replaceFirst("ical", "ic", "ful", "")
+	case 's':
+		s.replaceFirst("ness", "")
+	}
+}
+
+// step4 removes -ant, -ence etc. from stems with a measure greater than one
+func (s *stemmer) step4() {
+	var suffixes []string
+	switch s.b[s.k()-1] {
+	case 'a':
+		suffixes = []string{"al"}
+	case 'c':
+		suffixes = []string{"ance", "ence"}
+	case 'e':
+		suffixes = []string{"er"}
+	case 'i':
+		suffixes = []string{"ic"}
+	case 'l':
+		suffixes = []string{"able", "ible"}
+	case 'n':
+		suffixes = []string{"ant", "ement", "ment", "ent"}
+	case 'o':
+		if s.ends("ion") && s.j >= 0 && (s.b[s.j] == 's' || s.b[s.j] == 't') {
+			break
+		}
+		suffixes = []string{"ou"}
+	case 's':
+		suffixes = []string{"ism"}
+	case 't':
+		suffixes = []string{"ate", "iti"}
+	case 'u':
+		suffixes = []string{"ous"}
+	case 'v':
+		suffixes = []string{"ive"}
+	case 'z':
+		suffixes = []string{"ize"}
+	default:
+		return
+	}
+
+	matched := suffixes == nil // the -sion/-tion case above already matched
+	for _, suffix := range suffixes {
+		if s.ends(suffix) {
+			matched = true
+			break
+		}
+	}
+	if matched && s.m() > 1 {
+		s.b = s.b[:s.j+1]
+	}
+}
+
+// step5 removes a final -e and changes -ll to -l when the measure is greater than one
+func (s *stemmer) step5() {
+	s.j = s.k()
+	if s.b[s.k()] == 'e' {
+		a := s.m()
+		if a > 1 || a == 1 && !s.cvc(s.k()-1) {
+			s.trim(1)
+		}
+	}
+	s.j = s.k()
+	if s.b[s.k()] == 'l' && s.doubleC(s.k()) && s.m() > 1 {
+		s.trim(1)
+	}
+}
@@ -0,0 +1,209 @@
+package brave
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"github.com/coopslarhette/raglib/lib/retrieval/ratelimit"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api.search.brave.com"
+
+// Freshness values for SearchRequest.Freshness. DateRange builds a custom range.
+const (
+	PastDay   = "pd"
+	PastWeek  = "pw"
+	PastMonth = "pm"
+	PastYear  = "py"
+)
+
+// DateRange returns a SearchRequest.Freshness restricting results to pages discovered between from and to
+func DateRange(from, to time.Time) string {
+	return from.Format(time.DateOnly) + "to" + to.Format(time.DateOnly)
+}
+
+// Client is the HTTP client for querying the Brave Web Search API
+type Client struct {
+	apiKey  string
+	client  *http.Client
+	baseURL string
+	limiter *ratelimit.Limiter
+}
+
+// SearchRequest is a search through the Brave Web Search API
+type SearchRequest struct {
+	Query string
+	// Country is the two-letter country code results come from, e.g. "us" or "de"
+	Country string
+	// SearchLang is the language code results are written in, e.g. "en"
+	SearchLang string
+	// SafeSearch is one of "off", "moderate" or "strict", Brave defaults to "moderate"
+	SafeSearch string
+	// Freshness restricts results by when Brave discovered them, e.g. PastWeek or a DateRange
+	Freshness string
+	// ExtraSnippets returns up to five additional excerpts from each page alongside its description
+	ExtraSnippets bool
+	// Count is the number of results on the page, at most 20
+	Count int
+	// Offset is the zero-based index of the page of Count results to return, at most 9
+	Offset int
+}
+
+func (r SearchRequest) values() url.Values {
+	params := url.Values{}
+	params.Set("q", r.Query)
+	// Only web results are mapped to documents, so don't pay for news, videos and the rest
+	params.Set("result_filter", "web")
+	if r.Country != "" {
+		params.Set("country", r.Country)
+	}
+	if r.SearchLang != "" {
+		params.Set("search_lang", r.SearchLang)
+	}
+	if r.SafeSearch != "" {
+		params.Set("safesearch", r.SafeSearch)
+	}
+	if r.Freshness != "" {
+		params.Set("freshness", r.Freshness)
+	}
+	if r.ExtraSnippets {
+		params.Set("extra_snippets", "true")
+	}
+	if r.Count > 0 {
+		params.Set("count", strconv.Itoa(r.Count))
+	}
+	if r.Offset > 0 {
+		params.Set("offset", strconv.Itoa(r.Offset))
+	}
+	return params
+}
+
+type Profile struct {
+	Name     string `json:"name"`
+	LongName string `json:"long_name"`
+	Img      string `json:"img"`
+}
+
+type MetaURL struct {
+	Scheme   string `json:"scheme"`
+	Netloc   string `json:"netloc"`
+	Hostname string `json:"hostname"`
+	Favicon  string `json:"favicon"`
+	// Path is the URL's path formatted for display, e.g. " › doc › faq"
+	Path string `json:"path"`
+}
+
+type Thumbnail struct {
+	Src      string `json:"src"`
+	Original string `json:"original"`
+}
+
+// WebResult is a single web search result. Description and ExtraSnippets may contain <strong> tags around the words
+// matching the query.
+type WebResult struct {
+	Title         string    `json:"title"`
+	URL           string    `json:"url"`
+	Description   string    `json:"description"`
+	Age           string    `json:"age"`
+	PageAge       string    `json:"page_age"`
+	Language      string    `json:"language"`
+	Profile       Profile   `json:"profile"`
+	MetaURL       MetaURL   `json:"meta_url"`
+	Thumbnail     Thumbnail `json:"thumbnail"`
+	ExtraSnippets []string  `json:"extra_snippets"`
+}
+
+type Query struct {
+	Original             string `json:"original"`
+	Altered              string `json:"altered"`
+	MoreResultsAvailable bool   `json:"more_results_available"`
+}
+
+// SearchResponse represents the JSON response of the Brave Web Search API. Like serp.SearchResult, it only holds the
+// fields that are interesting to us currently.
+type SearchResponse struct {
+	Query Query `json:"query"`
+	Web   struct {
+		Results []WebResult `json:"results"`
+	} `json:"web"`
+}
+
+func NewClient(apiKey string, client *http.Client) *Client {
+	return &Client{
+		apiKey:  apiKey,
+		client:  client,
+		baseURL: defaultBaseURL,
+	}
+}
+
+// SetLimiter throttles, retries and meters the client's searches with limiter
+func (c *Client) SetLimiter(limiter *ratelimit.Limiter) {
+	c.limiter = limiter
+}
+
+// Search returns one page of web results for request
+func (c *Client) Search(ctx context.Context, request SearchRequest) (*SearchResponse, error) {
+	apiURL := c.baseURL + "/res/v1/web/search?" + request.values().Encode()
+
+	var result *SearchResponse
+	err := c.limiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.get(ctx, apiURL)
+		return err
+	})
+	return result, err
+}
+
+func (c *Client) get(ctx context.Context, apiURL string) (*SearchResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing request for Brave API: %v", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while executing request to Brave API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Brave API response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := apierror.FromResponse("brave", resp, body)
+		if apiErr.RetryAfter == 0 {
+			apiErr.RetryAfter = rateLimitReset(resp.Header.Get("X-RateLimit-Reset"))
+		}
+		return nil, apiErr
+	}
+
+	var result SearchResponse
+	if err = json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error parsing Brave API response: %v", err)
+	}
+
+	return &result, nil
+}
+
+// rateLimitReset parses Brave's X-RateLimit-Reset header, which lists the seconds until each of the plan's limits
+// resets, shortest window first, e.g. "1, 1419704"
+func rateLimitReset(value string) time.Duration {
+	first, _, _ := strings.Cut(value, ",")
+	seconds, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,148 @@
+package brave
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c := NewClient("key", server.Client())
+	c.baseURL = server.URL
+	return c
+}
+
+func TestRetriever_Query(t *testing.T) {
+	var got *http.Request
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte(`{
+			"query": {"original": "golang generics", "more_results_available": false},
+			"web": {"results": [{
+				"title": "Tutorial: Getting started with generics",
+				"url": "https://go.dev/doc/tutorial/generics",
+				"description": "An introduction to <strong>generics</strong> in Go &amp; how to use them.",
+				"page_age": "2022-03-15T00:00:00",
+				"age": "March 15, 2022",
+				"meta_url": {"netloc": "go.dev", "hostname": "go.dev", "favicon": "https://imgs.search.brave.com/favicon.png", "path": " › doc › tutorial › generics"},
+				"thumbnail": {"src": "https://imgs.search.brave.com/thumb.png"},
+				"extra_snippets": ["With <strong>generics</strong>, you can declare functions.", "Type parameters make this possible."]
+			}]}
+		}`))
+	})
+
+	retriever := NewRetrieverWithRequest(c, SearchRequest{Country: "us", Freshness: PastYear, ExtraSnippets: true})
+	docs, err := retriever.Query(context.Background(), "golang generics", 5)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	if got.URL.Path != "/res/v1/web/search" || got.Header.Get("X-Subscription-Token") != "key" {
+		t.Errorf("request = %s with token %q", got.URL.Path, got.Header.Get("X-Subscription-Token"))
+	}
+	params := got.URL.Query()
+	for k, want := range map[string]string{"q": "golang generics", "country": "us", "freshness": "py", "extra_snippets": "true", "count": "5"} {
+		if params.Get(k) != want {
+			t.Errorf("parameter %s = %q, want %q", k, params.Get(k), want)
+		}
+	}
+
+	if len(docs) != 1 {
+		t.Fatalf("Query() returned %d documents, want 1", len(docs))
+	}
+	doc := docs[0]
+	wantPassages := []string{
+		"An introduction to generics in Go & how to use them.",
+		"With generics, you can declare functions.",
+		"Type parameters make this possible.",
+	}
+	if len(doc.Passages) != len(wantPassages) {
+		t.Fatalf("got %d passages, want %d", len(doc.Passages), len(wantPassages))
+	}
+	for i, want := range wantPassages {
+		if doc.Passages[i].Text != want {
+			t.Errorf("passage %d = %q, want %q", i, doc.Passages[i].Text, want)
+		}
+	}
+
+	ref := doc.WebReference
+	if ref.Link != "https://go.dev/doc/tutorial/generics" || ref.DisplayedLink != "go.dev › doc › tutorial › generics" ||
+		ref.Date != "2022-03-15T00:00:00" || ref.Thumbnail != "https://imgs.search.brave.com/thumb.png" ||
+		ref.Favicon != "https://imgs.search.brave.com/favicon.png" || ref.APISource != "brave" {
+		t.Errorf("WebReference = %+v", ref)
+	}
+}
+
+func TestRetriever_Paging(t *testing.T) {
+	const total = 45
+	var requests []url.Values
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		requests = append(requests, params)
+		count, _ := strconv.Atoi(params.Get("count"))
+		offset, _ := strconv.Atoi(params.Get("offset"))
+
+		var results []string
+		for i := offset * count; i < min((offset+1)*count, total); i++ {
+			results = append(results, fmt.Sprintf(`{"title": "result %d", "url": "https://example.com/%d"}`, i, i))
+		}
+		more := (offset+1)*count < total
+		fmt.Fprintf(w, `{"query": {"more_results_available": %v}, "web": {"results": [%s]}}`, more, strings.Join(results, ","))
+	})
+
+	docs, err := NewRetriever(c).Query(context.Background(), "q", 50)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != total {
+		t.Fatalf("Query() returned %d documents, want %d", len(docs), total)
+	}
+	for i, doc := range docs {
+		if want := fmt.Sprintf("https://example.com/%d", i); doc.WebReference.Link != want {
+			t.Errorf("docs[%d] link = %s, want %s", i, doc.WebReference.Link, want)
+		}
+	}
+	if len(requests) != 3 {
+		t.Errorf("sent %d requests, want 3", len(requests))
+	}
+
+	if _, err = NewRetriever(c).Query(context.Background(), "q", -1); err == nil || len(requests) != 3 {
+		t.Errorf("Query() with negative topK error = %v, want an error without sending a request", err)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Reset", "1, 1419704")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"type": "ErrorResponse", "error": {"id": "e1", "status": 429, "code": "RATE_LIMITED", "detail": "Request rate limit exceeded for plan."}}`))
+	})
+
+	_, err := NewRetriever(c).Query(context.Background(), "q", 10)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Query() error = %v, want *apierror.Error", err)
+	}
+	if !apiErr.Retryable() || apiErr.Message != "Request rate limit exceeded for plan." || apiErr.RetryAfter != time.Second {
+		t.Errorf("got error %+v", apiErr)
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	if got, want := DateRange(from, to), "2024-01-01to2024-06-30"; got != want {
+		t.Errorf("DateRange() = %q, want %q", got, want)
+	}
+}
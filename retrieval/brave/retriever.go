@@ -0,0 +1,101 @@
+package brave
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+)
+
+const (
+	// maxCount is the most results Brave returns per page
+	maxCount = 20
+	// maxOffset is the last page Brave will return
+	maxOffset = 9
+)
+
+// Retriever implements the retrieval.Retriever interface for the Brave Web Search API, which searches Brave's own
+// index rather than scraping Google
+type Retriever struct {
+	client *Client
+	// request is the template for each search, with its Query, Count and Offset filled in per page
+	request SearchRequest
+}
+
+func (br Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	var docs []document.Document
+	seen := make(map[string]bool)
+
+	request := br.request
+	request.Query = query
+	// Offset counts pages of Count results, so Count has to stay the same from page to page
+	request.Count = min(topK, maxCount)
+	for ; len(docs) < topK && request.Offset <= maxOffset; request.Offset++ {
+		result, err := br.client.Search(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("error querying Brave API: %w", err)
+		}
+
+		for _, r := range result.Web.Results {
+			if seen[r.URL] {
+				continue
+			}
+			seen[r.URL] = true
+			docs = append(docs, toDocument(r))
+		}
+
+		if len(result.Web.Results) == 0 || !result.Query.MoreResultsAvailable {
+			break
+		}
+	}
+
+	if len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs, nil
+}
+
+// toDocument maps a result to a document whose passages are its description followed by any extra snippets
+func toDocument(r WebResult) document.Document {
+	description := extract.PlainText(r.Description)
+	passages := []document.Passage{{Text: description}}
+	for _, snippet := range r.ExtraSnippets {
+		passages = append(passages, document.Passage{Text: extract.PlainText(snippet)})
+	}
+
+	date := r.PageAge
+	if date == "" {
+		date = r.Age
+	}
+
+	return document.Document{
+		Passages: passages,
+		Corpus:   document.Web,
+		WebReference: &document.WebReference{
+			Title:         extract.PlainText(r.Title),
+			Link:          r.URL,
+			DisplayedLink: r.MetaURL.Netloc + r.MetaURL.Path,
+			Blurb:         description,
+			Date:          date,
+			Favicon:       r.MetaURL.Favicon,
+			Thumbnail:     r.Thumbnail.Src,
+			APISource:     "brave",
+		},
+		Title: extract.PlainText(r.Title),
+	}
+}
+
+func NewRetriever(client *Client) Retriever {
+	return Retriever{client: client}
+}
+
+// NewRetrieverWithRequest creates a Retriever whose searches use the locale and filters of request, e.g. Freshness or
+// ExtraSnippets. Its Query, Count and Offset are ignored.
+func NewRetrieverWithRequest(client *Client, request SearchRequest) Retriever {
+	request.Offset = 0
+	return Retriever{client: client, request: request}
+}
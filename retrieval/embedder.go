@@ -0,0 +1,33 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+)
+
+// Embedder turns text into vectors so it can be compared by similarity. Implementations must return exactly one
+// vector per input text, in the same order as texts.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Embed calls embedder and checks that it kept the Embedder contract, returning exactly one non-empty vector per text,
+// all of the same dimension, so callers can index into the result without guarding against a misbehaving embedder.
+func Embed(ctx context.Context, embedder Embedder, texts []string) ([][]float32, error) {
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("embedder returned an empty vector for text %d", i)
+		}
+		if len(v) != len(vectors[0]) {
+			return nil, fmt.Errorf("embedder returned vectors of mixed dimensions %d and %d", len(vectors[0]), len(v))
+		}
+	}
+	return vectors, nil
+}
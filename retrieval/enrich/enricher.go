@@ -0,0 +1,201 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"github.com/coopslarhette/raglib/lib/retrieval/robots"
+	"golang.org/x/net/html/charset"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency     = 8
+	defaultTimeout         = 10 * time.Second
+	defaultMaxBodyBytes    = 5 << 20
+	defaultMaxPassageChars = 1500
+)
+
+// ErrDisallowed is returned when a site's robots.txt doesn't allow fetching a page
+var ErrDisallowed = errors.New("fetching page is disallowed by robots.txt")
+
+// Config controls how an Enricher fetches pages. Zero values fall back to defaults.
+type Config struct {
+	// UserAgent identifies the fetcher to sites and is matched against robots.txt rules
+	UserAgent string
+	// Concurrency is the maximum number of pages fetched at once
+	Concurrency int
+	// Timeout bounds fetching each page, including its robots.txt
+	Timeout time.Duration
+	// MaxBodyBytes is how much of each page is read, anything past it is ignored
+	MaxBodyBytes int64
+	// MaxPassageChars is the maximum size of each passage the page text is split into
+	MaxPassageChars int
+	// MaxPassages caps how many passages are kept from each page, 0 keeps them all
+	MaxPassages int
+	// Append keeps a document's original passages, e.g. the search snippet, and adds the page text after them.
+	// Otherwise the page text replaces them.
+	Append bool
+}
+
+func (c Config) withDefaults() Config {
+	if c.UserAgent == "" {
+		c.UserAgent = retrieval.DefaultUserAgent
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultConcurrency
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if c.MaxPassageChars <= 0 {
+		c.MaxPassageChars = defaultMaxPassageChars
+	}
+	return c
+}
+
+// Enricher fetches the full pages behind web documents and fills their passages with the pages' main text. Search
+// APIs like SerpApi only return short snippets, which are often too thin to ground an answer in.
+type Enricher struct {
+	client *http.Client
+	robots *robots.Cache
+	config Config
+}
+
+// Enrich returns a copy of docs with passages taken from the page each document's WebReference links to. Pages are
+// fetched concurrently. Documents without a link, or whose page can't be fetched, is disallowed by robots.txt, or has
+// no extractable text, are returned unchanged. An error is only returned if ctx is done.
+func (e *Enricher) Enrich(ctx context.Context, docs []document.Document) ([]document.Document, error) {
+	enriched := make([]document.Document, len(docs))
+	copy(enriched, docs)
+
+	sem := make(chan struct{}, e.config.Concurrency)
+	var wg sync.WaitGroup
+	for i, d := range docs {
+		if d.WebReference == nil || d.WebReference.Link == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, link string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			article, err := e.Fetch(ctx, link)
+			if err != nil {
+				return
+			}
+			passages := extract.Chunk(article.Sections, e.config.MaxPassageChars)
+			if e.config.MaxPassages > 0 && len(passages) > e.config.MaxPassages {
+				passages = passages[:e.config.MaxPassages]
+			}
+			if len(passages) == 0 {
+				return
+			}
+
+			if e.config.Append {
+				passages = append(append([]document.Passage(nil), enriched[i].Passages...), passages...)
+			}
+			enriched[i].Passages = passages
+		}(i, d.WebReference.Link)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return enriched, nil
+}
+
+// Fetch downloads the page at link, if robots.txt allows it, and extracts its main content
+func (e *Enricher) Fetch(ctx context.Context, link string) (*extract.Article, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	allowed, err := e.robots.Allowed(ctx, link)
+	if err != nil {
+		return nil, fmt.Errorf("error checking robots.txt: %v", err)
+	}
+	if !allowed {
+		return nil, ErrDisallowed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing page request: %v", err)
+	}
+	req.Header.Set("User-Agent", e.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching page: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response status code is not OK; recieved code: %v", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("unsupported page content type %q", mediaType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, e.config.MaxBodyBytes), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding page: %v", err)
+	}
+
+	article, err := extract.FromHTML(body)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting page content: %v", err)
+	}
+	return article, nil
+}
+
+// NewEnricher creates an Enricher that fetches pages with client
+func NewEnricher(client *http.Client, config Config) *Enricher {
+	config = config.withDefaults()
+	return &Enricher{
+		client: client,
+		robots: robots.NewCache(client, config.UserAgent, time.Hour),
+		config: config,
+	}
+}
+
+// Retriever implements the retrieval.Retriever interface by enriching the documents another retriever returns, e.g.
+// wrapping a serp.Retriever so documents carry full page text rather than Google snippets
+type Retriever struct {
+	retriever retrieval.Retriever
+	enricher  *Enricher
+}
+
+func (er Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	docs, err := er.retriever.Query(ctx, query, topK)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving documents: %v", err)
+	}
+
+	return er.enricher.Enrich(ctx, docs)
+}
+
+func NewRetriever(retriever retrieval.Retriever, enricher *Enricher) Retriever {
+	return Retriever{retriever, enricher}
+}
@@ -0,0 +1,77 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnricher_Enrich(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body><nav>Menu</nav><article><p>The full article text.</p></article></body></html>`)
+	})
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("fetched page disallowed by robots.txt")
+	})
+	mux.HandleFunc("/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		fmt.Fprint(w, "%PDF-1.4")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	webDoc := func(path string) document.Document {
+		return document.Document{
+			Passages:     []document.Passage{{Text: "snippet"}},
+			Corpus:       document.Web,
+			WebReference: &document.WebReference{Link: server.URL + path},
+		}
+	}
+	docs := []document.Document{webDoc("/article"), webDoc("/private"), webDoc("/report.pdf"), webDoc("/missing"), {Title: "no link"}}
+
+	tests := []struct {
+		name   string
+		append bool
+		want   []string
+	}{
+		{name: "replace", want: []string{"The full article text."}},
+		{name: "append", append: true, want: []string{"snippet", "The full article text."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enricher := NewEnricher(server.Client(), Config{Append: tt.append})
+			enriched, err := enricher.Enrich(context.Background(), docs)
+			if err != nil {
+				t.Fatalf("Enrich() error = %v", err)
+			}
+
+			var got []string
+			for _, p := range enriched[0].Passages {
+				got = append(got, p.Text)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("enriched passages = %q, want %q", got, tt.want)
+			}
+
+			// Pages that can't be used leave the document as it was
+			for i := 1; i < len(docs); i++ {
+				if len(enriched[i].Passages) != len(docs[i].Passages) {
+					t.Errorf("document %d passages changed to %v", i, enriched[i].Passages)
+				}
+			}
+			if docs[0].Passages[0].Text != "snippet" {
+				t.Errorf("Enrich() modified its input")
+			}
+		})
+	}
+}
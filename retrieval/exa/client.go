@@ -5,20 +5,34 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"github.com/coopslarhette/raglib/lib/retrieval/ratelimit"
 	"io"
 	"net/http"
 	"time"
 )
 
+const defaultBaseURL = "https://api.exa.ai"
+
 // Client is the HTTP client for querying Exa API endpoints
 type Client struct {
-	apiKey string
-	client *http.Client
+	apiKey  string
+	client  *http.Client
+	baseURL string
+	limiter *ratelimit.Limiter
 }
 
 type Contents struct {
 	Text       *TextContent       `json:"text,omitempty"`
 	Highlights *HighlightsContent `json:"highlights,omitempty"`
+	Summary    *SummaryContent    `json:"summary,omitempty"`
+	// Subpages is the number of pages linked from each result to crawl and return alongside it
+	Subpages int `json:"subpages,omitempty"`
+	// SubpageTarget are terms used to pick which subpages to crawl, e.g. "docs" or "pricing"
+	SubpageTarget []string `json:"subpageTarget,omitempty"`
+	// Livecrawl is one of "never", "fallback", "always" or "auto", and controls whether Exa crawls pages live
+	// rather than serving its cached copy
+	Livecrawl string `json:"livecrawl,omitempty"`
 }
 
 type TextContent struct {
@@ -54,6 +68,40 @@ type SearchRequest struct {
 	Contents           *Contents  `json:"contents,omitempty"`
 }
 
+// FindSimilarRequest represents the request structure for the Exa API findSimilar endpoint, which finds pages
+// similar to the page at URL
+type FindSimilarRequest struct {
+	URL                 string     `json:"url"`
+	NumResults          int        `json:"numResults"`
+	Category            string     `json:"category,omitempty"`
+	IncludeDomains      []string   `json:"includeDomains,omitempty"`
+	ExcludeDomains      []string   `json:"excludeDomains,omitempty"`
+	StartCrawlDate      *time.Time `json:"startCrawlDate,omitempty"`
+	EndCrawlDate        *time.Time `json:"endCrawlDate,omitempty"`
+	StartPublishedDate  *time.Time `json:"startPublishedDate,omitempty"`
+	EndPublishedDate    *time.Time `json:"endPublishedDate,omitempty"`
+	IncludeText         []string   `json:"includeText,omitempty"`
+	ExcludeText         []string   `json:"excludeText,omitempty"`
+	ExcludeSourceDomain bool       `json:"excludeSourceDomain,omitempty"`
+	Contents            *Contents  `json:"contents,omitempty"`
+}
+
+// ContentsRequest represents the request structure for the Exa API contents endpoint, which returns the contents of
+// known pages. Pages are given either by URL or by the ID of an earlier search result.
+type ContentsRequest struct {
+	URLs []string `json:"urls,omitempty"`
+	IDs  []string `json:"ids,omitempty"`
+	Contents
+}
+
+// AnswerRequest represents the request structure for the Exa API answer endpoint, which answers a query with an LLM
+// grounded in Exa search results
+type AnswerRequest struct {
+	Query string `json:"query"`
+	// Text includes the full text of each citation in the response
+	Text bool `json:"text,omitempty"`
+}
+
 type SearchResponse struct {
 	Results            []SearchResult `json:"results"`
 	ResolvedSearchType string         `json:"resolvedSearchType,omitempty"`
@@ -67,28 +115,100 @@ type SearchResult struct {
 	Author          string    `json:"author,omitempty"`
 	Score           float64   `json:"score,omitempty"`
 	ID              string    `json:"id,omitempty"`
+	Image           string    `json:"image,omitempty"`
+	Favicon         string    `json:"favicon,omitempty"`
 	Text            string    `json:"text,omitempty"`
 	Highlights      []string  `json:"highlights,omitempty"`
 	HighlightScores []float64 `json:"highlightScores,omitempty"`
 	Summary         string    `json:"summary,omitempty"`
+	// Subpages holds the crawled subpages of the result when Contents.Subpages was requested
+	Subpages []SearchResult `json:"subpages,omitempty"`
+}
+
+// ContentsStatus reports whether the contents of a single requested page could be retrieved
+type ContentsStatus struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  *struct {
+		Tag            string `json:"tag"`
+		HTTPStatusCode int    `json:"httpStatusCode"`
+	} `json:"error,omitempty"`
+}
+
+type ContentsResponse struct {
+	Results  []SearchResult   `json:"results"`
+	Statuses []ContentsStatus `json:"statuses,omitempty"`
+}
+
+type AnswerResponse struct {
+	Answer    string         `json:"answer"`
+	Citations []SearchResult `json:"citations"`
 }
 
 func NewClient(apiKey string, client *http.Client) *Client {
 	return &Client{
-		apiKey: apiKey,
-		client: client,
+		apiKey:  apiKey,
+		client:  client,
+		baseURL: defaultBaseURL,
 	}
 }
 
 func (c *Client) Search(ctx context.Context, request SearchRequest) (*SearchResponse, error) {
+	var result SearchResponse
+	if err := c.post(ctx, "/search", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindSimilar returns pages similar to the page at request.URL
+func (c *Client) FindSimilar(ctx context.Context, request FindSimilarRequest) (*SearchResponse, error) {
+	var result SearchResponse
+	if err := c.post(ctx, "/findSimilar", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Contents returns the contents of the requested pages
+func (c *Client) Contents(ctx context.Context, request ContentsRequest) (*ContentsResponse, error) {
+	var result ContentsResponse
+	if err := c.post(ctx, "/contents", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Answer returns an LLM generated answer to request.Query, along with the search results it cites
+func (c *Client) Answer(ctx context.Context, request AnswerRequest) (*AnswerResponse, error) {
+	var result AnswerResponse
+	if err := c.post(ctx, "/answer", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetLimiter throttles, retries and meters the client's requests with limiter. Exa bills every endpoint per request,
+// so one limiter covers them all.
+func (c *Client) SetLimiter(limiter *ratelimit.Limiter) {
+	c.limiter = limiter
+}
+
+func (c *Client) post(ctx context.Context, path string, request any, result any) error {
 	requestBody, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling request body: %v", err)
+		return fmt.Errorf("error marshaling request body: %v", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.exa.ai/search", bytes.NewBuffer(requestBody))
+	return c.limiter.Do(ctx, func(ctx context.Context) error {
+		return c.send(ctx, path, requestBody, result)
+	})
+}
+
+func (c *Client) send(ctx context.Context, path string, requestBody []byte, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewBuffer(requestBody))
 	if err != nil {
-		return nil, fmt.Errorf("error constructing request for Exa API: %v", err)
+		return fmt.Errorf("error constructing request for Exa API: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -96,23 +216,22 @@ func (c *Client) Search(ctx context.Context, request SearchRequest) (*SearchResp
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error while executing request to Exa API: %v", err)
+		return fmt.Errorf("error while executing request to Exa API: %v", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response status code is not OK; recieved code: %v", resp.StatusCode)
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading Exa API response body: %v", err)
+		return fmt.Errorf("error reading Exa API response body: %v", err)
 	}
 
-	var result SearchResponse
-	if err = json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("error parsing Exa API response: %v", err)
+	if resp.StatusCode != http.StatusOK {
+		return apierror.FromResponse("exa", resp, body)
 	}
 
-	return &result, nil
+	if err = json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("error parsing Exa API response: %v", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,117 @@
+package exa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c := NewClient("key", server.Client())
+	c.baseURL = server.URL
+	return c
+}
+
+func TestClient_Contents(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/contents" || r.Header.Get("x-api-key") != "key" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		// Content options are sent at the top level of the request, next to the urls
+		if body["subpages"] != float64(2) || body["summary"] == nil || body["urls"] == nil {
+			t.Errorf("unexpected request body: %v", body)
+		}
+
+		fmt.Fprint(w, `{"results": [{"url": "https://example.com", "summary": "A summary", "subpages": [{"url": "https://example.com/docs", "text": "Docs"}]}],
+			"statuses": [{"id": "https://example.com", "status": "success"}]}`)
+	})
+
+	resp, err := c.Contents(context.Background(), ContentsRequest{
+		URLs: []string{"https://example.com"},
+		Contents: Contents{
+			Summary:       &SummaryContent{Query: "what is it"},
+			Subpages:      2,
+			SubpageTarget: []string{"docs"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Contents() error = %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Summary != "A summary" || len(resp.Results[0].Subpages) != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestClient_Answer(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/answer" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"answer": "Paris", "citations": [{"url": "https://en.wikipedia.org/wiki/Paris", "title": "Paris"}]}`)
+	})
+
+	resp, err := c.Answer(context.Background(), AnswerRequest{Query: "capital of france"})
+	if err != nil {
+		t.Fatalf("Answer() error = %v", err)
+	}
+	if resp.Answer != "Paris" || len(resp.Citations) != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSimilarRetriever(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/findSimilar" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+
+		var body FindSimilarRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		if body.URL != "https://go.dev/blog/go1.22" || body.NumResults != 2 {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+
+		fmt.Fprint(w, `{"results": [{"url": "https://www.example.com/go-release", "title": "Go release notes", "text": "Loop variables", "favicon": "https://example.com/favicon.ico"}]}`)
+	})
+
+	docs, err := NewSimilarRetriever(c).Query(context.Background(), "https://go.dev/blog/go1.22", 2)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 1 || docs[0].Title != "Go release notes" || docs[0].WebReference.DisplayedLink != "example.com" ||
+		docs[0].WebReference.Favicon != "https://example.com/favicon.ico" {
+		t.Errorf("unexpected documents: %+v", docs)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error": "Service temporarily unavailable"}`)
+	})
+
+	_, err := NewRetriever(c).Query(context.Background(), "q", 1)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Query() error = %v, want *apierror.Error", err)
+	}
+	if apiErr.Message != "Service temporarily unavailable" || apiErr.RequestID != "req-1" || !apiErr.Retryable() {
+		t.Errorf("got error %+v", apiErr)
+	}
+}
@@ -5,29 +5,76 @@ import (
 	"fmt"
 	"github.com/coopslarhette/raglib/lib/document"
 	"github.com/coopslarhette/raglib/lib/retrieval/urls"
+	"sort"
 )
 
+// HighlightsOptions configures a Retriever to use Exa highlights, the sentences of each page most relevant to the
+// query, as passages instead of the page's truncated full text
+type HighlightsOptions struct {
+	// NumSentences is the number of sentences in each highlight
+	NumSentences int
+	// HighlightsPerURL is the number of highlights returned for each page
+	HighlightsPerURL int
+	// IncludeText adds the page's truncated full text as a final passage after the highlights
+	IncludeText bool
+	// CharacterBudget caps the total characters of each document's passages. Highlights are added best first until
+	// the budget runs out, and full text fills whatever is left. 0 means no limit on highlights, and 1000 characters
+	// of full text.
+	CharacterBudget int
+}
+
 // Retriever implements the retrieval.Retriever interface for the Exa search service. It retrieves web documents using their API endpoint.
 type Retriever struct {
-	client *Client
+	client     *Client
+	highlights *HighlightsOptions
+	// similar makes Query treat its query as a URL and return pages similar to it
+	similar bool
 }
 
 func (er Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
-	request := SearchRequest{
-		Query:      query,
-		NumResults: topK,
-		Contents: &Contents{
-			Text: &TextContent{
-				MaxCharacters: 1000,
-			},
+	contents := &Contents{
+		Text: &TextContent{
+			MaxCharacters: 1000,
 		},
-		UseAutoprompt: true,
-		Type:          "auto",
 	}
 
-	result, err := er.client.Search(ctx, request)
+	if er.highlights != nil {
+		contents = &Contents{
+			Highlights: &HighlightsContent{
+				NumSentences:     er.highlights.NumSentences,
+				HighlightsPerUrl: er.highlights.HighlightsPerURL,
+				Query:            query,
+			},
+		}
+		if er.highlights.IncludeText {
+			maxCharacters := er.highlights.CharacterBudget
+			if maxCharacters <= 0 {
+				maxCharacters = 1000
+			}
+			contents.Text = &TextContent{MaxCharacters: maxCharacters}
+		}
+	}
+
+	var result *SearchResponse
+	var err error
+	if er.similar {
+		result, err = er.client.FindSimilar(ctx, FindSimilarRequest{
+			URL:                 query,
+			NumResults:          topK,
+			ExcludeSourceDomain: true,
+			Contents:            contents,
+		})
+	} else {
+		result, err = er.client.Search(ctx, SearchRequest{
+			Query:         query,
+			NumResults:    topK,
+			Contents:      contents,
+			UseAutoprompt: true,
+			Type:          "auto",
+		})
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error querying Exa API: %v", err)
+		return nil, fmt.Errorf("error querying Exa API: %w", err)
 	}
 
 	docs := make([]document.Document, len(result.Results))
@@ -37,11 +84,14 @@ func (er Retriever) Query(ctx context.Context, query string, topK int) ([]docume
 			return nil, fmt.Errorf("error parsing web page url: %v", err)
 		}
 
+		passages := []document.Passage{{Text: r.Text}}
+		if er.highlights != nil {
+			passages = highlightPassages(r, *er.highlights)
+		}
+
 		docs[i] = document.Document{
-			Passages: []document.Passage{
-				{Text: r.Text},
-			},
-			Corpus: document.Web,
+			Passages: passages,
+			Corpus:   document.Web,
 			WebReference: &document.WebReference{
 				Title:         r.Title,
 				Link:          r.URL,
@@ -49,21 +99,81 @@ func (er Retriever) Query(ctx context.Context, query string, topK int) ([]docume
 				Blurb:         r.Summary,
 				Date:          r.PublishedDate,
 				Author:        r.Author,
+				Favicon:       r.Favicon,
+				Thumbnail:     r.Image,
 				APISource:     "exa",
 			},
 			Title: r.Title,
 		}
-
-		// Add highlights as additional passages
-		// TODO: Don't do this for now, revisit
-		//for _, highlight := range r.Highlights {
-		//	docs[i].Passages = append(docs[i].Passages, document.Passage{Text: highlight})
-		//}
 	}
 
 	return docs, nil
 }
 
+// highlightPassages turns a result's highlights into passages ranked by their highlight score, followed by as much
+// of the full text as fits in the character budget when it was requested
+func highlightPassages(r SearchResult, options HighlightsOptions) []document.Passage {
+	passages := make([]document.Passage, len(r.Highlights))
+	for i, h := range r.Highlights {
+		passages[i] = document.Passage{Text: h}
+		if i < len(r.HighlightScores) {
+			passages[i].Score = r.HighlightScores[i]
+		}
+	}
+	sort.SliceStable(passages, func(i, j int) bool {
+		return passages[i].Score > passages[j].Score
+	})
+
+	budget := options.CharacterBudget
+	if budget > 0 {
+		used := 0
+		for i, p := range passages {
+			// The best highlight is always kept, even when it alone is over budget
+			if i > 0 && used+len(p.Text) > budget {
+				passages = passages[:i]
+				break
+			}
+			used += len(p.Text)
+		}
+		budget -= used
+	}
+
+	if options.IncludeText && r.Text != "" {
+		text := r.Text
+		if options.CharacterBudget > 0 && len(text) > budget {
+			text = truncate(text, budget)
+		}
+		if text != "" {
+			passages = append(passages, document.Passage{Text: text})
+		}
+	}
+
+	return passages
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 character
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	for n > 0 && n < len(s) && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
 func NewRetriever(client *Client) Retriever {
-	return Retriever{client}
+	return Retriever{client: client}
+}
+
+// NewHighlightsRetriever creates a Retriever that requests query-specific highlights and maps each one to a passage
+// carrying its highlight score
+func NewHighlightsRetriever(client *Client, options HighlightsOptions) Retriever {
+	return Retriever{client: client, highlights: &options}
+}
+
+// NewSimilarRetriever creates a Retriever whose Query takes a URL rather than a search query, and returns pages
+// similar to it from other domains
+func NewSimilarRetriever(client *Client) Retriever {
+	return Retriever{client: client, similar: true}
 }
@@ -0,0 +1,42 @@
+package exa
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHighlightPassages(t *testing.T) {
+	result := SearchResult{
+		Text:            "Full text of the page.",
+		Highlights:      []string{"second best", "best highlight", "worst"},
+		HighlightScores: []float64{0.5, 0.9, 0.1},
+	}
+
+	tests := []struct {
+		name    string
+		options HighlightsOptions
+		want    []string
+	}{
+		{name: "ranked by score", want: []string{"best highlight", "second best", "worst"}},
+		{name: "budget drops lowest scoring", options: HighlightsOptions{CharacterBudget: 26}, want: []string{"best highlight", "second best"}},
+		{name: "text fills remaining budget", options: HighlightsOptions{IncludeText: true, CharacterBudget: 39}, want: []string{"best highlight", "second best", "worst", "Full text"}},
+		{name: "text without budget", options: HighlightsOptions{IncludeText: true}, want: []string{"best highlight", "second best", "worst", "Full text of the page."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passages := highlightPassages(result, tt.options)
+			got := make([]string, len(passages))
+			for i, p := range passages {
+				got[i] = p.Text
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("highlightPassages() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if p := highlightPassages(result, HighlightsOptions{}); p[0].Score != 0.9 {
+		t.Errorf("top passage score = %v, want 0.9", p[0].Score)
+	}
+}
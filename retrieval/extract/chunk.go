@@ -0,0 +1,93 @@
+package extract
+
+import (
+	"github.com/coopslarhette/raglib/lib/document"
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk packs sections into passages of at most maxChars bytes, breaking on paragraph boundaries where possible.
+// Passages never span sections, and each section's heading starts its first passage. A maxChars of 0 or less makes
+// one passage per section.
+func Chunk(sections []Section, maxChars int) []document.Passage {
+	var passages []document.Passage
+	for _, s := range sections {
+		var paragraphs []string
+		if s.Heading != "" {
+			paragraphs = append(paragraphs, s.Heading)
+		}
+		for _, p := range strings.Split(s.Text, "\n\n") {
+			if p = strings.TrimSpace(p); p != "" {
+				paragraphs = append(paragraphs, p)
+			}
+		}
+
+		for _, text := range pack(paragraphs, maxChars) {
+			passages = append(passages, document.Passage{Text: text})
+		}
+	}
+	return passages
+}
+
+// pack greedily joins paragraphs into chunks of at most maxChars bytes, splitting paragraphs that are too long on
+// their own
+func pack(paragraphs []string, maxChars int) []string {
+	if maxChars <= 0 {
+		if len(paragraphs) == 0 {
+			return nil
+		}
+		return []string{strings.Join(paragraphs, "\n\n")}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, p := range paragraphs {
+		for _, piece := range split(p, maxChars) {
+			if current.Len() > 0 && current.Len()+2+len(piece) > maxChars {
+				flush()
+			}
+			if current.Len() > 0 {
+				current.WriteString("\n\n")
+			}
+			current.WriteString(piece)
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// split breaks text into pieces of at most maxChars bytes, preferring to break after a sentence and otherwise at a
+// space
+func split(text string, maxChars int) []string {
+	var pieces []string
+	for len(text) > maxChars {
+		cut := strings.LastIndex(text[:maxChars], ". ")
+		if cut > 0 {
+			cut++
+		} else if cut = strings.LastIndexByte(text[:maxChars], ' '); cut <= 0 {
+			// No good place to break, so break mid-word without splitting a rune
+			cut = maxChars
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = maxChars
+			}
+		}
+
+		pieces = append(pieces, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		pieces = append(pieces, text)
+	}
+	return pieces
+}
@@ -0,0 +1,404 @@
+package extract
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Section is a run of an article's content under a single heading
+type Section struct {
+	// Heading is empty for content before the first heading
+	Heading string
+	// Level is the heading level, 1 for <h1> through 6 for <h6>, and 0 when there is no heading
+	Level int
+	Text  string
+}
+
+// Article is the main content of a web page with navigation, ads and other boilerplate removed
+type Article struct {
+	Title       string
+	Description string
+	Author      string
+	// Published is the publication date as the page reports it, usually ISO 8601
+	Published string
+	Sections  []Section
+	// Canonical is the URL the page names as its preferred address with <link rel="canonical">, as written
+	Canonical string
+	// Robots is the content of the page's robots meta tag, e.g. "noindex, nofollow"
+	Robots string
+	// Links are the hrefs of every link on the page, as written and including those in navigation and other
+	// boilerplate, for crawlers to follow
+	Links []string
+}
+
+// Text returns the article's content as plain text, with headings on their own lines
+func (a *Article) Text() string {
+	parts := make([]string, 0, 2*len(a.Sections))
+	for _, s := range a.Sections {
+		if s.Heading != "" {
+			parts = append(parts, s.Heading)
+		}
+		if s.Text != "" {
+			parts = append(parts, s.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+var (
+	// removedElements never contain article content
+	removedElements = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true, atom.Nav: true,
+		atom.Footer: true, atom.Aside: true, atom.Form: true, atom.Iframe: true, atom.Svg: true, atom.Button: true,
+		atom.Select: true, atom.Input: true, atom.Textarea: true, atom.Canvas: true, atom.Dialog: true,
+		atom.Object: true, atom.Embed: true, atom.Audio: true, atom.Video: true, atom.Picture: true, atom.Img: true,
+	}
+
+	// removedRoles mark landmarks that aren't the page's main content
+	removedRoles = map[string]bool{
+		"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "dialog": true,
+		"alert": true, "menu": true, "menubar": true, "search": true,
+	}
+
+	// boilerplatePattern matches class names and ids of page chrome, unless contentPattern matches too
+	boilerplatePattern = regexp.MustCompile(`(?i)comment|footer|sidebar|side-bar|widget|breadcrumb|\bnav|menu|share|sharing|social|related|recommend|promo|advert|sponsor|\bads?\b|cookie|consent|banner|popup|modal|newsletter|subscribe|signup|masthead|toolbar|pagination|pager|skip-link`)
+	contentPattern     = regexp.MustCompile(`(?i)article|body|content|entry|main|post|text|story`)
+
+	// blockElements start a new paragraph of text
+	blockElements = map[atom.Atom]bool{
+		atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Dd: true, atom.Details: true,
+		atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true, atom.Header: true,
+		atom.Hr: true, atom.Li: true, atom.Main: true, atom.Ol: true, atom.P: true, atom.Section: true,
+		atom.Summary: true, atom.Table: true, atom.Tr: true, atom.Td: true, atom.Th: true, atom.Ul: true,
+		atom.Caption: true,
+	}
+
+	headingLevels = map[atom.Atom]int{atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6}
+)
+
+// FromHTML extracts the main content of the HTML page read from r, which must be UTF-8 encoded. The content is found
+// with a simplified version of the heuristics used by Mozilla's Readability: semantic <article> and <main> elements
+// are preferred, and otherwise the element whose paragraphs hold the most non-link text is chosen.
+func FromHTML(r io.Reader) (*Article, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HTML: %v", err)
+	}
+
+	a := &Article{}
+	readMetadata(root, a)
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.A {
+			if href := strings.TrimSpace(attr(n, "href")); href != "" {
+				a.Links = append(a.Links, href)
+			}
+		}
+		return true
+	})
+
+	prune(root, false)
+	content := mainContent(root)
+	if content == nil {
+		return a, nil
+	}
+
+	if a.Title == "" {
+		if h1 := find(content, func(n *html.Node) bool { return n.DataAtom == atom.H1 }); h1 != nil {
+			a.Title = collapseWhitespace(textContent(h1))
+		}
+	}
+
+	b := &sectionBuilder{}
+	b.walk(content)
+	b.flushSection()
+	a.Sections = b.sections
+
+	return a, nil
+}
+
+// readMetadata fills in the article's title and other details from the document head
+func readMetadata(root *html.Node, a *Article) {
+	var title, ogTitle string
+	walk(root, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Title:
+			if title == "" {
+				title = collapseWhitespace(textContent(n))
+			}
+		case atom.Meta:
+			name := strings.ToLower(attr(n, "name") + attr(n, "property"))
+			content := strings.TrimSpace(attr(n, "content"))
+			switch name {
+			case "og:title":
+				ogTitle = content
+			case "description", "og:description":
+				if a.Description == "" {
+					a.Description = content
+				}
+			case "author", "article:author":
+				if a.Author == "" {
+					a.Author = content
+				}
+			case "article:published_time", "datepublished", "date":
+				if a.Published == "" {
+					a.Published = content
+				}
+			case "robots":
+				a.Robots = content
+			}
+		case atom.Link:
+			if a.Canonical == "" && strings.EqualFold(attr(n, "rel"), "canonical") {
+				a.Canonical = strings.TrimSpace(attr(n, "href"))
+			}
+		case atom.Body:
+			return false
+		}
+		return true
+	})
+
+	// og:title usually leaves out the " | Site Name" suffix that <title> has
+	a.Title = ogTitle
+	if a.Title == "" {
+		a.Title = title
+	}
+}
+
+// prune removes elements that never hold article content. header elements are only removed outside of the main
+// content, where they hold the site's masthead rather than the article's title.
+func prune(n *html.Node, inContent bool) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode {
+			if isBoilerplate(c, inContent) {
+				n.RemoveChild(c)
+			} else {
+				prune(c, inContent || c.DataAtom == atom.Article || c.DataAtom == atom.Main)
+			}
+		} else if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+func isBoilerplate(n *html.Node, inContent bool) bool {
+	if removedElements[n.DataAtom] || n.DataAtom == atom.Header && !inContent {
+		return true
+	}
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" || removedRoles[attr(n, "role")] {
+		return true
+	}
+
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Article, atom.Main:
+		return false
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return boilerplatePattern.MatchString(names) && !contentPattern.MatchString(names)
+}
+
+// mainContent finds the element holding the page's main content
+func mainContent(root *html.Node) *html.Node {
+	var semantic []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main || attr(n, "role") == "main" ||
+			attr(n, "itemprop") == "articleBody" {
+			semantic = append(semantic, n)
+		}
+		return true
+	})
+
+	var best *html.Node
+	bestLength := 0
+	for _, n := range semantic {
+		if length := len(collapseWhitespace(textContent(n))); length > bestLength {
+			best, bestLength = n, length
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	if best = bestScoredCandidate(root); best != nil {
+		return best
+	}
+	return find(root, func(n *html.Node) bool { return n.DataAtom == atom.Body })
+}
+
+// bestScoredCandidate scores the parents of paragraphs by how much prose they hold, penalizing link-heavy elements
+func bestScoredCandidate(root *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Td && n.DataAtom != atom.Blockquote {
+			return true
+		}
+
+		text := collapseWhitespace(textContent(n))
+		if len(text) < 25 {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		if parent := n.Parent; parent != nil && parent.Type == html.ElementNode {
+			scores[parent] += score
+			if grandparent := parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+				scores[grandparent] += score / 2
+			}
+		}
+		return false
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, score := range scores {
+		score *= 1 - linkDensity(n)
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// linkDensity is the fraction of n's text that is inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(collapseWhitespace(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+
+	linked := 0
+	walk(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.A {
+			linked += len(collapseWhitespace(textContent(c)))
+			return false
+		}
+		return true
+	})
+	return float64(linked) / float64(total)
+}
+
+// sectionBuilder turns content into sections of paragraphs split on headings
+type sectionBuilder struct {
+	sections   []Section
+	current    Section
+	paragraphs []string
+	line       strings.Builder
+}
+
+func (b *sectionBuilder) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.line.WriteString(n.Data)
+		return
+	case html.ElementNode, html.DocumentNode:
+	default:
+		return
+	}
+
+	if level, ok := headingLevels[n.DataAtom]; ok {
+		b.flushSection()
+		b.current = Section{Heading: collapseWhitespace(textContent(n)), Level: level}
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		b.flushLine()
+		return
+	case atom.Pre:
+		b.flushLine()
+		if text := strings.Trim(textContent(n), "\n"); strings.TrimSpace(text) != "" {
+			b.paragraphs = append(b.paragraphs, text)
+		}
+		return
+	}
+
+	block := blockElements[n.DataAtom]
+	if block {
+		b.flushLine()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.walk(c)
+	}
+	if block {
+		b.flushLine()
+	}
+}
+
+func (b *sectionBuilder) flushLine() {
+	if text := collapseWhitespace(b.line.String()); text != "" {
+		b.paragraphs = append(b.paragraphs, text)
+	}
+	b.line.Reset()
+}
+
+func (b *sectionBuilder) flushSection() {
+	b.flushLine()
+	if b.current.Heading != "" || len(b.paragraphs) > 0 {
+		b.current.Text = strings.Join(b.paragraphs, "\n\n")
+		b.sections = append(b.sections, b.current)
+	}
+	b.current = Section{}
+	b.paragraphs = nil
+}
+
+// walk visits n and its descendants depth first, skipping the descendants of any node visit returns false for
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func find(n *html.Node, match func(*html.Node) bool) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if c.Type == html.ElementNode && match(c) {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,127 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>How to brew coffee | Example Blog</title>
+  <meta property="og:title" content="How to brew coffee">
+  <meta name="author" content="Jane Doe">
+  <meta property="article:published_time" content="2024-03-01T09:00:00Z">
+  <meta name="robots" content="noarchive">
+  <link rel="canonical" href="https://example.com/coffee">
+  <script>var tracking = true;</script>
+</head>
+<body>
+  <header class="site-header"><a href="/">Example Blog</a></header>
+  <nav><a href="/a">Home</a> <a href="/b">About</a></nav>
+  <div class="layout">
+    <div class="content">
+      <p>Brewing great coffee starts with freshly ground beans, clean water, and the right ratio.</p>
+      <h2>Grinding</h2>
+      <p>Use a burr grinder, set it to medium-fine for pour-over, and grind right before brewing.</p>
+      <pre>ratio = 1:16</pre>
+      <div class="share-buttons">Share on Twitter</div>
+      <h2>Pouring</h2>
+      <p>Bloom the grounds for thirty seconds, then pour slowly in circles, keeping the bed level.</p>
+    </div>
+    <div class="sidebar"><p>Popular posts, newsletter signup, and other things that are not the article.</p></div>
+  </div>
+  <footer>Copyright Example Blog</footer>
+</body>
+</html>`
+
+func TestFromHTML(t *testing.T) {
+	a, err := FromHTML(strings.NewReader(articlePage))
+	if err != nil {
+		t.Fatalf("FromHTML() error = %v", err)
+	}
+
+	if a.Title != "How to brew coffee" {
+		t.Errorf("Title = %q", a.Title)
+	}
+	if a.Author != "Jane Doe" || a.Published != "2024-03-01T09:00:00Z" {
+		t.Errorf("Author = %q, Published = %q", a.Author, a.Published)
+	}
+	if a.Canonical != "https://example.com/coffee" || a.Robots != "noarchive" {
+		t.Errorf("Canonical = %q, Robots = %q", a.Canonical, a.Robots)
+	}
+	// Links in boilerplate are kept for crawlers
+	if got := strings.Join(a.Links, " "); got != "/ /a /b" {
+		t.Errorf("Links = %q, want / /a /b", got)
+	}
+
+	if len(a.Sections) != 3 {
+		t.Fatalf("got %d sections, want 3: %+v", len(a.Sections), a.Sections)
+	}
+	if a.Sections[1].Heading != "Grinding" || a.Sections[1].Level != 2 {
+		t.Errorf("second section heading = %q level %d", a.Sections[1].Heading, a.Sections[1].Level)
+	}
+	if !strings.Contains(a.Sections[1].Text, "ratio = 1:16") {
+		t.Errorf("preformatted text missing from section: %q", a.Sections[1].Text)
+	}
+
+	text := a.Text()
+	for _, boilerplate := range []string{"Home", "Share on Twitter", "Popular posts", "Copyright", "tracking"} {
+		if strings.Contains(text, boilerplate) {
+			t.Errorf("extracted text contains boilerplate %q:\n%s", boilerplate, text)
+		}
+	}
+}
+
+func TestFromHTML_PrefersArticleElement(t *testing.T) {
+	page := `<html><body>
+		<div><p>Some unrelated teaser text that is long enough to be scored, with commas, and more commas.</p></div>
+		<article><h1>Title</h1><p>The article body.</p></article>
+	</body></html>`
+
+	a, err := FromHTML(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("FromHTML() error = %v", err)
+	}
+	if a.Title != "Title" || a.Text() != "Title\n\nThe article body." {
+		t.Errorf("got title %q and text %q", a.Title, a.Text())
+	}
+}
+
+func TestChunk(t *testing.T) {
+	sections := []Section{
+		{Text: "Intro paragraph."},
+		{Heading: "Details", Level: 2, Text: "First paragraph here.\n\nSecond paragraph here.\n\n" + strings.Repeat("word ", 20)},
+	}
+
+	passages := Chunk(sections, 50)
+	want := []string{
+		"Intro paragraph.",
+		"Details\n\nFirst paragraph here.",
+		"Second paragraph here.",
+		strings.TrimSpace(strings.Repeat("word ", 10)),
+		strings.TrimSpace(strings.Repeat("word ", 10)),
+	}
+	if len(passages) != len(want) {
+		t.Fatalf("Chunk() returned %d passages, want %d: %v", len(passages), len(want), passages)
+	}
+	for i, p := range passages {
+		if p.Text != want[i] {
+			t.Errorf("passage %d = %q, want %q", i, p.Text, want[i])
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := map[string]string{
+		"The <strong>Go</strong> language":                      "The Go language",
+		`<span class="searchmatch">Gopher</span> &amp; friends`: "Gopher & friends",
+		"1 &lt; 2":        "1 < 2",
+		"unclosed <b tag": "unclosed <b tag",
+	}
+	for in, want := range tests {
+		if got := PlainText(in); got != want {
+			t.Errorf("PlainText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package extract
+
+import (
+	"fmt"
+	"github.com/ledongthuc/pdf"
+	"io"
+	"strings"
+)
+
+// FromPDF extracts the text of a PDF, with one section per page in page order. Title and Author come from the
+// document's info dictionary when it has one. Scanned pages without a text layer have no text and are left out.
+func FromPDF(r io.ReaderAt, size int64) (article *Article, err error) {
+	// The PDF reader panics on some malformed files rather than returning an error
+	defer func() {
+		if p := recover(); p != nil {
+			article, err = nil, fmt.Errorf("error reading PDF: %v", p)
+		}
+	}()
+
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF: %v", err)
+	}
+
+	info := reader.Trailer().Key("Info")
+	article = &Article{
+		Title:  strings.TrimSpace(info.Key("Title").Text()),
+		Author: strings.TrimSpace(info.Key("Author").Text()),
+	}
+
+	// Fonts are shared between pages, and parsing their character maps is the slow part of extracting text
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("error extracting text of PDF page %d: %v", i, err)
+		}
+		if text = pdfText(text); text != "" {
+			article.Sections = append(article.Sections, Section{Text: text})
+		}
+	}
+
+	return article, nil
+}
+
+// pdfText tidies the text of a PDF page, keeping blank lines as paragraph breaks, joining the lines within a
+// paragraph and rejoining words hyphenated across lines
+func pdfText(text string) string {
+	var paragraphs []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			paragraphs = append(paragraphs, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			flush()
+			continue
+		}
+
+		s := current.String()
+		switch {
+		case current.Len() == 0:
+		case strings.HasSuffix(s, "-") && len(s) > 1 && s[len(s)-2] != ' ':
+			current.Reset()
+			current.WriteString(s[:len(s)-1])
+		default:
+			current.WriteByte(' ')
+		}
+		current.WriteString(line)
+	}
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,94 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a minimal PDF with one page per entry of pages, each page showing its lines of text in Helvetica
+func buildPDF(title string, pages [][]string) []byte {
+	var objects []string
+	pageIDs := make([]int, len(pages))
+	// 1 is the catalog, 2 the page tree, 3 the font and 4 the info dictionary, then a page and its content per page
+	for i, lines := range pages {
+		var content strings.Builder
+		content.WriteString("BT /F1 12 Tf 72 720 Td 14 TL\n")
+		for _, line := range lines {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", line)
+		}
+		content.WriteString("ET")
+
+		pageIDs[i] = 5 + 2*i
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageIDs[i]+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Author (Ada Lovelace) >>", title),
+	}, objects...)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestFromPDF(t *testing.T) {
+	data := buildPDF("Notes on the Analytical Engine", [][]string{
+		{"The Analytical Engine weaves", "algebraic patterns."},
+		{},
+		{"It might act upon other things besides number."},
+	})
+
+	article, err := FromPDF(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("FromPDF() error = %v", err)
+	}
+	if article.Title != "Notes on the Analytical Engine" || article.Author != "Ada Lovelace" {
+		t.Errorf("Title, Author = %q, %q", article.Title, article.Author)
+	}
+	if len(article.Sections) != 2 {
+		t.Fatalf("got %d sections, want 2 with the empty page left out: %+v", len(article.Sections), article.Sections)
+	}
+	for i, want := range []string{"Analytical Engine weaves", "act upon other things"} {
+		if !strings.Contains(article.Sections[i].Text, want) {
+			t.Errorf("section %d = %q, want it to contain %q", i, article.Sections[i].Text, want)
+		}
+	}
+}
+
+func TestFromPDF_Invalid(t *testing.T) {
+	data := []byte("%PDF-1.4\nnot really a pdf")
+	if _, err := FromPDF(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("FromPDF() error = nil, want an error for a malformed PDF")
+	}
+}
+
+func TestPDFText(t *testing.T) {
+	text := "Retrieval aug-\nmented generation\ncombines search\n\n  with   generation. "
+	want := "Retrieval augmented generation combines search\n\nwith generation."
+	if got := pdfText(text); got != want {
+		t.Errorf("pdfText() = %q, want %q", got, want)
+	}
+}
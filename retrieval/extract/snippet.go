@@ -0,0 +1,27 @@
+package extract
+
+import (
+	"html"
+	"strings"
+)
+
+// PlainText removes the tags search APIs put around matched query terms in titles and snippets, e.g. <strong> or
+// <span class="searchmatch">, and unescapes HTML entities. Unlike FromHTML it's meant for short inline markup, not
+// whole pages.
+func PlainText(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			break
+		}
+		b.WriteString(s[:start])
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return html.UnescapeString(b.String())
+}
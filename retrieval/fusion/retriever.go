@@ -0,0 +1,125 @@
+package fusion
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"sort"
+	"sync"
+)
+
+// K is the reciprocal rank fusion constant suggested by Cormack et al., it dampens the advantage of the very top ranks
+// so that agreement across rankings matters more than any single first place.
+const K = 60
+
+// Fused is an item merged by Fuse, identified by the key it was ranked under
+type Fused struct {
+	Key   string
+	Score float64
+}
+
+// Fuse merges rankings, each a list of keys best first, with reciprocal rank fusion using the constant k. A key scores
+// once per ranking, at its first rank in it. Each key is returned once, highest fused score first, with ties kept in the order the keys were first seen.
+func Fuse(rankings [][]string, k int) []Fused {
+	byKey := make(map[string]int)
+	var all []Fused
+	for _, ranking := range rankings {
+		seen := make(map[string]bool, len(ranking))
+		for rank, key := range ranking {
+			// A key listed twice in one ranking only counts at its best rank
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			i, ok := byKey[key]
+			if !ok {
+				i = len(all)
+				byKey[key] = i
+				all = append(all, Fused{Key: key})
+			}
+			all[i].Score += 1 / float64(k+rank+1)
+		}
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Score > all[j].Score
+	})
+	return all
+}
+
+// Retriever implements the retrieval.Retriever interface by querying several retrievers concurrently and merging
+// their rankings with reciprocal rank fusion. Scores from different backends, e.g. BM25 and cosine similarity, aren't
+// comparable, so only each document's rank in each list is used.
+type Retriever struct {
+	retrievers []retrieval.Retriever
+	k          int
+}
+
+func (fr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	rankings := make([][]document.Document, len(fr.retrievers))
+	errs := make([]error, len(fr.retrievers))
+
+	var wg sync.WaitGroup
+	for i, r := range fr.retrievers {
+		wg.Add(1)
+		go func(i int, r retrieval.Retriever) {
+			defer wg.Done()
+			rankings[i], errs[i] = r.Query(ctx, query, topK)
+		}(i, r)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("error querying retriever %d: %v", i, err)
+		}
+	}
+
+	// A document returned by several retrievers is kept as the first retriever returned it
+	byKey := make(map[string]document.Document)
+	keys := make([][]string, len(rankings))
+	for i, ranking := range rankings {
+		for _, doc := range ranking {
+			key := documentKey(doc)
+			if _, ok := byKey[key]; !ok {
+				byKey[key] = doc
+			}
+			keys[i] = append(keys[i], key)
+		}
+	}
+
+	fused := Fuse(keys, fr.k)
+	if len(fused) > topK {
+		fused = fused[:topK]
+	}
+	docs := make([]document.Document, len(fused))
+	for i, f := range fused {
+		docs[i] = byKey[f.Key]
+	}
+	return docs, nil
+}
+
+// documentKey identifies the same document returned by different retrievers. Web documents are identified by their
+// link, anything else by its title and text.
+func documentKey(doc document.Document) string {
+	if doc.WebReference != nil && doc.WebReference.Link != "" {
+		return doc.WebReference.Link
+	}
+
+	key := doc.Title
+	for _, p := range doc.Passages {
+		key += "\x00" + p.Text
+	}
+	return key
+}
+
+// NewRetriever creates a Retriever that fuses the results of retrievers
+func NewRetriever(retrievers ...retrieval.Retriever) Retriever {
+	return Retriever{retrievers, K}
+}
@@ -0,0 +1,123 @@
+package fusion
+
+import (
+	"context"
+	"errors"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// stubRetriever returns its documents, cut to topK, or its error
+type stubRetriever struct {
+	docs []document.Document
+	err  error
+}
+
+func (s stubRetriever) Query(_ context.Context, _ string, topK int) ([]document.Document, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.docs) > topK {
+		return s.docs[:topK], nil
+	}
+	return s.docs, nil
+}
+
+func webDoc(link string) document.Document {
+	return document.Document{Title: link, WebReference: &document.WebReference{Link: link}}
+}
+
+func titles(docs []document.Document) []string {
+	var ts []string
+	for _, d := range docs {
+		ts = append(ts, d.Title)
+	}
+	return ts
+}
+
+func TestRetriever_Query(t *testing.T) {
+	tests := []struct {
+		name       string
+		retrievers []retrieval.Retriever
+		topK       int
+		want       []string
+	}{
+		{
+			name: "agreement outranks a single first place",
+			retrievers: []retrieval.Retriever{
+				stubRetriever{docs: []document.Document{webDoc("a"), webDoc("b"), webDoc("c")}},
+				stubRetriever{docs: []document.Document{webDoc("d"), webDoc("b"), webDoc("c")}},
+			},
+			topK: 4,
+			want: []string{"b", "c", "a", "d"},
+		},
+		{
+			name: "ties keep first seen order",
+			retrievers: []retrieval.Retriever{
+				stubRetriever{docs: []document.Document{webDoc("a"), webDoc("b")}},
+				stubRetriever{docs: []document.Document{webDoc("c"), webDoc("d")}},
+			},
+			topK: 4,
+			want: []string{"a", "c", "b", "d"},
+		},
+		{
+			name: "duplicates are merged by link",
+			retrievers: []retrieval.Retriever{
+				stubRetriever{docs: []document.Document{webDoc("a")}},
+				stubRetriever{docs: []document.Document{{Title: "a again", WebReference: &document.WebReference{Link: "a"}}}},
+				stubRetriever{docs: []document.Document{{Title: "x", Passages: []document.Passage{{Text: "local"}}}, {Title: "x", Passages: []document.Passage{{Text: "local"}}}}},
+			},
+			topK: 10,
+			want: []string{"a", "x"},
+		},
+		{
+			name: "cut to topK",
+			retrievers: []retrieval.Retriever{
+				stubRetriever{docs: []document.Document{webDoc("a"), webDoc("b"), webDoc("c")}},
+			},
+			topK: 2,
+			want: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := NewRetriever(tt.retrievers...).Query(context.Background(), "q", tt.topK)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if got := titles(docs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetriever_QueryErrors(t *testing.T) {
+	failing := NewRetriever(stubRetriever{docs: []document.Document{webDoc("a")}}, stubRetriever{err: errors.New("unavailable")})
+	if _, err := failing.Query(context.Background(), "q", 5); err == nil {
+		t.Error("Query() error = nil, want the failing retriever's error")
+	}
+
+	if _, err := NewRetriever(stubRetriever{}).Query(context.Background(), "q", -1); err == nil {
+		t.Error("Query() error = nil, want error for negative topK")
+	}
+}
+
+func TestFuse(t *testing.T) {
+	fused := Fuse([][]string{{"a", "b"}, {"b"}}, K)
+	if len(fused) != 2 || fused[0].Key != "b" || fused[1].Key != "a" {
+		t.Fatalf("Fuse() = %v, want b then a", fused)
+	}
+	if want := 1/float64(K+2) + 1/float64(K+1); math.Abs(fused[0].Score-want) > 1e-12 {
+		t.Errorf("Fuse() score of b = %v, want %v", fused[0].Score, want)
+	}
+
+	repeated := Fuse([][]string{{"a", "b", "a"}}, K)
+	if want := 1 / float64(K+1); len(repeated) != 2 || math.Abs(repeated[0].Score-want) > 1e-12 {
+		t.Errorf("Fuse() with a repeated key = %v, want a scored once at rank 1", repeated)
+	}
+}
@@ -0,0 +1,25 @@
+// Package doctext builds the plain text that rankers, rerankers and diversifiers judge a document by.
+package doctext
+
+import (
+	"github.com/coopslarhette/raglib/lib/document"
+	"strings"
+)
+
+// Text joins a document's title, if it has one, and its passages with newlines. When maxBytes is positive the text
+// is cut to at most that many bytes without splitting a UTF-8 sequence.
+func Text(doc document.Document, maxBytes int) string {
+	texts := make([]string, 0, len(doc.Passages)+1)
+	if doc.Title != "" {
+		texts = append(texts, doc.Title)
+	}
+	for _, p := range doc.Passages {
+		texts = append(texts, p.Text)
+	}
+
+	text := strings.Join(texts, "\n")
+	if maxBytes > 0 && len(text) > maxBytes {
+		text = strings.ToValidUTF8(text[:maxBytes], "")
+	}
+	return text
+}
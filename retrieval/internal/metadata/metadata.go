@@ -0,0 +1,18 @@
+// Package metadata holds helpers for the passage metadata that vector store retrievers return.
+package metadata
+
+// Select returns the entries of metadata whose keys are listed in keys, or nil if there are none
+func Select(metadata map[string]string, keys []string) map[string]string {
+	var selected map[string]string
+	for _, key := range keys {
+		value, ok := metadata[key]
+		if !ok {
+			continue
+		}
+		if selected == nil {
+			selected = make(map[string]string, len(keys))
+		}
+		selected[key] = value
+	}
+	return selected
+}
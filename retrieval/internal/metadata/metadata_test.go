@@ -0,0 +1,20 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	m := map[string]string{"parentID": "p1", "lang": "en", "team": "search"}
+
+	if got, want := Select(m, []string{"parentID", "missing"}), map[string]string{"parentID": "p1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Select() = %v, want %v", got, want)
+	}
+	if got := Select(m, nil); got != nil {
+		t.Errorf("Select() without keys = %v, want nil", got)
+	}
+	if got := Select(nil, []string{"parentID"}); got != nil {
+		t.Errorf("Select() of nil metadata = %v, want nil", got)
+	}
+}
@@ -0,0 +1,188 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WikipediaURL returns the API endpoint of the Wikipedia in language, e.g. "en" or "de"
+func WikipediaURL(language string) string {
+	return "https://" + language + ".wikipedia.org/w/api.php"
+}
+
+// Client is the HTTP client for the action API of a MediaWiki wiki. Full article text needs the wiki to have the
+// TextExtracts extension installed, as Wikipedia does.
+type Client struct {
+	apiURL    string
+	userAgent string
+	client    *http.Client
+}
+
+// SearchHit is an article matching a search. Snippet is HTML, with the matching words wrapped in
+// <span class="searchmatch">.
+type SearchHit struct {
+	PageID    int    `json:"pageid"`
+	Title     string `json:"title"`
+	Snippet   string `json:"snippet"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Page is an article along with its plain text
+type Page struct {
+	PageID int    `json:"pageid"`
+	Title  string `json:"title"`
+	// Extract is the article's plain text, with section headings on their own lines marked up like "== History =="
+	Extract      string `json:"extract"`
+	FullURL      string `json:"fullurl"`
+	CanonicalURL string `json:"canonicalurl"`
+	// LastModified is when the article was last edited
+	LastModified string `json:"-"`
+	Missing      bool   `json:"missing"`
+}
+
+// apiError is the error MediaWiki reports in the body of a response, usually with a 200 status code
+type apiError struct {
+	Code string `json:"code"`
+	Info string `json:"info"`
+}
+
+// NewClient creates a Client for the wiki whose api.php is at apiURL, e.g. WikipediaURL("en")
+func NewClient(apiURL string, client *http.Client) *Client {
+	return &Client{
+		apiURL:    apiURL,
+		userAgent: retrieval.DefaultUserAgent,
+		client:    client,
+	}
+}
+
+// SetUserAgent sets the User-Agent requests are sent with. Wikimedia's policy asks for one identifying the application
+// and how to contact its operator.
+func (c *Client) SetUserAgent(userAgent string) {
+	c.userAgent = userAgent
+}
+
+// Search returns up to limit articles matching query, most relevant first
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	params := url.Values{}
+	params.Set("list", "search")
+	params.Set("srsearch", query)
+	params.Set("srlimit", strconv.Itoa(limit))
+	params.Set("srprop", "snippet|timestamp")
+
+	var result struct {
+		Query struct {
+			Search []SearchHit `json:"search"`
+		} `json:"query"`
+	}
+	if err := c.get(ctx, params, &result); err != nil {
+		return nil, err
+	}
+	return result.Query.Search, nil
+}
+
+// Page returns the article titled title, following redirects
+func (c *Client) Page(ctx context.Context, title string) (*Page, error) {
+	params := url.Values{}
+	params.Set("prop", "extracts|info|revisions")
+	params.Set("titles", title)
+	params.Set("redirects", "1")
+	params.Set("explaintext", "1")
+	params.Set("exsectionformat", "wiki")
+	params.Set("inprop", "url")
+	params.Set("rvprop", "timestamp")
+
+	var result struct {
+		Query struct {
+			Pages []struct {
+				Page
+				Revisions []struct {
+					Timestamp string `json:"timestamp"`
+				} `json:"revisions"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	if err := c.get(ctx, params, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Query.Pages) == 0 || result.Query.Pages[0].Missing {
+		return nil, fmt.Errorf("MediaWiki page %q not found", title)
+	}
+	p := result.Query.Pages[0]
+	page := p.Page
+	if len(p.Revisions) > 0 {
+		page.LastModified = p.Revisions[0].Timestamp
+	}
+	return &page, nil
+}
+
+// articleURL guesses the URL of an article from the API endpoint, for when the article itself couldn't be fetched
+func (c *Client) articleURL(title string) string {
+	u, err := url.Parse(c.apiURL)
+	if err != nil {
+		return ""
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/w/api.php") + "/wiki/" + strings.ReplaceAll(title, " ", "_")
+	u.RawQuery = ""
+	return u.String()
+}
+
+func (c *Client) get(ctx context.Context, params url.Values, result any) error {
+	params.Set("action", "query")
+	params.Set("format", "json")
+	params.Set("formatversion", "2")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error constructing request for MediaWiki API: %v", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while executing request to MediaWiki API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading MediaWiki API response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return apierror.FromResponse("mediawiki", resp, body)
+	}
+
+	var envelope struct {
+		Error *apiError `json:"error"`
+	}
+	if err = json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("error parsing MediaWiki API response: %v", err)
+	}
+	if envelope.Error != nil {
+		e := &apierror.Error{
+			Provider:   "mediawiki",
+			StatusCode: resp.StatusCode,
+			Message:    envelope.Error.Code + ": " + envelope.Error.Info,
+			RequestID:  resp.Header.Get("X-Request-Id"),
+		}
+		// MediaWiki reports rate limiting in the body rather than with a 429
+		if envelope.Error.Code == "ratelimited" || envelope.Error.Code == "maxlag" {
+			e.StatusCode = http.StatusTooManyRequests
+		}
+		return e
+	}
+
+	if err = json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("error parsing MediaWiki API response: %v", err)
+	}
+	return nil
+}
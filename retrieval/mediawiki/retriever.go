@@ -0,0 +1,229 @@
+package mediawiki
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/bm25"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxPassageChars = 1500
+	defaultMaxPassages     = 5
+	defaultConcurrency     = 4
+)
+
+// skippedSections are the sections at the end of Wikipedia articles that hold lists of links rather than prose
+var skippedSections = map[string]bool{
+	"references": true, "external links": true, "see also": true, "further reading": true, "notes": true,
+	"bibliography": true, "sources": true, "citations": true, "footnotes": true,
+}
+
+// Config configures a Retriever
+type Config struct {
+	// MaxPassageChars is the maximum size of each passage sections are split into
+	MaxPassageChars int
+	// MaxPassages caps the passages kept from each article. The article's lead is always kept, followed by the
+	// passages sharing the most words with the query.
+	MaxPassages int
+	// Concurrency is how many articles are fetched at once
+	Concurrency int
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxPassageChars <= 0 {
+		c.MaxPassageChars = defaultMaxPassageChars
+	}
+	if c.MaxPassages <= 0 {
+		c.MaxPassages = defaultMaxPassages
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultConcurrency
+	}
+	return c
+}
+
+// Retriever implements the retrieval.Retriever interface for Wikipedia, or any other MediaWiki wiki. It searches the
+// wiki and returns the matching articles' most relevant sections as passages.
+type Retriever struct {
+	client *Client
+	config Config
+}
+
+func (mr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	hits, err := mr.client.Search(ctx, query, topK)
+	if err != nil {
+		return nil, fmt.Errorf("error searching MediaWiki: %w", err)
+	}
+
+	docs := make([]document.Document, len(hits))
+	sem := make(chan struct{}, mr.config.Concurrency)
+	var wg sync.WaitGroup
+	for i, hit := range hits {
+		wg.Add(1)
+		go func(i int, hit SearchHit) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// The search snippet is still worth returning when the article can't be fetched, so errors leave page nil
+			page, _ := mr.client.Page(ctx, hit.Title)
+			docs[i] = mr.toDocument(query, hit, page)
+		}(i, hit)
+	}
+	wg.Wait()
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// toDocument builds a document from an article's sections, or from its search snippet when page is nil or the wiki
+// doesn't return plain text extracts
+func (mr Retriever) toDocument(query string, hit SearchHit, page *Page) document.Document {
+	snippet := extract.PlainText(hit.Snippet)
+	title := hit.Title
+	link := mr.client.articleURL(hit.Title)
+	date := hit.Timestamp
+	passages := []document.Passage{{Text: snippet}}
+
+	if page != nil {
+		title = page.Title
+		if page.CanonicalURL != "" {
+			link = page.CanonicalURL
+		} else if page.FullURL != "" {
+			link = page.FullURL
+		}
+		if page.LastModified != "" {
+			date = page.LastModified
+		}
+		if sectionPassages := extract.Chunk(Sections(page.Extract), mr.config.MaxPassageChars); len(sectionPassages) > 0 {
+			passages = selectPassages(sectionPassages, query, mr.config.MaxPassages)
+		}
+	}
+
+	displayed := link
+	if u, err := url.Parse(link); err == nil {
+		displayed = u.Hostname()
+	}
+
+	return document.Document{
+		Passages: passages,
+		Corpus:   document.Web,
+		WebReference: &document.WebReference{
+			Title:         title,
+			Link:          link,
+			DisplayedLink: displayed,
+			Blurb:         snippet,
+			Date:          date,
+			APISource:     "mediawiki",
+		},
+		Title: title,
+	}
+}
+
+// Sections splits a plain text extract into sections at its "== Heading ==" lines. Each section's heading is the path
+// of headings leading to it, e.g. "History > Early years", and the lead section before the first heading has none.
+// Empty sections and those listing references or links are left out.
+func Sections(extractText string) []extract.Section {
+	var sections []extract.Section
+	var path []string
+	var current extract.Section
+	var paragraphs []string
+
+	flush := func() {
+		current.Text = strings.Join(paragraphs, "\n\n")
+		paragraphs = nil
+		if current.Text == "" || (len(path) > 0 && skippedSections[strings.ToLower(path[0])]) {
+			return
+		}
+		sections = append(sections, current)
+	}
+
+	for _, line := range strings.Split(extractText, "\n") {
+		line = strings.TrimSpace(line)
+		if level, heading, ok := parseHeading(line); ok {
+			flush()
+			// Level 2 headings are the top level sections of an article
+			depth := max(level-2, 0)
+			path = append(path[:min(depth, len(path))], heading)
+			current = extract.Section{Heading: strings.Join(path, " > "), Level: level}
+			continue
+		}
+		if line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	flush()
+
+	return sections
+}
+
+// parseHeading parses a wikitext heading line such as "=== Early years ===" into its level and text
+func parseHeading(line string) (int, string, bool) {
+	if len(line) < 4 || line[0] != '=' || line[len(line)-1] != '=' {
+		return 0, "", false
+	}
+	level := 0
+	for level < len(line) && line[level] == '=' {
+		level++
+	}
+	heading := strings.TrimSpace(strings.Trim(line, "="))
+	if heading == "" {
+		return 0, "", false
+	}
+	return level, heading, true
+}
+
+// selectPassages keeps the lead passage and the passages sharing the most distinct words with query, up to limit in
+// total, in article order. Each passage's Score is the fraction of the query's words it contains.
+func selectPassages(passages []document.Passage, query string, limit int) []document.Passage {
+	terms := make(map[string]bool)
+	for _, t := range bm25.English(query) {
+		terms[t] = true
+	}
+
+	scored := make([]document.Passage, len(passages))
+	for i, p := range passages {
+		scored[i] = p
+		if len(terms) == 0 {
+			continue
+		}
+		matched := make(map[string]bool)
+		for _, t := range bm25.English(p.Text) {
+			if terms[t] {
+				matched[t] = true
+			}
+		}
+		scored[i].Score = float64(len(matched)) / float64(len(terms))
+	}
+	if len(scored) <= limit {
+		return scored
+	}
+
+	order := make([]int, len(scored)-1)
+	for i := range order {
+		order[i] = i + 1
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scored[order[a]].Score > scored[order[b]].Score
+	})
+	keep := append([]int{0}, order[:limit-1]...)
+	sort.Ints(keep)
+
+	selected := make([]document.Passage, len(keep))
+	for i, idx := range keep {
+		selected[i] = scored[idx]
+	}
+	return selected
+}
+
+func NewRetriever(client *Client, config Config) Retriever {
+	return Retriever{client: client, config: config.withDefaults()}
+}
@@ -0,0 +1,132 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const goExtract = `Go is a statically typed, compiled high-level programming language designed at Google.
+
+== History ==
+Go was designed at Google in 2007 to improve programming productivity.
+It was publicly announced in November 2009.
+
+=== Version 1.0 ===
+Version 1.0 was released in March 2012.
+
+== Design ==
+
+=== Concurrency ===
+Go has goroutines and channels.
+
+== See also ==
+Comparison of programming languages`
+
+func TestSections(t *testing.T) {
+	got := Sections(goExtract)
+
+	want := []struct {
+		heading string
+		level   int
+		text    string
+	}{
+		{"", 0, "Go is a statically typed, compiled high-level programming language designed at Google."},
+		{"History", 2, "Go was designed at Google in 2007 to improve programming productivity.\n\nIt was publicly announced in November 2009."},
+		{"History > Version 1.0", 3, "Version 1.0 was released in March 2012."},
+		{"Design > Concurrency", 3, "Go has goroutines and channels."},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Sections() returned %d sections, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Heading != w.heading || got[i].Level != w.level || got[i].Text != w.text {
+			t.Errorf("section %d = %+v, want %q level %d %q", i, got[i], w.heading, w.level, w.text)
+		}
+	}
+}
+
+func TestRetriever_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("format") != "json" || params.Get("formatversion") != "2" || r.Header.Get("User-Agent") == "" {
+			t.Errorf("request %s with User-Agent %q", r.URL, r.Header.Get("User-Agent"))
+		}
+
+		var response any
+		switch {
+		case params.Get("list") == "search":
+			response = map[string]any{"query": map[string]any{"search": []map[string]any{
+				{"title": "Go (programming language)", "snippet": `<span class="searchmatch">Go</span> is a language &amp; more`, "timestamp": "2024-01-01T00:00:00Z"},
+				{"title": "Gopher", "snippet": "A <span class=\"searchmatch\">gopher</span>", "timestamp": "2023-05-01T00:00:00Z"},
+			}}}
+		case params.Get("titles") == "Go (programming language)":
+			response = map[string]any{"query": map[string]any{"pages": []map[string]any{{
+				"title":        "Go (programming language)",
+				"extract":      goExtract,
+				"fullurl":      "https://en.wikipedia.org/wiki/Go_(programming_language)",
+				"canonicalurl": "https://en.wikipedia.org/wiki/Go_(programming_language)",
+				"revisions":    []map[string]any{{"timestamp": "2024-06-30T12:00:00Z"}},
+			}}}}
+		default:
+			response = map[string]any{"query": map[string]any{"pages": []map[string]any{{"title": params.Get("titles"), "missing": true}}}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	retriever := NewRetriever(NewClient(server.URL+"/w/api.php", server.Client()), Config{MaxPassages: 3})
+	docs, err := retriever.Query(context.Background(), "when was go version 1.0 released", 2)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Query() returned %d documents, want 2", len(docs))
+	}
+
+	doc := docs[0]
+	var texts []string
+	for _, p := range doc.Passages {
+		texts = append(texts, p.Text)
+	}
+	wantTexts := []string{
+		"Go is a statically typed, compiled high-level programming language designed at Google.",
+		"History\n\nGo was designed at Google in 2007 to improve programming productivity.\n\nIt was publicly announced in November 2009.",
+		"History > Version 1.0\n\nVersion 1.0 was released in March 2012.",
+	}
+	if !reflect.DeepEqual(texts, wantTexts) {
+		t.Errorf("passages = %q, want %q", texts, wantTexts)
+	}
+	ref := doc.WebReference
+	if ref.Link != "https://en.wikipedia.org/wiki/Go_(programming_language)" || ref.Date != "2024-06-30T12:00:00Z" ||
+		ref.Blurb != "Go is a language & more" || ref.APISource != "mediawiki" {
+		t.Errorf("WebReference = %+v", ref)
+	}
+
+	// An article that can't be fetched falls back to its search snippet
+	fallback := docs[1]
+	if len(fallback.Passages) != 1 || fallback.Passages[0].Text != "A gopher" {
+		t.Errorf("fallback passages = %+v", fallback.Passages)
+	}
+	if want := server.URL + "/wiki/Gopher"; fallback.WebReference.Link != want || fallback.WebReference.Date != "2023-05-01T00:00:00Z" {
+		t.Errorf("fallback WebReference = %+v, want link %s", fallback.WebReference, want)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error": {"code": "ratelimited", "info": "You've exceeded your rate limit."}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewRetriever(NewClient(server.URL, server.Client()), Config{}).Query(context.Background(), "q", 5)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || !apiErr.Retryable() || apiErr.Message != "ratelimited: You've exceeded your rate limit." {
+		t.Errorf("Query() error = %v, want a retryable *apierror.Error", err)
+	}
+}
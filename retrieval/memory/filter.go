@@ -0,0 +1,64 @@
+package memory
+
+// Filter reports whether an entry with the given metadata may be returned from a search
+type Filter func(metadata map[string]string) bool
+
+// Equals matches entries whose metadata value for key is exactly value
+func Equals(key, value string) Filter {
+	return func(metadata map[string]string) bool {
+		v, ok := metadata[key]
+		return ok && v == value
+	}
+}
+
+// In matches entries whose metadata value for key is any of values
+func In(key string, values ...string) Filter {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return func(metadata map[string]string) bool {
+		v, ok := metadata[key]
+		return ok && set[v]
+	}
+}
+
+// Exists matches entries that have any value for key
+func Exists(key string) Filter {
+	return func(metadata map[string]string) bool {
+		_, ok := metadata[key]
+		return ok
+	}
+}
+
+// And matches entries that satisfy every one of filters
+func And(filters ...Filter) Filter {
+	return func(metadata map[string]string) bool {
+		for _, f := range filters {
+			if !f(metadata) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches entries that satisfy at least one of filters
+func Or(filters ...Filter) Filter {
+	return func(metadata map[string]string) bool {
+		for _, f := range filters {
+			if f(metadata) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches entries that don't satisfy f
+func Not(f Filter) Filter {
+	return func(metadata map[string]string) bool {
+		return !f(metadata)
+	}
+}
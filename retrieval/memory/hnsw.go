@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// The HNSW implementation follows Malkov & Yashunin, "Efficient and robust approximate nearest neighbor search using
+// Hierarchical Navigable Small World graphs" (https://arxiv.org/abs/1603.09320), with the simple neighbor selection
+// heuristic.
+
+type candidate struct {
+	id   int
+	dist float32
+}
+
+// candidateHeap is a min-heap of candidates by distance, or a max-heap when max is set
+type candidateHeap struct {
+	items []candidate
+	max   bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.max {
+		return h.items[i].dist > h.items[j].dist
+	}
+	return h.items[i].dist < h.items[j].dist
+}
+
+func (h *candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *candidateHeap) Push(x any) { h.items = append(h.items, x.(candidate)) }
+
+func (h *candidateHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+func (h *candidateHeap) top() candidate { return h.items[0] }
+
+func (ix *Index) maxNeighbors(layer int) int {
+	if layer == 0 {
+		return 2 * ix.config.M
+	}
+	return ix.config.M
+}
+
+func (ix *Index) randomLevel() int {
+	// 1 - Float64() is in (0, 1], which keeps the log finite
+	return int(math.Floor(-math.Log(1-ix.rng.Float64()) * ix.levelMult))
+}
+
+// link connects a freshly appended node into the graph
+func (ix *Index) link(id int) {
+	n := ix.nodes[id]
+	n.level = ix.randomLevel()
+	n.neighbors = make([][]int, n.level+1)
+
+	if ix.entryPoint == -1 {
+		ix.entryPoint = id
+		ix.maxLevel = n.level
+		return
+	}
+
+	ep := ix.entryPoint
+	for layer := ix.maxLevel; layer > n.level; layer-- {
+		ep = ix.searchLayer(n.entry.Vector, ep, 1, layer)[0].id
+	}
+
+	for layer := min(n.level, ix.maxLevel); layer >= 0; layer-- {
+		found := ix.searchLayer(n.entry.Vector, ep, ix.config.EfConstruction, layer)
+
+		neighbors := make([]int, 0, ix.config.M)
+		for _, c := range found {
+			if len(neighbors) == ix.config.M {
+				break
+			}
+			neighbors = append(neighbors, c.id)
+		}
+		n.neighbors[layer] = neighbors
+
+		for _, nb := range neighbors {
+			other := ix.nodes[nb]
+			other.neighbors[layer] = append(other.neighbors[layer], id)
+			if len(other.neighbors[layer]) > ix.maxNeighbors(layer) {
+				other.neighbors[layer] = ix.closest(other.entry.Vector, other.neighbors[layer], ix.maxNeighbors(layer))
+			}
+		}
+
+		ep = found[0].id
+	}
+
+	if n.level > ix.maxLevel {
+		ix.maxLevel = n.level
+		ix.entryPoint = id
+	}
+}
+
+// closest returns the limit ids nearest to vector
+func (ix *Index) closest(vector []float32, ids []int, limit int) []int {
+	candidates := make([]candidate, len(ids))
+	for i, id := range ids {
+		candidates[i] = candidate{id, ix.config.Metric.distance(vector, ix.nodes[id].entry.Vector)}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].dist < candidates[j].dist
+	})
+
+	out := make([]int, 0, limit)
+	for _, c := range candidates[:limit] {
+		out = append(out, c.id)
+	}
+	return out
+}
+
+// searchLayer returns up to ef nodes on layer closest to vector, nearest first, starting the walk from ep
+func (ix *Index) searchLayer(vector []float32, ep int, ef int, layer int) []candidate {
+	start := candidate{ep, ix.config.Metric.distance(vector, ix.nodes[ep].entry.Vector)}
+	visited := map[int]bool{ep: true}
+	candidates := &candidateHeap{items: []candidate{start}}
+	results := &candidateHeap{items: []candidate{start}, max: true}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(candidate)
+		if results.Len() >= ef && c.dist > results.top().dist {
+			break
+		}
+
+		for _, nb := range ix.nodes[c.id].neighbors[layer] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+
+			d := ix.config.Metric.distance(vector, ix.nodes[nb].entry.Vector)
+			if results.Len() < ef || d < results.top().dist {
+				heap.Push(candidates, candidate{nb, d})
+				heap.Push(results, candidate{nb, d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sort.Slice(results.items, func(i, j int) bool {
+		return results.items[i].dist < results.items[j].dist
+	})
+	return results.items
+}
+
+// searchGraph returns at least topK live nodes matching filter when that many exist, nearest first. Deleted and
+// filtered out nodes still take up room in the candidate list, so the list is widened until enough survive.
+func (ix *Index) searchGraph(vector []float32, topK int, filter Filter) []candidate {
+	ep := ix.entryPoint
+	for layer := ix.maxLevel; layer > 0; layer-- {
+		ep = ix.searchLayer(vector, ep, 1, layer)[0].id
+	}
+
+	ef := max(ix.config.EfSearch, topK)
+	for {
+		found := ix.searchLayer(vector, ep, ef, 0)
+
+		matched := make([]candidate, 0, len(found))
+		for _, c := range found {
+			if ix.matches(ix.nodes[c.id], filter) {
+				matched = append(matched, c)
+			}
+		}
+
+		if len(matched) >= topK || ef >= len(ix.nodes) {
+			return matched
+		}
+		ef *= 2
+	}
+}
@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/vecmath"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Mode selects how an Index searches its vectors
+type Mode int
+
+const (
+	// BruteForce compares the query against every vector. Exact, and fast enough for a few thousand entries.
+	BruteForce Mode = iota
+	// HNSW searches an approximate nearest neighbor graph, trading a little recall for much faster queries on larger
+	// corpora.
+	HNSW
+)
+
+const (
+	defaultM              = 16
+	defaultEfConstruction = 200
+	defaultEfSearch       = 50
+)
+
+// Config controls the behavior of an Index. The HNSW fields are ignored in BruteForce mode, and zero values fall back
+// to sensible defaults.
+type Config struct {
+	Metric Metric
+	Mode   Mode
+	// M is the maximum number of neighbors each node keeps on the upper layers of the graph, layer 0 keeps 2*M
+	M int
+	// EfConstruction is the size of the candidate list used while inserting, higher builds a better graph slower
+	EfConstruction int
+	// EfSearch is the size of the candidate list used while querying, higher improves recall at the cost of speed
+	EfSearch int
+	// Seed makes the layer assignment of HNSW nodes, and thus the built graph, reproducible
+	Seed int64
+}
+
+func (c Config) withDefaults() Config {
+	if c.M <= 0 {
+		c.M = defaultM
+	}
+	if c.EfConstruction <= 0 {
+		c.EfConstruction = defaultEfConstruction
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = defaultEfSearch
+	}
+	return c
+}
+
+// Entry is a single item stored in an Index
+type Entry struct {
+	// ID uniquely identifies the entry, adding an entry with an existing ID replaces it. When empty the Index assigns
+	// one.
+	ID       string
+	Document document.Document
+	Metadata map[string]string
+	// Vector is the embedding of Document. It's stored normalized when the Index uses the Cosine metric.
+	Vector []float32
+}
+
+// Result is an Entry matched by a search, along with its similarity to the query. Higher scores are more similar.
+type Result struct {
+	Entry Entry
+	Score float32
+}
+
+type node struct {
+	entry   Entry
+	deleted bool
+	level   int
+	// neighbors[l] holds the ids of the node's neighbors on layer l of the HNSW graph
+	neighbors [][]int
+}
+
+// Index is an in-process vector index. It is safe for concurrent use.
+type Index struct {
+	mu     sync.RWMutex
+	config Config
+	dim    int
+	nodes  []*node
+	ids    map[string]int
+	nextID int
+	live   int
+
+	// HNSW state
+	entryPoint int
+	maxLevel   int
+	levelMult  float64
+	rng        *rand.Rand
+}
+
+func NewIndex(config Config) *Index {
+	config = config.withDefaults()
+	return &Index{
+		config:     config,
+		ids:        make(map[string]int),
+		entryPoint: -1,
+		levelMult:  1 / math.Log(float64(config.M)),
+		rng:        rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// Len returns the number of entries in the index
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.live
+}
+
+// Get returns the entry with the given id
+func (ix *Index) Get(id string) (Entry, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	i, ok := ix.ids[id]
+	if !ok {
+		return Entry{}, false
+	}
+	return ix.nodes[i].entry, true
+}
+
+// Add inserts entries into the index, replacing any existing entries with the same ID. Every entry must have a
+// Vector, and all vectors in an index must share the same dimension.
+func (ix *Index) Add(entries ...Entry) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	// An empty index takes the dimension of the first entry, but only once the whole batch is known to be valid
+	dim := ix.dim
+	for _, e := range entries {
+		if len(e.Vector) == 0 {
+			return fmt.Errorf("entry %q has no vector", e.ID)
+		}
+		if dim == 0 {
+			dim = len(e.Vector)
+		}
+		if len(e.Vector) != dim {
+			return fmt.Errorf("entry %q has vector of dimension %d, index has dimension %d", e.ID, len(e.Vector), dim)
+		}
+	}
+	ix.dim = dim
+
+	for _, e := range entries {
+		if e.ID == "" {
+			e.ID = ix.assignID()
+		}
+		if ix.config.Metric == Cosine {
+			e.Vector = vecmath.Normalize(e.Vector)
+		} else {
+			e.Vector = append([]float32(nil), e.Vector...)
+		}
+
+		ix.delete(e.ID)
+		ix.insert(e)
+	}
+
+	return nil
+}
+
+// Delete removes the entries with the given ids, ids that aren't in the index are ignored
+func (ix *Index) Delete(ids ...string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, id := range ids {
+		ix.delete(id)
+	}
+}
+
+// Search returns the topK entries most similar to vector that satisfy filter, most similar first. A nil filter
+// matches every entry.
+func (ix *Index) Search(vector []float32, topK int, filter Filter) ([]Result, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if ix.live == 0 || topK == 0 {
+		return nil, nil
+	}
+	if len(vector) != ix.dim {
+		return nil, fmt.Errorf("query vector has dimension %d, index has dimension %d", len(vector), ix.dim)
+	}
+	if ix.config.Metric == Cosine {
+		vector = vecmath.Normalize(vector)
+	}
+
+	var candidates []candidate
+	if ix.config.Mode == HNSW {
+		candidates = ix.searchGraph(vector, topK, filter)
+	} else {
+		candidates = ix.searchAll(vector, filter)
+	}
+
+	if len(candidates) > topK {
+		candidates = candidates[:topK]
+	}
+	results := make([]Result, len(candidates))
+	for i, c := range candidates {
+		results[i] = Result{
+			Entry: ix.nodes[c.id].entry,
+			Score: ix.config.Metric.score(c.dist),
+		}
+	}
+	return results, nil
+}
+
+func (ix *Index) assignID() string {
+	for {
+		ix.nextID++
+		id := strconv.Itoa(ix.nextID)
+		if _, taken := ix.ids[id]; !taken {
+			return id
+		}
+	}
+}
+
+func (ix *Index) insert(e Entry) {
+	id := len(ix.nodes)
+	ix.nodes = append(ix.nodes, &node{entry: e})
+	ix.ids[e.ID] = id
+	ix.live++
+
+	if ix.config.Mode == HNSW {
+		ix.link(id)
+	}
+}
+
+func (ix *Index) delete(id string) {
+	i, ok := ix.ids[id]
+	if !ok {
+		return
+	}
+	delete(ix.ids, id)
+	ix.live--
+
+	// Graph nodes are still needed to route searches through, so they are only marked as deleted
+	if ix.config.Mode == HNSW {
+		ix.nodes[i].deleted = true
+		return
+	}
+
+	last := len(ix.nodes) - 1
+	if i != last {
+		ix.nodes[i] = ix.nodes[last]
+		ix.ids[ix.nodes[i].entry.ID] = i
+	}
+	ix.nodes[last] = nil
+	ix.nodes = ix.nodes[:last]
+}
+
+func (ix *Index) matches(n *node, filter Filter) bool {
+	return !n.deleted && (filter == nil || filter(n.entry.Metadata))
+}
+
+func (ix *Index) searchAll(vector []float32, filter Filter) []candidate {
+	var candidates []candidate
+	for i, n := range ix.nodes {
+		if !ix.matches(n, filter) {
+			continue
+		}
+		candidates = append(candidates, candidate{i, ix.config.Metric.distance(vector, n.entry.Vector)})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].dist < candidates[j].dist
+	})
+	return candidates
+}
@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func randomEntries(n, dim int, seed int64) []Entry {
+	rng := rand.New(rand.NewSource(seed))
+	entries := make([]Entry, n)
+	for i := range entries {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = rng.Float32()*2 - 1
+		}
+		group := "even"
+		if i%2 == 1 {
+			group = "odd"
+		}
+		entries[i] = Entry{
+			ID:       fmt.Sprintf("e%d", i),
+			Document: document.Document{Title: fmt.Sprintf("doc %d", i)},
+			Metadata: map[string]string{"group": group},
+			Vector:   v,
+		}
+	}
+	return entries
+}
+
+func ids(results []Result) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.Entry.ID
+	}
+	return out
+}
+
+func TestIndex_Search(t *testing.T) {
+	entries := []Entry{
+		{ID: "x", Vector: []float32{1, 0}},
+		{ID: "y", Vector: []float32{0, 1}},
+		{ID: "xy", Vector: []float32{3, 3}},
+	}
+
+	tests := []struct {
+		name   string
+		metric Metric
+		query  []float32
+		want   []string
+	}{
+		{name: "cosine ignores magnitude", metric: Cosine, query: []float32{1, 0.1}, want: []string{"x", "xy", "y"}},
+		{name: "dot favors magnitude", metric: DotProduct, query: []float32{1, 0.1}, want: []string{"xy", "x", "y"}},
+		{name: "euclidean", metric: Euclidean, query: []float32{0.1, 0.9}, want: []string{"y", "x", "xy"}},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []Mode{BruteForce, HNSW} {
+			t.Run(tt.name, func(t *testing.T) {
+				ix := NewIndex(Config{Metric: tt.metric, Mode: mode})
+				if err := ix.Add(entries...); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+				results, err := ix.Search(tt.query, 3, nil)
+				if err != nil {
+					t.Fatalf("Search() error = %v", err)
+				}
+				if got := strings.Join(ids(results), ","); got != strings.Join(tt.want, ",") {
+					t.Errorf("Search() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestIndex_HNSWRecall(t *testing.T) {
+	entries := randomEntries(2000, 16, 1)
+	exact := NewIndex(Config{Mode: BruteForce})
+	approx := NewIndex(Config{Mode: HNSW, Seed: 1})
+	if err := exact.Add(entries...); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := approx.Add(entries...); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	queries := randomEntries(50, 16, 2)
+	var hits, total int
+	for _, q := range queries {
+		want, _ := exact.Search(q.Vector, 10, nil)
+		got, _ := approx.Search(q.Vector, 10, nil)
+
+		wantIDs := make(map[string]bool)
+		for _, r := range want {
+			wantIDs[r.Entry.ID] = true
+		}
+		for _, r := range got {
+			if wantIDs[r.Entry.ID] {
+				hits++
+			}
+		}
+		total += len(want)
+	}
+
+	if recall := float64(hits) / float64(total); recall < 0.9 {
+		t.Errorf("HNSW recall@10 = %.2f, want at least 0.9", recall)
+	}
+}
+
+func TestIndex_FilterAndDelete(t *testing.T) {
+	for _, mode := range []Mode{BruteForce, HNSW} {
+		ix := NewIndex(Config{Mode: mode})
+		entries := randomEntries(200, 8, 3)
+		if err := ix.Add(entries...); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+
+		results, err := ix.Search(entries[0].Vector, 20, Equals("group", "odd"))
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if len(results) != 20 {
+			t.Errorf("mode %d: got %d filtered results, want 20", mode, len(results))
+		}
+		for _, r := range results {
+			if r.Entry.Metadata["group"] != "odd" {
+				t.Errorf("mode %d: filtered search returned entry %s from group %s", mode, r.Entry.ID, r.Entry.Metadata["group"])
+			}
+		}
+
+		ix.Delete("e0")
+		results, _ = ix.Search(entries[0].Vector, 1, nil)
+		if len(results) != 1 || results[0].Entry.ID == "e0" {
+			t.Errorf("mode %d: search after delete returned %v", mode, ids(results))
+		}
+		if ix.Len() != 199 {
+			t.Errorf("mode %d: Len() = %d, want 199", mode, ix.Len())
+		}
+	}
+}
+
+func TestIndex_AddInvalidBatch(t *testing.T) {
+	ix := NewIndex(Config{})
+	err := ix.Add(Entry{ID: "a", Vector: []float32{1, 0}}, Entry{ID: "b", Vector: []float32{1, 0, 0}})
+	if err == nil {
+		t.Fatal("Add() of mixed dimensions error = nil, want an error")
+	}
+	if ix.Len() != 0 {
+		t.Errorf("Len() after failed Add() = %d, want 0", ix.Len())
+	}
+
+	// The failed batch mustn't have fixed the empty index's dimension
+	if err := ix.Add(Entry{ID: "c", Vector: []float32{1, 0, 0}}); err != nil {
+		t.Errorf("Add() after failed batch error = %v", err)
+	}
+}
+
+func TestIndex_SaveLoad(t *testing.T) {
+	ix := NewIndex(Config{Mode: HNSW, Metric: Euclidean})
+	entries := randomEntries(300, 8, 4)
+	if err := ix.Add(entries...); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	ix.Delete("e5")
+
+	var buf bytes.Buffer
+	if err := ix.Save(&buf); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if loaded.Len() != ix.Len() {
+		t.Errorf("loaded Len() = %d, want %d", loaded.Len(), ix.Len())
+	}
+	want, _ := ix.Search(entries[7].Vector, 5, nil)
+	got, _ := loaded.Search(entries[7].Vector, 5, nil)
+	if strings.Join(ids(got), ",") != strings.Join(ids(want), ",") {
+		t.Errorf("loaded Search() = %v, want %v", ids(got), ids(want))
+	}
+
+	// The loaded index should still accept inserts
+	if err = loaded.Add(Entry{ID: "new", Vector: entries[7].Vector}); err != nil {
+		t.Fatalf("Add() after Load() error = %v", err)
+	}
+}
+
+// letterEmbedder embeds text as counts of the letters a, b and c
+type letterEmbedder struct{}
+
+func (letterEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{
+			float32(strings.Count(text, "a")),
+			float32(strings.Count(text, "b")),
+			float32(strings.Count(text, "c")),
+		}
+	}
+	return vectors, nil
+}
+
+func TestRetriever_Query(t *testing.T) {
+	r := NewRetriever(NewIndex(Config{}), letterEmbedder{})
+	err := r.Add(context.Background(),
+		Entry{Document: document.Document{Title: "a", Passages: []document.Passage{{Text: "aaa"}}}, Metadata: map[string]string{"lang": "en"}},
+		Entry{Document: document.Document{Title: "b", Passages: []document.Passage{{Text: "bbb"}}}, Metadata: map[string]string{"lang": "fr"}},
+		Entry{Document: document.Document{Title: "ab", Passages: []document.Passage{{Text: "aab"}}}, Metadata: map[string]string{"lang": "fr"}},
+	)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	docs, err := r.Query(context.Background(), "a", 2)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 2 || docs[0].Title != "a" || docs[1].Title != "ab" {
+		t.Errorf("Query() returned unexpected documents: %+v", docs)
+	}
+
+	docs, err = r.WithFilter(Equals("lang", "fr")).Query(context.Background(), "a", 2)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 2 || docs[0].Title != "ab" || docs[1].Title != "b" {
+		t.Errorf("filtered Query() returned unexpected documents: %+v", docs)
+	}
+}
+
+// emptyEmbedder breaks the Embedder contract by returning no vectors at all
+type emptyEmbedder struct{}
+
+func (emptyEmbedder) Embed(context.Context, []string) ([][]float32, error) {
+	return nil, nil
+}
+
+func TestRetriever_QueryEmptyEmbedding(t *testing.T) {
+	r := NewRetriever(NewIndex(Config{}), emptyEmbedder{})
+	if _, err := r.Query(context.Background(), "a", 2); err == nil {
+		t.Error("Query() error = nil, want error for embedder that returned no vectors")
+	}
+	if err := r.Add(context.Background(), Entry{Document: document.Document{Title: "a"}}); err == nil {
+		t.Error("Add() error = nil, want error for embedder that returned no vectors")
+	}
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+)
+
+// Metric is the similarity function used to compare vectors in an Index
+type Metric int
+
+const (
+	Cosine Metric = iota
+	DotProduct
+	Euclidean
+)
+
+func (m Metric) String() string {
+	switch m {
+	case Cosine:
+		return "cosine"
+	case DotProduct:
+		return "dot"
+	case Euclidean:
+		return "euclidean"
+	default:
+		return fmt.Sprintf("Metric(%d)", int(m))
+	}
+}
+
+// distance returns how far apart a and b are under the metric, lower is closer. Vectors are expected to already be
+// normalized when the metric is Cosine, so cosine distance reduces to a negated dot product.
+func (m Metric) distance(a, b []float32) float32 {
+	if m == Euclidean {
+		var sum float32
+		for i := range a {
+			d := a[i] - b[i]
+			sum += d * d
+		}
+		return float32(math.Sqrt(float64(sum)))
+	}
+
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return -dot
+}
+
+// score converts a distance into a similarity score, where higher is more similar
+func (m Metric) score(distance float32) float32 {
+	if m == Euclidean {
+		return 1 / (1 + distance)
+	}
+	return -distance
+}
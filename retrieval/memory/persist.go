@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// snapshot is the on-disk representation of an Index. The HNSW graph is stored alongside the entries so loading
+// doesn't require rebuilding it.
+type snapshot struct {
+	Config     Config
+	Dim        int
+	Nodes      []snapshotNode
+	NextID     int
+	EntryPoint int
+	MaxLevel   int
+}
+
+type snapshotNode struct {
+	Entry     Entry
+	Deleted   bool
+	Level     int
+	Neighbors [][]int
+}
+
+// Save writes the index to w so it can later be restored with Load
+func (ix *Index) Save(w io.Writer) error {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	s := snapshot{
+		Config:     ix.config,
+		Dim:        ix.dim,
+		Nodes:      make([]snapshotNode, len(ix.nodes)),
+		NextID:     ix.nextID,
+		EntryPoint: ix.entryPoint,
+		MaxLevel:   ix.maxLevel,
+	}
+	for i, n := range ix.nodes {
+		s.Nodes[i] = snapshotNode{n.entry, n.deleted, n.level, n.neighbors}
+	}
+
+	if err := gob.NewEncoder(w).Encode(s); err != nil {
+		return fmt.Errorf("error encoding index: %v", err)
+	}
+	return nil
+}
+
+// SaveFile writes the index to the file at path, replacing it if it exists
+func (ix *Index) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating index file: %v", err)
+	}
+
+	if err = ix.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads an index previously written with Index.Save
+func Load(r io.Reader) (*Index, error) {
+	var s snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("error decoding index: %v", err)
+	}
+
+	config := s.Config.withDefaults()
+	ix := &Index{
+		config:     config,
+		dim:        s.Dim,
+		nodes:      make([]*node, len(s.Nodes)),
+		ids:        make(map[string]int, len(s.Nodes)),
+		nextID:     s.NextID,
+		entryPoint: s.EntryPoint,
+		maxLevel:   s.MaxLevel,
+		levelMult:  1 / math.Log(float64(config.M)),
+		// Continuing from the original seed would repeat levels already handed out, so offset it by the node count
+		rng: rand.New(rand.NewSource(config.Seed + int64(len(s.Nodes)))),
+	}
+	for i, sn := range s.Nodes {
+		neighbors := sn.Neighbors
+		if config.Mode == HNSW && len(neighbors) < sn.Level+1 {
+			// gob drops trailing empty layers, restore them so every layer up to the node's level is addressable
+			neighbors = append(neighbors, make([][]int, sn.Level+1-len(neighbors))...)
+		}
+
+		ix.nodes[i] = &node{sn.Entry, sn.Deleted, sn.Level, neighbors}
+		if !sn.Deleted {
+			ix.ids[sn.Entry.ID] = i
+			ix.live++
+		}
+	}
+
+	return ix, nil
+}
+
+// LoadFile reads an index previously written with Index.SaveFile
+func LoadFile(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening index file: %v", err)
+	}
+	defer f.Close()
+
+	return Load(f)
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/doctext"
+)
+
+// Retriever implements the retrieval.Retriever interface over an in-process Index. It needs no external services,
+// which makes it a good fit for tests and small corpora.
+type Retriever struct {
+	index    *Index
+	embedder retrieval.Embedder
+	filter   Filter
+}
+
+func (mr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	vectors, err := retrieval.Embed(ctx, mr.embedder, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error creating query embedding: %v", err)
+	}
+
+	results, err := mr.index.Search(vectors[0], topK, mr.filter)
+	if err != nil {
+		return nil, fmt.Errorf("error searching index: %v", err)
+	}
+
+	docs := make([]document.Document, len(results))
+	for i, r := range results {
+		docs[i] = r.Entry.Document
+	}
+	return docs, nil
+}
+
+// Add embeds any entries that don't have a Vector yet and inserts them into the index
+func (mr Retriever) Add(ctx context.Context, entries ...Entry) error {
+	var texts []string
+	var missing []int
+	for i, e := range entries {
+		if len(e.Vector) == 0 {
+			texts = append(texts, doctext.Text(e.Document, 0))
+			missing = append(missing, i)
+		}
+	}
+
+	if len(texts) > 0 {
+		vectors, err := retrieval.Embed(ctx, mr.embedder, texts)
+		if err != nil {
+			return fmt.Errorf("error embedding entries: %v", err)
+		}
+
+		entries = append([]Entry(nil), entries...)
+		for j, i := range missing {
+			entries[i].Vector = vectors[j]
+		}
+	}
+
+	return mr.index.Add(entries...)
+}
+
+// WithFilter returns a copy of the retriever that only returns entries matching filter
+func (mr Retriever) WithFilter(filter Filter) Retriever {
+	mr.filter = filter
+	return mr
+}
+
+func NewRetriever(index *Index, embedder retrieval.Embedder) Retriever {
+	return Retriever{index: index, embedder: embedder}
+}
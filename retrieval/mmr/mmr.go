@@ -0,0 +1,170 @@
+package mmr
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"math"
+	"sort"
+)
+
+// Diversifier reorders documents with maximal marginal relevance (Carbonell & Goldstein, 1998). Each pick maximizes
+//
+//	lambda * relevance(doc) - (1 - lambda) * max similarity(doc, already picked)
+//
+// so a lambda of 1 keeps the original relevance order and lower values increasingly favor documents unlike the ones
+// already picked.
+type Diversifier struct {
+	similarity Similarity
+	lambda     float64
+	// duplicateThreshold is the similarity at or above which a document is dropped as a near-duplicate of a more
+	// relevant one, 0 disables collapsing
+	duplicateThreshold float64
+}
+
+// Diversify returns up to topK of docs in maximal marginal relevance order. docs should be in relevance order, as
+// returned by a retriever.
+func (d Diversifier) Diversify(ctx context.Context, query string, docs []document.Document, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	relevance, pairwise, err := d.similarity.Compare(ctx, query, docs)
+	if err != nil {
+		return nil, fmt.Errorf("error comparing documents: %v", err)
+	}
+
+	candidates := make([]int, 0, len(docs))
+	for i := range docs {
+		candidates = append(candidates, i)
+	}
+	if d.duplicateThreshold > 0 {
+		candidates = collapse(candidates, relevance, pairwise, d.duplicateThreshold)
+	}
+
+	selected := selectMMR(candidates, relevance, pairwise, d.lambda, topK)
+	diversified := make([]document.Document, len(selected))
+	for i, s := range selected {
+		diversified[i] = docs[s]
+	}
+	return diversified, nil
+}
+
+// CollapseDuplicates drops every document whose similarity to a more relevant document is at or above threshold,
+// keeping the remaining documents in their original order
+func (d Diversifier) CollapseDuplicates(ctx context.Context, query string, docs []document.Document, threshold float64) ([]document.Document, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	relevance, pairwise, err := d.similarity.Compare(ctx, query, docs)
+	if err != nil {
+		return nil, fmt.Errorf("error comparing documents: %v", err)
+	}
+
+	candidates := make([]int, len(docs))
+	for i := range candidates {
+		candidates[i] = i
+	}
+
+	kept := collapse(candidates, relevance, pairwise, threshold)
+	collapsed := make([]document.Document, len(kept))
+	for i, k := range kept {
+		collapsed[i] = docs[k]
+	}
+	return collapsed, nil
+}
+
+// collapse removes candidates that are near-duplicates of a more relevant candidate that was kept, returning the rest
+// in their original order. Ties in relevance go to the earlier candidate.
+func collapse(candidates []int, relevance []float64, pairwise [][]float64, threshold float64) []int {
+	byRelevance := append([]int(nil), candidates...)
+	sort.SliceStable(byRelevance, func(i, j int) bool {
+		return relevance[byRelevance[i]] > relevance[byRelevance[j]]
+	})
+
+	keep := make(map[int]bool, len(candidates))
+	var kept []int
+	for _, c := range byRelevance {
+		duplicate := false
+		for _, k := range kept {
+			if pairwise[c][k] >= threshold {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			keep[c] = true
+			kept = append(kept, c)
+		}
+	}
+
+	inOrder := make([]int, 0, len(kept))
+	for _, c := range candidates {
+		if keep[c] {
+			inOrder = append(inOrder, c)
+		}
+	}
+	return inOrder
+}
+
+func selectMMR(candidates []int, relevance []float64, pairwise [][]float64, lambda float64, topK int) []int {
+	remaining := append([]int(nil), candidates...)
+	selected := make([]int, 0, min(topK, len(remaining)))
+
+	for len(selected) < topK && len(remaining) > 0 {
+		best, bestScore := 0, math.Inf(-1)
+		for i, c := range remaining {
+			redundancy := 0.0
+			for _, s := range selected {
+				redundancy = math.Max(redundancy, pairwise[c][s])
+			}
+
+			score := lambda*relevance[c] - (1-lambda)*redundancy
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		selected = append(selected, remaining[best])
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+	return selected
+}
+
+// NewDiversifier creates a Diversifier. lambda is clamped to [0, 1], and a duplicateThreshold of 0 disables
+// near-duplicate collapsing.
+func NewDiversifier(similarity Similarity, lambda float64, duplicateThreshold float64) Diversifier {
+	return Diversifier{similarity, math.Min(math.Max(lambda, 0), 1), duplicateThreshold}
+}
+
+// Retriever implements the retrieval.Retriever interface by over-fetching candidates from another retriever and
+// diversifying them
+type Retriever struct {
+	retriever   retrieval.Retriever
+	diversifier Diversifier
+	overFetch   int
+}
+
+func (mr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	docs, err := mr.retriever.Query(ctx, query, topK*mr.overFetch)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving candidates: %v", err)
+	}
+
+	return mr.diversifier.Diversify(ctx, query, docs, topK)
+}
+
+// NewRetriever wraps retriever so that each query fetches overFetch times as many candidates as requested, giving
+// diversification room to pick from. An overFetch below 1 is treated as 1.
+func NewRetriever(retriever retrieval.Retriever, diversifier Diversifier, overFetch int) Retriever {
+	return Retriever{retriever, diversifier, max(overFetch, 1)}
+}
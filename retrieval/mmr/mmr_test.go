@@ -0,0 +1,87 @@
+package mmr
+
+import (
+	"context"
+	"github.com/coopslarhette/raglib/lib/document"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func webDocs(texts ...string) []document.Document {
+	docs := make([]document.Document, len(texts))
+	for i, text := range texts {
+		docs[i] = document.Document{Passages: []document.Passage{{Text: text}}}
+	}
+	return docs
+}
+
+func texts(docs []document.Document) []string {
+	out := make([]string, len(docs))
+	for i, d := range docs {
+		out[i] = d.Passages[0].Text
+	}
+	return out
+}
+
+var (
+	original   = "the quick brown fox jumps over the lazy dog near the river bank"
+	mirror     = "the quick brown fox jumps over the lazy dog near the river bank today"
+	unrelated  = "go channels let goroutines communicate without sharing memory"
+	syndicated = "breaking: the quick brown fox jumps over the lazy dog near the river bank"
+)
+
+func TestDiversifier_Diversify(t *testing.T) {
+	docs := webDocs(original, mirror, syndicated, unrelated)
+
+	tests := []struct {
+		name      string
+		lambda    float64
+		threshold float64
+		topK      int
+		want      []string
+	}{
+		{name: "lambda of 1 keeps ranking", lambda: 1, topK: 3, want: []string{original, mirror, syndicated}},
+		{name: "low lambda promotes diverse documents", lambda: 0.3, topK: 2, want: []string{original, unrelated}},
+		{name: "collapse near-duplicates", lambda: 1, threshold: 0.7, topK: 4, want: []string{original, unrelated}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDiversifier(NewShingleSimilarity(3), tt.lambda, tt.threshold)
+			got, err := d.Diversify(context.Background(), "fox", docs, tt.topK)
+			if err != nil {
+				t.Fatalf("Diversify() error = %v", err)
+			}
+			if !reflect.DeepEqual(texts(got), tt.want) {
+				t.Errorf("Diversify() = %q, want %q", texts(got), tt.want)
+			}
+		})
+	}
+}
+
+type wordEmbedder struct{}
+
+// Embed maps text to a vector of its counts of "fox" and "go"
+func (wordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(strings.Count(text, "fox")), float32(strings.Count(text, "go"))}
+	}
+	return vectors, nil
+}
+
+func TestEmbeddingSimilarity(t *testing.T) {
+	docs := webDocs(unrelated, original, mirror)
+
+	relevance, pairwise, err := NewEmbeddingSimilarity(wordEmbedder{}).Compare(context.Background(), "fox", docs)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if relevance[0] >= relevance[1] {
+		t.Errorf("relevance = %v, want fox documents more relevant", relevance)
+	}
+	if pairwise[1][2] < 0.99 || pairwise[0][1] > 0.01 {
+		t.Errorf("pairwise = %v, want mirrored documents similar and unrelated dissimilar", pairwise)
+	}
+}
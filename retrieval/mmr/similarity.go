@@ -0,0 +1,130 @@
+package mmr
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/doctext"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/vecmath"
+	"strings"
+	"unicode"
+)
+
+// Similarity scores how relevant each document is to the query and how similar documents are to each other. Scores
+// should be comparable between the two, ideally on a 0 to 1 scale.
+type Similarity interface {
+	// Compare returns relevance[i], the relevance of docs[i] to query, and pairwise[i][j], the similarity of docs[i]
+	// and docs[j]. docs are given in their original ranking order.
+	Compare(ctx context.Context, query string, docs []document.Document) (relevance []float64, pairwise [][]float64, err error)
+}
+
+// EmbeddingSimilarity implements the Similarity interface with the cosine similarity of embeddings
+type EmbeddingSimilarity struct {
+	embedder retrieval.Embedder
+}
+
+func (es EmbeddingSimilarity) Compare(ctx context.Context, query string, docs []document.Document) ([]float64, [][]float64, error) {
+	texts := make([]string, len(docs)+1)
+	texts[0] = query
+	for i, d := range docs {
+		texts[i+1] = doctext.Text(d, 0)
+	}
+
+	vectors, err := retrieval.Embed(ctx, es.embedder, texts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error embedding query and documents: %v", err)
+	}
+
+	relevance := make([]float64, len(docs))
+	pairwise := newMatrix(len(docs))
+	for i := range docs {
+		relevance[i] = vecmath.Cosine(vectors[0], vectors[i+1])
+		for j := 0; j < i; j++ {
+			pairwise[i][j] = vecmath.Cosine(vectors[i+1], vectors[j+1])
+			pairwise[j][i] = pairwise[i][j]
+		}
+		pairwise[i][i] = 1
+	}
+	return relevance, pairwise, nil
+}
+
+func NewEmbeddingSimilarity(embedder retrieval.Embedder) EmbeddingSimilarity {
+	return EmbeddingSimilarity{embedder}
+}
+
+// ShingleSimilarity implements the Similarity interface without any model calls. Documents are compared by the
+// Jaccard similarity of their sets of word shingles, i.e. runs of size consecutive words, which is high for mirrored
+// or syndicated copies of the same page. Shingles say nothing about relevance to a query, so relevance is taken from
+// each document's position in the original ranking instead.
+type ShingleSimilarity struct {
+	size int
+}
+
+func (ss ShingleSimilarity) Compare(_ context.Context, _ string, docs []document.Document) ([]float64, [][]float64, error) {
+	shingles := make([]map[string]bool, len(docs))
+	for i, d := range docs {
+		shingles[i] = shingle(doctext.Text(d, 0), ss.size)
+	}
+
+	relevance := make([]float64, len(docs))
+	pairwise := newMatrix(len(docs))
+	for i := range docs {
+		relevance[i] = 1 - float64(i)/float64(len(docs))
+		for j := 0; j < i; j++ {
+			pairwise[i][j] = jaccard(shingles[i], shingles[j])
+			pairwise[j][i] = pairwise[i][j]
+		}
+		pairwise[i][i] = 1
+	}
+	return relevance, pairwise, nil
+}
+
+// NewShingleSimilarity creates a ShingleSimilarity over shingles of size words. Sizes below 1 default to 3, which is
+// a common choice for spotting near-duplicate web pages.
+func NewShingleSimilarity(size int) ShingleSimilarity {
+	if size < 1 {
+		size = 3
+	}
+	return ShingleSimilarity{size}
+}
+
+func shingle(text string, size int) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	shingles := make(map[string]bool)
+	if len(words) < size {
+		if len(words) > 0 {
+			shingles[strings.Join(words, " ")] = true
+		}
+		return shingles
+	}
+	for i := 0; i+size <= len(words); i++ {
+		shingles[strings.Join(words[i:i+size], " ")] = true
+	}
+	return shingles
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+
+	intersection := 0
+	for s := range a {
+		if b[s] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+func newMatrix(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+	}
+	return m
+}
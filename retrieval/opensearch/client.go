@@ -0,0 +1,121 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"github.com/coopslarhette/raglib/lib/retrieval/ratelimit"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client is the HTTP client for the search API of an OpenSearch or Elasticsearch cluster, which share the same
+// request and response shapes apart from kNN queries
+type Client struct {
+	baseURL string
+	client  *http.Client
+	// authorization is the value of the Authorization header sent with every request, empty for none
+	authorization string
+	limiter       *ratelimit.Limiter
+}
+
+// Hit is a single document matched by a search
+type Hit struct {
+	Index string  `json:"_index"`
+	ID    string  `json:"_id"`
+	Score float64 `json:"_score"`
+	// Source is the document as it was indexed, minus any fields excluded by the request
+	Source json.RawMessage `json:"_source"`
+	// Highlight maps each highlighted field to its matching fragments, best first
+	Highlight map[string][]string `json:"highlight"`
+}
+
+// SearchResponse represents the JSON response of a search. Like serp.SearchResult, it only holds the fields that
+// are interesting to us currently.
+type SearchResponse struct {
+	Took     int  `json:"took"`
+	TimedOut bool `json:"timed_out"`
+	Hits     struct {
+		Hits []Hit `json:"hits"`
+	} `json:"hits"`
+}
+
+// NewClient creates a Client for the cluster at baseURL, e.g. "https://search.internal:9200"
+func NewClient(baseURL string, client *http.Client) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// SetBasicAuth authenticates requests with a username and password, e.g. for the OpenSearch security plugin
+func (c *Client) SetBasicAuth(username, password string) {
+	req := http.Request{Header: http.Header{}}
+	req.SetBasicAuth(username, password)
+	c.authorization = req.Header.Get("Authorization")
+}
+
+// SetAPIKey authenticates requests with an Elasticsearch API key, the base64 encoded value returned when creating it
+func (c *Client) SetAPIKey(apiKey string) {
+	c.authorization = "ApiKey " + apiKey
+}
+
+// SetLimiter throttles and retries the client's searches with limiter
+func (c *Client) SetLimiter(limiter *ratelimit.Limiter) {
+	c.limiter = limiter
+}
+
+// Search runs a search with the query DSL body against index, which may also be a comma separated list of indices,
+// an alias or a pattern like "kb-*"
+func (c *Client) Search(ctx context.Context, index string, body any) (*SearchResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling search request: %v", err)
+	}
+	apiURL := c.baseURL + "/" + url.PathEscape(index) + "/_search"
+
+	var result *SearchResponse
+	err = c.limiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.post(ctx, apiURL, payload)
+		return err
+	})
+	return result, err
+}
+
+func (c *Client) post(ctx context.Context, apiURL string, payload []byte) (*SearchResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error constructing request for OpenSearch: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while executing request to OpenSearch: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OpenSearch response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse("opensearch", resp, body)
+	}
+
+	var result SearchResponse
+	if err = json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error parsing OpenSearch response: %v", err)
+	}
+	return &result, nil
+}
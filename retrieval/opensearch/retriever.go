@@ -0,0 +1,389 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/fusion"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultFragmentSize = 300
+	defaultFragments    = 3
+)
+
+// Mode is the kind of query a Retriever runs
+type Mode int
+
+const (
+	// Match runs a BM25 scored multi_match query over Config.MatchFields
+	Match Mode = iota
+	// KNN embeds the query and runs an approximate kNN query on Fields.Vector
+	KNN
+	// Hybrid runs Match and KNN concurrently and merges their rankings with reciprocal rank fusion, which needs no
+	// search pipeline or rank plugin on the cluster
+	Hybrid
+)
+
+// Flavor is the search engine the cluster runs. The two only differ in how kNN queries are written.
+type Flavor int
+
+const (
+	OpenSearch Flavor = iota
+	Elasticsearch
+)
+
+// Fields maps fields of the indexed documents into documents. Nested fields are written with dots, e.g.
+// "meta.author". Empty names fall back to the defaults in parentheses.
+type Fields struct {
+	// Title (title) becomes Document.Title
+	Title string
+	// Text (text) is the body of the document, used as its passage and highlighted
+	Text string
+	// Vector (embedding) holds the document's embedding, it's searched by kNN queries and never fetched
+	Vector string
+	// Link is the document's URL. When set and present on a hit, the document is a web document with a WebReference.
+	Link string
+	// Date and Author fill the WebReference of web documents
+	Date   string
+	Author string
+}
+
+func (f Fields) withDefaults() Fields {
+	if f.Title == "" {
+		f.Title = "title"
+	}
+	if f.Text == "" {
+		f.Text = "text"
+	}
+	if f.Vector == "" {
+		f.Vector = "embedding"
+	}
+	return f
+}
+
+// Config configures a Retriever
+type Config struct {
+	// Index is the index, alias or pattern searched
+	Index  string
+	Flavor Flavor
+	Mode   Mode
+	Fields Fields
+	// MatchFields are the fields searched by Match queries, with optional boosts like "title^2". Defaults to
+	// Fields.Text and Fields.Title.
+	MatchFields []string
+	// Embedder embeds queries for KNN and Hybrid modes. It must be the model that embedded Fields.Vector.
+	Embedder retrieval.Embedder
+	// NumCandidates is how many candidates Elasticsearch considers per shard in kNN queries, default 10 times topK
+	NumCandidates int
+	// Filter is a query DSL clause every hit must match, in every mode, e.g. {"term": {"team": "search"}}
+	Filter json.RawMessage
+	// Highlight turns the fragments of Fields.Text that match a Match query into the document's passages, instead of
+	// its whole text. Hits without highlights, like those found only by kNN, keep their whole text.
+	Highlight bool
+	// FragmentSize is the length of highlight fragments in characters
+	FragmentSize int
+	// Fragments is the maximum number of highlight fragments per document
+	Fragments int
+}
+
+func (c Config) withDefaults() Config {
+	c.Fields = c.Fields.withDefaults()
+	if len(c.MatchFields) == 0 {
+		c.MatchFields = []string{c.Fields.Text, c.Fields.Title}
+	}
+	if c.FragmentSize <= 0 {
+		c.FragmentSize = defaultFragmentSize
+	}
+	if c.Fragments <= 0 {
+		c.Fragments = defaultFragments
+	}
+	return c
+}
+
+// Retriever implements the retrieval.Retriever interface over an OpenSearch or Elasticsearch index
+type Retriever struct {
+	client *Client
+	config Config
+}
+
+func (osr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	var hits []Hit
+	var err error
+	switch osr.config.Mode {
+	case Match:
+		hits, err = osr.search(ctx, osr.matchQuery(query, topK))
+	case KNN:
+		hits, err = osr.knn(ctx, query, topK)
+	case Hybrid:
+		hits, err = osr.hybrid(ctx, query, topK)
+	default:
+		err = fmt.Errorf("unknown mode %d", osr.config.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]document.Document, 0, len(hits))
+	for _, h := range hits {
+		doc, err := osr.toDocument(h)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (osr Retriever) search(ctx context.Context, body map[string]any) ([]Hit, error) {
+	resp, err := osr.client.Search(ctx, osr.config.Index, body)
+	if err != nil {
+		return nil, fmt.Errorf("error searching OpenSearch index %s: %w", osr.config.Index, err)
+	}
+	return resp.Hits.Hits, nil
+}
+
+func (osr Retriever) knn(ctx context.Context, query string, topK int) ([]Hit, error) {
+	if osr.config.Embedder == nil {
+		return nil, fmt.Errorf("kNN queries need an embedder")
+	}
+	vectors, err := retrieval.Embed(ctx, osr.config.Embedder, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error creating query embedding: %v", err)
+	}
+	return osr.search(ctx, osr.knnQuery(vectors[0], topK))
+}
+
+// hybrid fuses the rankings of a Match and a KNN query. Hits found by both keep the Match hit, which carries the
+// highlights, and every hit's Score becomes its fused score.
+func (osr Retriever) hybrid(ctx context.Context, query string, topK int) ([]Hit, error) {
+	var matched, nearest []Hit
+	var matchErr, knnErr error
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		matched, matchErr = osr.search(ctx, osr.matchQuery(query, topK))
+	}()
+	go func() {
+		defer wg.Done()
+		nearest, knnErr = osr.knn(ctx, query, topK)
+	}()
+	wg.Wait()
+
+	if matchErr != nil {
+		return nil, matchErr
+	}
+	if knnErr != nil {
+		return nil, knnErr
+	}
+
+	byKey := make(map[string]Hit)
+	var keys [][]string
+	for _, ranking := range [][]Hit{matched, nearest} {
+		var ranked []string
+		for _, h := range ranking {
+			key := h.Index + "\x00" + h.ID
+			if _, ok := byKey[key]; !ok {
+				byKey[key] = h
+			}
+			ranked = append(ranked, key)
+		}
+		keys = append(keys, ranked)
+	}
+
+	fused := fusion.Fuse(keys, fusion.K)
+	if len(fused) > topK {
+		fused = fused[:topK]
+	}
+	hits := make([]Hit, len(fused))
+	for i, f := range fused {
+		hits[i] = byKey[f.Key]
+		hits[i].Score = f.Score
+	}
+	return hits, nil
+}
+
+func (osr Retriever) matchQuery(query string, topK int) map[string]any {
+	var q any = map[string]any{
+		"multi_match": map[string]any{
+			"query":  query,
+			"fields": osr.config.MatchFields,
+		},
+	}
+	if osr.config.Filter != nil {
+		q = map[string]any{
+			"bool": map[string]any{
+				"must":   []any{q},
+				"filter": []any{osr.config.Filter},
+			},
+		}
+	}
+
+	body := osr.baseQuery(topK)
+	body["query"] = q
+	if osr.config.Highlight {
+		body["highlight"] = map[string]any{
+			"fields": map[string]any{
+				osr.config.Fields.Text: map[string]any{
+					"fragment_size":       osr.config.FragmentSize,
+					"number_of_fragments": osr.config.Fragments,
+				},
+			},
+			// Passages are plain text, so matches aren't wrapped in <em> tags
+			"pre_tags":  []string{""},
+			"post_tags": []string{""},
+		}
+	}
+	return body
+}
+
+func (osr Retriever) knnQuery(vector []float32, topK int) map[string]any {
+	body := osr.baseQuery(topK)
+
+	if osr.config.Flavor == Elasticsearch {
+		numCandidates := osr.config.NumCandidates
+		if numCandidates <= 0 {
+			// Elasticsearch rejects more than 10,000 candidates
+			numCandidates = min(10*topK, 10000)
+		}
+		knn := map[string]any{
+			"field":          osr.config.Fields.Vector,
+			"query_vector":   vector,
+			"k":              topK,
+			"num_candidates": max(numCandidates, topK),
+		}
+		if osr.config.Filter != nil {
+			knn["filter"] = osr.config.Filter
+		}
+		body["knn"] = knn
+		return body
+	}
+
+	knn := map[string]any{
+		"vector": vector,
+		"k":      topK,
+	}
+	if osr.config.Filter != nil {
+		knn["filter"] = osr.config.Filter
+	}
+	body["query"] = map[string]any{
+		"knn": map[string]any{osr.config.Fields.Vector: knn},
+	}
+	return body
+}
+
+// baseQuery is the part of the request shared by every mode
+func (osr Retriever) baseQuery(topK int) map[string]any {
+	return map[string]any{
+		"size": topK,
+		// Embeddings are large and never needed in documents
+		"_source": map[string]any{"excludes": []string{osr.config.Fields.Vector}},
+	}
+}
+
+func (osr Retriever) toDocument(h Hit) (document.Document, error) {
+	var source map[string]any
+	if len(h.Source) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(h.Source))
+		decoder.UseNumber()
+		if err := decoder.Decode(&source); err != nil {
+			return document.Document{}, fmt.Errorf("error parsing source of hit %s: %v", h.ID, err)
+		}
+	}
+
+	fields := osr.config.Fields
+	doc := document.Document{
+		Title:  fieldString(source, fields.Title, " "),
+		Corpus: document.Personal,
+	}
+
+	for _, fragment := range h.Highlight[fields.Text] {
+		doc.Passages = append(doc.Passages, document.Passage{Text: fragment, Score: h.Score})
+	}
+	if len(doc.Passages) == 0 {
+		doc.Passages = []document.Passage{{Text: fieldString(source, fields.Text, "\n"), Score: h.Score}}
+	}
+
+	if fields.Link == "" {
+		return doc, nil
+	}
+	link := fieldString(source, fields.Link, "")
+	if link == "" {
+		return doc, nil
+	}
+
+	displayed := link
+	if u, err := url.Parse(link); err == nil && u.Host != "" {
+		displayed = u.Host + u.Path
+	}
+	var blurb string
+	if len(h.Highlight[fields.Text]) > 0 {
+		blurb = h.Highlight[fields.Text][0]
+	}
+
+	doc.Corpus = document.Web
+	doc.WebReference = &document.WebReference{
+		Title:         doc.Title,
+		Link:          link,
+		DisplayedLink: displayed,
+		Blurb:         blurb,
+		Date:          fieldString(source, fields.Date, ""),
+		Author:        fieldString(source, fields.Author, ", "),
+		APISource:     "opensearch",
+	}
+	return doc, nil
+}
+
+// fieldString returns the value of the field at path in source as text, joining arrays with sep. Both nested
+// objects and keys that themselves contain dots are found.
+func fieldString(source map[string]any, path, sep string) string {
+	if path == "" {
+		return ""
+	}
+	if v, ok := source[path]; ok {
+		return valueString(v, sep)
+	}
+	head, rest, ok := strings.Cut(path, ".")
+	if !ok {
+		return ""
+	}
+	nested, _ := source[head].(map[string]any)
+	return fieldString(nested, rest, sep)
+}
+
+func valueString(v any, sep string) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	case []any:
+		var parts []string
+		for _, item := range v {
+			if s := valueString(item, sep); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, sep)
+	default:
+		return ""
+	}
+}
+
+func NewRetriever(client *Client, config Config) Retriever {
+	return Retriever{client: client, config: config.withDefaults()}
+}
@@ -0,0 +1,213 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fixedEmbedder []float32
+
+func (e fixedEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = e
+	}
+	return vectors, nil
+}
+
+// stubCluster answers match queries with matchHits and kNN queries with knnHits, recording every request body
+type stubCluster struct {
+	matchHits, knnHits string
+	mu                 sync.Mutex
+	requests           []map[string]any
+}
+
+func (s *stubCluster) serve(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/kb/_search" {
+			t.Errorf("request = %s %s, want POST /kb/_search", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "ApiKey secret" {
+			t.Errorf("Authorization = %q, want ApiKey secret", got)
+		}
+
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("request body isn't JSON: %v", err)
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, body)
+		s.mu.Unlock()
+
+		hits := s.matchHits
+		if strings.Contains(string(data), "knn") {
+			hits = s.knnHits
+		}
+		w.Write([]byte(`{"took": 3, "hits": {"hits": [` + hits + `]}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestRetriever(url string, config Config) Retriever {
+	client := NewClient(url, http.DefaultClient)
+	client.SetAPIKey("secret")
+	config.Index = "kb"
+	config.Embedder = fixedEmbedder{0.5, 1}
+	return NewRetriever(client, config)
+}
+
+func TestRetriever_Match(t *testing.T) {
+	stub := &stubCluster{matchHits: `
+		{"_index": "kb", "_id": "1", "_score": 7.5,
+		 "_source": {"title": "Rotating keys", "body": "long text", "meta": {"url": "https://wiki.internal/keys", "authors": ["ana", "bo"]}, "updated": "2024-05-01"},
+		 "highlight": {"body": ["rotate the signing keys", "keys expire after"]}},
+		{"_index": "kb", "_id": "2", "_score": 3,
+		 "_source": {"title": "On call", "body": ["first", "second"]}}`}
+	server := stub.serve(t)
+
+	r := newTestRetriever(server.URL, Config{
+		Fields:    Fields{Text: "body", Link: "meta.url", Author: "meta.authors", Date: "updated"},
+		Filter:    json.RawMessage(`{"term": {"team": "infra"}}`),
+		Highlight: true,
+	})
+	docs, err := r.Query(context.Background(), "rotate keys", 5)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	want := []document.Document{
+		{
+			Title:    "Rotating keys",
+			Passages: []document.Passage{{Text: "rotate the signing keys", Score: 7.5}, {Text: "keys expire after", Score: 7.5}},
+			Corpus:   document.Web,
+			WebReference: &document.WebReference{
+				Title:         "Rotating keys",
+				Link:          "https://wiki.internal/keys",
+				DisplayedLink: "wiki.internal/keys",
+				Blurb:         "rotate the signing keys",
+				Date:          "2024-05-01",
+				Author:        "ana, bo",
+				APISource:     "opensearch",
+			},
+		},
+		{
+			Title:    "On call",
+			Passages: []document.Passage{{Text: "first\nsecond", Score: 3}},
+			Corpus:   document.Personal,
+		},
+	}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("Query() = %+v, want %+v", docs, want)
+	}
+
+	query := stub.requests[0]["query"].(map[string]any)["bool"].(map[string]any)
+	fields := query["must"].([]any)[0].(map[string]any)["multi_match"].(map[string]any)["fields"]
+	if !reflect.DeepEqual(fields, []any{"body", "title"}) {
+		t.Errorf("multi_match fields = %v, want body and title", fields)
+	}
+	if filter := query["filter"].([]any)[0]; !reflect.DeepEqual(filter, map[string]any{"term": map[string]any{"team": "infra"}}) {
+		t.Errorf("filter = %v", filter)
+	}
+	if _, ok := stub.requests[0]["highlight"].(map[string]any)["fields"].(map[string]any)["body"]; !ok {
+		t.Errorf("highlight = %v, want body highlighted", stub.requests[0]["highlight"])
+	}
+}
+
+func TestRetriever_KNN(t *testing.T) {
+	tests := []struct {
+		name   string
+		flavor Flavor
+		want   string
+	}{
+		{
+			name:   "opensearch",
+			flavor: OpenSearch,
+			want:   `{"_source":{"excludes":["embedding"]},"query":{"knn":{"embedding":{"k":4,"vector":[0.5,1]}}},"size":4}`,
+		},
+		{
+			name:   "elasticsearch",
+			flavor: Elasticsearch,
+			want:   `{"_source":{"excludes":["embedding"]},"knn":{"field":"embedding","k":4,"num_candidates":40,"query_vector":[0.5,1]},"size":4}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubCluster{knnHits: `{"_index": "kb", "_id": "1", "_score": 0.9, "_source": {"title": "T", "text": "body"}}`}
+			server := stub.serve(t)
+
+			r := newTestRetriever(server.URL, Config{Flavor: tt.flavor, Mode: KNN})
+			docs, err := r.Query(context.Background(), "q", 4)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(docs) != 1 || docs[0].Passages[0].Text != "body" {
+				t.Errorf("Query() = %+v", docs)
+			}
+
+			got, _ := json.Marshal(stub.requests[0])
+			if string(got) != tt.want {
+				t.Errorf("request =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetriever_Hybrid(t *testing.T) {
+	stub := &stubCluster{
+		matchHits: `{"_index": "kb", "_id": "a", "_score": 9, "_source": {"title": "A"}, "highlight": {"text": ["hit"]}},
+			{"_index": "kb", "_id": "b", "_score": 8, "_source": {"title": "B"}}`,
+		knnHits: `{"_index": "kb", "_id": "c", "_score": 0.9, "_source": {"title": "C"}},
+			{"_index": "kb", "_id": "b", "_score": 0.8, "_source": {"title": "B"}},
+			{"_index": "kb", "_id": "d", "_score": 0.75, "_source": {"title": "D"}},
+			{"_index": "kb", "_id": "a", "_score": 0.7, "_source": {"title": "A", "text": "whole"}}`,
+	}
+	server := stub.serve(t)
+
+	r := newTestRetriever(server.URL, Config{Mode: Hybrid, Highlight: true})
+	docs, err := r.Query(context.Background(), "q", 2)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(stub.requests) != 2 {
+		t.Fatalf("sent %d requests, want a match and a kNN query", len(stub.requests))
+	}
+
+	var titles []string
+	for _, d := range docs {
+		titles = append(titles, d.Title)
+	}
+	// b is second in both rankings, which beats a being first and fourth
+	if got := strings.Join(titles, ","); got != "B,A" {
+		t.Errorf("Query() titles = %s, want B,A", got)
+	}
+	if docs[1].Passages[0].Text != "hit" {
+		t.Errorf("a's passages = %+v, want the highlight from the match hit", docs[1].Passages)
+	}
+}
+
+func TestRetriever_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"type": "search_phase_execution_exception", "reason": "all shards failed"}, "status": 400}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestRetriever(server.URL, Config{}).Query(context.Background(), "q", 3)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Message != "all shards failed" {
+		t.Errorf("Query() error = %v, want the cluster's reason", err)
+	}
+}
@@ -0,0 +1,182 @@
+package papers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"github.com/coopslarhette/raglib/lib/retrieval/ratelimit"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultArxivURL = "https://export.arxiv.org/api/query"
+
+// arXiv's dates in search queries are minutes, e.g. 202401311430
+const arxivDateFormat = "200601021504"
+
+// ArxivSource searches arXiv through its Atom API. arXiv asks clients to wait three seconds between requests, which
+// a limiter set with SetLimiter can enforce.
+type ArxivSource struct {
+	apiURL  string
+	client  *http.Client
+	limiter *ratelimit.Limiter
+}
+
+type arxivFeed struct {
+	Entries []arxivEntry `xml:"entry"`
+}
+
+type arxivEntry struct {
+	ID        string `xml:"id"`
+	Published string `xml:"published"`
+	Title     string `xml:"title"`
+	Summary   string `xml:"summary"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Links []struct {
+		Href  string `xml:"href,attr"`
+		Rel   string `xml:"rel,attr"`
+		Title string `xml:"title,attr"`
+	} `xml:"link"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+	DOI        string `xml:"http://arxiv.org/schemas/atom doi"`
+	JournalRef string `xml:"http://arxiv.org/schemas/atom journal_ref"`
+}
+
+func NewArxivSource(client *http.Client) *ArxivSource {
+	return &ArxivSource{
+		apiURL: defaultArxivURL,
+		client: client,
+	}
+}
+
+// SetLimiter throttles and retries the source's requests with limiter
+func (s *ArxivSource) SetLimiter(limiter *ratelimit.Limiter) {
+	s.limiter = limiter
+}
+
+func (s *ArxivSource) Search(ctx context.Context, query string, filter Filter, limit int) ([]Paper, error) {
+	params := url.Values{}
+	params.Set("search_query", arxivQuery(query, filter))
+	params.Set("max_results", strconv.Itoa(limit))
+	params.Set("sortBy", "relevance")
+
+	var feed *arxivFeed
+	err := s.limiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		feed, err = s.get(ctx, s.apiURL+"?"+params.Encode())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	papers := make([]Paper, 0, len(feed.Entries))
+	for _, e := range feed.Entries {
+		// arXiv reports a bad query as a feed with a single entry describing the error
+		if strings.Contains(e.ID, "arxiv.org/api/errors") {
+			return nil, &apierror.Error{Provider: "arxiv", StatusCode: http.StatusBadRequest, Message: collapseSpace(e.Summary)}
+		}
+		papers = append(papers, e.paper())
+	}
+	return papers, nil
+}
+
+func (s *ArxivSource) get(ctx context.Context, apiURL string) (*arxivFeed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing request for arXiv API: %v", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while executing request to arXiv API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading arXiv API response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse("arxiv", resp, body)
+	}
+
+	var feed arxivFeed
+	if err = xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("error parsing arXiv API response: %v", err)
+	}
+	return &feed, nil
+}
+
+// arxivQuery builds an arXiv search query matching query in any field, narrowed down by filter
+func arxivQuery(query string, filter Filter) string {
+	clauses := []string{"all:(" + query + ")"}
+
+	if len(filter.Categories) > 0 {
+		categories := make([]string, len(filter.Categories))
+		for i, c := range filter.Categories {
+			categories[i] = "cat:" + c
+		}
+		clauses = append(clauses, "("+strings.Join(categories, " OR ")+")")
+	}
+
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		from, to := filter.From, filter.To
+		if from.IsZero() {
+			// arXiv opened in 1991
+			from = time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		if to.IsZero() {
+			to = time.Now()
+		}
+		clauses = append(clauses, fmt.Sprintf("submittedDate:[%s TO %s]",
+			from.UTC().Format(arxivDateFormat), to.UTC().Format(arxivDateFormat)))
+	}
+
+	return strings.Join(clauses, " AND ")
+}
+
+func (e arxivEntry) paper() Paper {
+	// IDs are the abstract page's URL, e.g. http://arxiv.org/abs/2005.11401v4
+	id := e.ID
+	if i := strings.LastIndex(id, "/abs/"); i >= 0 {
+		id = id[i+len("/abs/"):]
+	}
+
+	p := Paper{
+		Source:   "arxiv",
+		ID:       id,
+		Title:    collapseSpace(e.Title),
+		Abstract: collapseSpace(e.Summary),
+		URL:      e.ID,
+		DOI:      e.DOI,
+		Venue:    collapseSpace(e.JournalRef),
+	}
+	p.Published, _ = time.Parse(time.RFC3339, e.Published)
+
+	for _, a := range e.Authors {
+		p.Authors = append(p.Authors, a.Name)
+	}
+	for _, c := range e.Categories {
+		p.Categories = append(p.Categories, c.Term)
+	}
+	for _, l := range e.Links {
+		switch {
+		case l.Title == "pdf":
+			p.PDFURL = l.Href
+		case l.Rel == "alternate":
+			p.URL = l.Href
+		}
+	}
+	return p
+}
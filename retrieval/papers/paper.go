@@ -0,0 +1,49 @@
+package papers
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Paper is an academic paper found by a Source
+type Paper struct {
+	// Source is the catalog the paper was found in, e.g. "arxiv"
+	Source string
+	// ID is the paper's identifier in its source, e.g. an arXiv ID like "2005.11401v4"
+	ID       string
+	Title    string
+	Authors  []string
+	Abstract string
+	// Published is when the paper was first published, zero when the source doesn't know
+	Published time.Time
+	// URL is the paper's landing page
+	URL string
+	// PDFURL links to the paper's full text, empty when there's no open access copy
+	PDFURL string
+	// Categories are the subject areas the paper is filed under, e.g. "cs.CL" on arXiv
+	Categories []string
+	DOI        string
+	// Venue is the journal or conference the paper appeared in, when known
+	Venue string
+}
+
+// Filter narrows a search down by subject and date
+type Filter struct {
+	// Categories restricts results to papers in any of these subject areas, in the naming of the source: arXiv
+	// categories like "cs.CL" for arXiv, or fields of study like "Computer Science" for Semantic Scholar
+	Categories []string
+	// From and To restrict results to papers published in the range, either may be zero to leave it open
+	From time.Time
+	To   time.Time
+}
+
+// Source searches a catalog of papers
+type Source interface {
+	Search(ctx context.Context, query string, filter Filter, limit int) ([]Paper, error)
+}
+
+// collapseSpace joins the lines of a field that the source wrapped, like arXiv titles and abstracts
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,198 @@
+package papers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const arxivFeedXML = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:arxiv="http://arxiv.org/schemas/atom">
+  <entry>
+    <id>http://arxiv.org/abs/2005.11401v4</id>
+    <updated>2021-04-12T15:42:44Z</updated>
+    <published>2020-05-22T17:26:28Z</published>
+    <title>Retrieval-Augmented Generation for
+      Knowledge-Intensive NLP Tasks</title>
+    <summary>  Large pre-trained language models have been shown to store
+factual knowledge in their parameters.
+</summary>
+    <author><name>Patrick Lewis</name></author>
+    <author><name>Ethan Perez</name></author>
+    <arxiv:doi>10.48550/arXiv.2005.11401</arxiv:doi>
+    <arxiv:journal_ref>NeurIPS 2020</arxiv:journal_ref>
+    <link href="http://arxiv.org/abs/2005.11401v4" rel="alternate" type="text/html"/>
+    <link title="pdf" href="%s/pdf/2005.11401v4" rel="related" type="application/pdf"/>
+    <arxiv:primary_category term="cs.CL" scheme="http://arxiv.org/schemas/atom"/>
+    <category term="cs.CL" scheme="http://arxiv.org/schemas/atom"/>
+    <category term="cs.LG" scheme="http://arxiv.org/schemas/atom"/>
+  </entry>
+</feed>`
+
+// onePagePDF writes a minimal single page PDF showing text in Helvetica
+func onePagePDF(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestArxivQuery(t *testing.T) {
+	filter := Filter{
+		Categories: []string{"cs.CL", "cs.IR"},
+		From:       time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:         time.Date(2020, 12, 31, 23, 59, 0, 0, time.UTC),
+	}
+	want := "all:(retrieval augmented generation) AND (cat:cs.CL OR cat:cs.IR) AND submittedDate:[202001010000 TO 202012312359]"
+	if got := arxivQuery("retrieval augmented generation", filter); got != want {
+		t.Errorf("arxivQuery() = %q, want %q", got, want)
+	}
+	if got := arxivQuery("rag", Filter{}); got != "all:(rag)" {
+		t.Errorf("arxivQuery() = %q, want all:(rag)", got)
+	}
+}
+
+func TestRetriever_Arxiv(t *testing.T) {
+	var params url.Values
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/query":
+			params = r.URL.Query()
+			fmt.Fprintf(w, arxivFeedXML, server.URL)
+		case "/pdf/2005.11401v4":
+			w.Write(onePagePDF("We explore a general-purpose fine-tuning recipe for RAG models."))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := NewArxivSource(server.Client())
+	source.apiURL = server.URL + "/api/query"
+	retriever := NewRetriever(source, Config{
+		Filter:   Filter{Categories: []string{"cs.CL"}},
+		FullText: true,
+		Client:   server.Client(),
+	})
+
+	docs, err := retriever.Query(context.Background(), "rag", 3)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if params.Get("search_query") != "all:(rag) AND (cat:cs.CL)" || params.Get("max_results") != "3" {
+		t.Errorf("request parameters = %v", params)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("Query() returned %d documents, want 1", len(docs))
+	}
+
+	doc := docs[0]
+	if doc.Title != "Retrieval-Augmented Generation for Knowledge-Intensive NLP Tasks" {
+		t.Errorf("Title = %q", doc.Title)
+	}
+	ref := doc.WebReference
+	if ref.Link != "http://arxiv.org/abs/2005.11401v4" || ref.DisplayedLink != "arxiv.org" || ref.Date != "2020-05-22" ||
+		ref.Author != "Patrick Lewis, Ethan Perez" || ref.PDF != server.URL+"/pdf/2005.11401v4" || ref.APISource != "arxiv" {
+		t.Errorf("WebReference = %+v", ref)
+	}
+
+	if len(doc.Passages) != 2 {
+		t.Fatalf("got %d passages, want the abstract and the full text: %v", len(doc.Passages), doc.Passages)
+	}
+	if doc.Passages[0].Text != "Large pre-trained language models have been shown to store factual knowledge in their parameters." {
+		t.Errorf("abstract passage = %q", doc.Passages[0].Text)
+	}
+	if !strings.Contains(doc.Passages[1].Text, "fine-tuning recipe") {
+		t.Errorf("full text passage = %q", doc.Passages[1].Text)
+	}
+}
+
+func TestArxivSource_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>http://arxiv.org/api/errors#incorrect_id_format_for_1234</id><title>Error</title><summary>incorrect id format for 1234</summary></entry></feed>`))
+	}))
+	defer server.Close()
+
+	source := NewArxivSource(server.Client())
+	source.apiURL = server.URL
+	_, err := source.Search(context.Background(), "q", Filter{}, 5)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Message != "incorrect id format for 1234" {
+		t.Errorf("Search() error = %v, want the arXiv error entry", err)
+	}
+}
+
+func TestSemanticScholarSource(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte(`{"total": 1, "offset": 0, "data": [{
+			"paperId": "abc123",
+			"title": "Dense Passage Retrieval for Open-Domain Question Answering",
+			"abstract": null,
+			"tldr": {"model": "tldr@v2.0.0", "text": "Dense retrieval outperforms BM25."},
+			"year": 2020,
+			"publicationDate": null,
+			"url": "https://www.semanticscholar.org/paper/abc123",
+			"authors": [{"authorId": "1", "name": "Vladimir Karpukhin"}],
+			"openAccessPdf": {"url": "https://aclanthology.org/2020.emnlp-main.550.pdf", "status": "HYBRID"},
+			"externalIds": {"DOI": "10.18653/v1/2020.emnlp-main.550", "CorpusId": 215737187},
+			"venue": "EMNLP",
+			"fieldsOfStudy": ["Computer Science"]
+		}]}`))
+	}))
+	defer server.Close()
+
+	source := NewSemanticScholarCompatibleSource(server.URL+"/", "s2-key", server.Client())
+	filter := Filter{Categories: []string{"Computer Science"}, From: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+	papers, err := source.Search(context.Background(), "dense retrieval", filter, 5)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if got.URL.Path != "/paper/search" || got.Header.Get("x-api-key") != "s2-key" {
+		t.Errorf("request to %s with key %q", got.URL.Path, got.Header.Get("x-api-key"))
+	}
+	params := got.URL.Query()
+	if params.Get("fieldsOfStudy") != "Computer Science" || params.Get("publicationDateOrYear") != "2019-01-01:" || params.Get("limit") != "5" {
+		t.Errorf("request parameters = %v", params)
+	}
+
+	if len(papers) != 1 {
+		t.Fatalf("Search() returned %d papers, want 1", len(papers))
+	}
+	p := papers[0]
+	if p.Abstract != "Dense retrieval outperforms BM25." || p.DOI != "10.18653/v1/2020.emnlp-main.550" ||
+		p.PDFURL != "https://aclanthology.org/2020.emnlp-main.550.pdf" || p.Published.Year() != 2020 ||
+		len(p.Authors) != 1 || p.Source != "semanticscholar" {
+		t.Errorf("paper = %+v", p)
+	}
+}
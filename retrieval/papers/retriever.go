@@ -0,0 +1,194 @@
+package papers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxPassageChars = 1500
+	defaultMaxPassages     = 10
+	defaultMaxPDFBytes     = 32 << 20
+	defaultConcurrency     = 4
+	defaultTimeout         = 30 * time.Second
+)
+
+// Config configures a Retriever
+type Config struct {
+	// Filter narrows every search down by subject and date
+	Filter Filter
+	// FullText downloads each paper's open access PDF and adds its text as passages after the abstract. Papers whose
+	// PDF is missing or can't be read keep just their abstract.
+	FullText bool
+	// Client downloads PDFs, http.DefaultClient when nil
+	Client *http.Client
+	// MaxPassageChars is the maximum size of each passage of full text
+	MaxPassageChars int
+	// MaxPassages caps the full text passages kept from each paper
+	MaxPassages int
+	// MaxPDFBytes is the largest PDF that will be downloaded, default 32MB
+	MaxPDFBytes int64
+	// Concurrency is how many PDFs are downloaded at once
+	Concurrency int
+	// Timeout bounds each PDF download, default 30s
+	Timeout time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	if c.MaxPassageChars <= 0 {
+		c.MaxPassageChars = defaultMaxPassageChars
+	}
+	if c.MaxPassages <= 0 {
+		c.MaxPassages = defaultMaxPassages
+	}
+	if c.MaxPDFBytes <= 0 {
+		c.MaxPDFBytes = defaultMaxPDFBytes
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultConcurrency
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	return c
+}
+
+// Retriever implements the retrieval.Retriever interface for academic papers, so answers cite papers rather than
+// posts about them
+type Retriever struct {
+	source Source
+	config Config
+}
+
+func (pr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	papers, err := pr.source.Search(ctx, query, pr.config.Filter, topK)
+	if err != nil {
+		return nil, fmt.Errorf("error searching for papers: %w", err)
+	}
+
+	docs := make([]document.Document, len(papers))
+	for i, p := range papers {
+		docs[i] = toDocument(p)
+	}
+	if !pr.config.FullText {
+		return docs, nil
+	}
+
+	sem := make(chan struct{}, pr.config.Concurrency)
+	var wg sync.WaitGroup
+	for i, p := range papers {
+		if p.PDFURL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(doc *document.Document, pdfURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			passages, err := pr.fullText(ctx, pdfURL)
+			if err != nil {
+				return
+			}
+			doc.Passages = append(doc.Passages, passages...)
+		}(&docs[i], p.PDFURL)
+	}
+	wg.Wait()
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// fullText downloads the PDF at pdfURL and splits its text into passages
+func (pr Retriever) fullText(ctx context.Context, pdfURL string) ([]document.Passage, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pdfURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing request for PDF: %v", err)
+	}
+	req.Header.Set("User-Agent", retrieval.DefaultUserAgent)
+
+	resp, err := pr.config.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading PDF: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading PDF: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, pr.config.MaxPDFBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error downloading PDF: %v", err)
+	}
+	if int64(len(data)) > pr.config.MaxPDFBytes {
+		return nil, fmt.Errorf("PDF is larger than %d bytes", pr.config.MaxPDFBytes)
+	}
+
+	article, err := extract.FromPDF(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	passages := extract.Chunk(article.Sections, pr.config.MaxPassageChars)
+	if len(passages) > pr.config.MaxPassages {
+		passages = passages[:pr.config.MaxPassages]
+	}
+	return passages, nil
+}
+
+// toDocument maps a paper to a document whose first passage is its abstract
+func toDocument(p Paper) document.Document {
+	displayed := p.URL
+	if u, err := url.Parse(p.URL); err == nil {
+		displayed = u.Hostname()
+	}
+
+	var date string
+	if !p.Published.IsZero() {
+		date = p.Published.Format(time.DateOnly)
+	}
+
+	abstract := p.Abstract
+	if abstract == "" {
+		abstract = p.Title
+	}
+
+	return document.Document{
+		Passages: []document.Passage{{Text: abstract}},
+		Corpus:   document.Web,
+		WebReference: &document.WebReference{
+			Title:         p.Title,
+			Link:          p.URL,
+			DisplayedLink: displayed,
+			Blurb:         p.Abstract,
+			Date:          date,
+			Author:        strings.Join(p.Authors, ", "),
+			APISource:     p.Source,
+			PDF:           p.PDFURL,
+		},
+		Title: p.Title,
+	}
+}
+
+func NewRetriever(source Source, config Config) Retriever {
+	return Retriever{source: source, config: config.withDefaults()}
+}
@@ -0,0 +1,171 @@
+package papers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"github.com/coopslarhette/raglib/lib/retrieval/ratelimit"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSemanticScholarURL = "https://api.semanticscholar.org/graph/v1"
+
+// semanticScholarFields are the paper fields requested from the search endpoint
+const semanticScholarFields = "title,authors,abstract,tldr,publicationDate,year,url,openAccessPdf,externalIds,venue,fieldsOfStudy"
+
+// SemanticScholarSource searches the Semantic Scholar Academic Graph API, or any service serving the same JSON
+type SemanticScholarSource struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+	limiter *ratelimit.Limiter
+}
+
+type semanticScholarPaper struct {
+	PaperID  string `json:"paperId"`
+	Title    string `json:"title"`
+	Abstract string `json:"abstract"`
+	TLDR     *struct {
+		Text string `json:"text"`
+	} `json:"tldr"`
+	PublicationDate string `json:"publicationDate"`
+	Year            int    `json:"year"`
+	URL             string `json:"url"`
+	Authors         []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	OpenAccessPDF *struct {
+		URL string `json:"url"`
+	} `json:"openAccessPdf"`
+	ExternalIDs   map[string]any `json:"externalIds"`
+	Venue         string         `json:"venue"`
+	FieldsOfStudy []string       `json:"fieldsOfStudy"`
+}
+
+// NewSemanticScholarSource creates a source for the public Semantic Scholar API. apiKey may be empty, though
+// unauthenticated requests share a low rate limit.
+func NewSemanticScholarSource(apiKey string, client *http.Client) *SemanticScholarSource {
+	return &SemanticScholarSource{
+		apiKey:  apiKey,
+		baseURL: defaultSemanticScholarURL,
+		client:  client,
+	}
+}
+
+// NewSemanticScholarCompatibleSource creates a source for a service serving the Semantic Scholar API at baseURL,
+// e.g. a mirror of the Academic Graph
+func NewSemanticScholarCompatibleSource(baseURL, apiKey string, client *http.Client) *SemanticScholarSource {
+	s := NewSemanticScholarSource(apiKey, client)
+	s.baseURL = strings.TrimRight(baseURL, "/")
+	return s
+}
+
+// SetLimiter throttles, retries and meters the source's requests with limiter
+func (s *SemanticScholarSource) SetLimiter(limiter *ratelimit.Limiter) {
+	s.limiter = limiter
+}
+
+func (s *SemanticScholarSource) Search(ctx context.Context, query string, filter Filter, limit int) ([]Paper, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("fields", semanticScholarFields)
+	if len(filter.Categories) > 0 {
+		params.Set("fieldsOfStudy", strings.Join(filter.Categories, ","))
+	}
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		var from, to string
+		if !filter.From.IsZero() {
+			from = filter.From.Format(time.DateOnly)
+		}
+		if !filter.To.IsZero() {
+			to = filter.To.Format(time.DateOnly)
+		}
+		params.Set("publicationDateOrYear", from+":"+to)
+	}
+
+	var result struct {
+		Data []semanticScholarPaper `json:"data"`
+	}
+	err := s.limiter.Do(ctx, func(ctx context.Context) error {
+		return s.get(ctx, s.baseURL+"/paper/search?"+params.Encode(), &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	papers := make([]Paper, len(result.Data))
+	for i, p := range result.Data {
+		papers[i] = p.paper()
+	}
+	return papers, nil
+}
+
+func (s *SemanticScholarSource) get(ctx context.Context, apiURL string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("error constructing request for Semantic Scholar API: %v", err)
+	}
+	if s.apiKey != "" {
+		req.Header.Set("x-api-key", s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while executing request to Semantic Scholar API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading Semantic Scholar API response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return apierror.FromResponse("semanticscholar", resp, body)
+	}
+
+	if err = json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("error parsing Semantic Scholar API response: %v", err)
+	}
+	return nil
+}
+
+func (p semanticScholarPaper) paper() Paper {
+	paper := Paper{
+		Source:     "semanticscholar",
+		ID:         p.PaperID,
+		Title:      collapseSpace(p.Title),
+		Abstract:   collapseSpace(p.Abstract),
+		URL:        p.URL,
+		Venue:      p.Venue,
+		Categories: p.FieldsOfStudy,
+	}
+	// Publishers don't always license abstracts for redistribution, so fall back to the generated summary
+	if paper.Abstract == "" && p.TLDR != nil {
+		paper.Abstract = p.TLDR.Text
+	}
+
+	if p.PublicationDate != "" {
+		paper.Published, _ = time.Parse(time.DateOnly, p.PublicationDate)
+	} else if p.Year > 0 {
+		paper.Published = time.Date(p.Year, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, a := range p.Authors {
+		paper.Authors = append(paper.Authors, a.Name)
+	}
+	if p.OpenAccessPDF != nil {
+		paper.PDFURL = p.OpenAccessPDF.URL
+	}
+	if doi, ok := p.ExternalIDs["DOI"].(string); ok {
+		paper.DOI = doi
+	}
+	return paper
+}
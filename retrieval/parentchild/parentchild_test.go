@@ -0,0 +1,136 @@
+package parentchild
+
+import (
+	"context"
+	"github.com/coopslarhette/raglib/lib/document"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stubRetriever returns its documents, cut to topK, whatever the query
+type stubRetriever struct {
+	docs  []document.Document
+	topKs []int
+}
+
+func (s *stubRetriever) Query(_ context.Context, _ string, topK int) ([]document.Document, error) {
+	s.topKs = append(s.topKs, topK)
+	if len(s.docs) > topK {
+		return s.docs[:topK], nil
+	}
+	return s.docs, nil
+}
+
+func passages(texts ...string) []document.Passage {
+	ps := make([]document.Passage, len(texts))
+	for i, t := range texts {
+		ps[i] = document.Passage{Text: t}
+	}
+	return ps
+}
+
+func TestSplit(t *testing.T) {
+	doc := document.Document{
+		Title:    "Guide",
+		Corpus:   document.Personal,
+		Passages: []document.Passage{{Text: "Install it.\n\nThen run it.", Metadata: map[string]string{"startLine": "3"}}, {Text: "Configure it."}},
+	}
+
+	parents, children := Split([]document.Document{doc}, 15)
+	if len(parents) != 1 || !reflect.DeepEqual(parents[0].Document, doc) {
+		t.Fatalf("Split() parents = %+v, want the document", parents)
+	}
+	if again, _ := Split([]document.Document{doc}, 15); again[0].ID != parents[0].ID {
+		t.Errorf("Split() ID = %s then %s, want it stable", parents[0].ID, again[0].ID)
+	}
+
+	var got []string
+	for _, c := range children {
+		p := c.Passages[0]
+		if c.Title != "Guide" || c.Corpus != document.Personal || p.Metadata[MetadataParentID] != parents[0].ID {
+			t.Errorf("Split() child = %+v, want the parent's title, corpus and ID", c)
+		}
+		got = append(got, p.Metadata[MetadataParentPassage]+" "+p.Metadata["startLine"]+" "+p.Text)
+	}
+	if want := []string{"0 3 Install it.", "0 3 Then run it.", "1  Configure it."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Split() children = %q, want %q", got, want)
+	}
+	if doc.Passages[0].Metadata[MetadataParentID] != "" {
+		t.Errorf("Split() modified the parent's passage metadata")
+	}
+}
+
+func TestRetriever_Query(t *testing.T) {
+	guide := document.Document{Title: "Guide", Passages: passages("intro", "install", "configure", "deploy", "faq")}
+	notes := document.Document{Title: "Notes", Passages: passages("monday", "tuesday")}
+	parents, children := Split([]document.Document{guide, notes}, 0)
+
+	store := NewMemoryStore()
+	store.Put(parents...)
+
+	// Hits in rank order: guide's deploy, notes' tuesday, guide's install, then a document that wasn't split
+	hits := []document.Document{children[3], children[6], children[1], {Title: "Loose", Passages: passages("loose")}}
+	// Copy the passages before scoring them, so the hit doesn't share them with children[3]
+	hits[0].Passages = append([]document.Passage(nil), hits[0].Passages...)
+	hits[0].Passages[0].Score = 0.9
+
+	summarize := func(docs []document.Document) []string {
+		var got []string
+		for _, d := range docs {
+			var texts []string
+			for _, p := range d.Passages {
+				texts = append(texts, p.Text)
+			}
+			got = append(got, d.Title+": "+strings.Join(texts, ","))
+		}
+		return got
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		topK   int
+		want   []string
+	}{
+		{"passage", Config{}, 3, []string{"Guide: install,deploy", "Notes: tuesday", "Loose: loose"}},
+		{"window", Config{Expansion: ExpandWindow}, 3, []string{"Guide: intro,install,configure,deploy,faq", "Notes: monday,tuesday", "Loose: loose"}},
+		{"document", Config{Expansion: ExpandDocument}, 1, []string{"Guide: intro,install,configure,deploy,faq"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			children := &stubRetriever{docs: hits}
+			docs, err := NewRetriever(children, store, tt.config).Query(context.Background(), "q", tt.topK)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if got := summarize(docs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query() = %q, want %q", got, tt.want)
+			}
+			if children.topKs[0] != tt.topK*defaultOverFetch {
+				t.Errorf("Query() fetched %d children, want %d", children.topKs[0], tt.topK*defaultOverFetch)
+			}
+		})
+	}
+
+	docs, err := NewRetriever(&stubRetriever{docs: hits}, store, Config{}).Query(context.Background(), "q", 1)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if docs[0].Passages[1].Score != 0.9 {
+		t.Errorf("Query() hit passage score = %v, want the child's score", docs[0].Passages[1].Score)
+	}
+	if children[3].Passages[0].Score != 0 {
+		t.Errorf("scoring the hit changed the children fixture")
+	}
+
+	// Without their parents, hits are merged as they are
+	store.Delete(parents[0].ID)
+	docs, err = NewRetriever(&stubRetriever{docs: hits}, store, Config{}).Query(context.Background(), "q", 1)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got, want := summarize(docs), []string{"Guide: install,deploy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Query() without the parent = %q, want %q", got, want)
+	}
+}
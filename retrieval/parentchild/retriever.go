@@ -0,0 +1,192 @@
+package parentchild
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"sort"
+	"strconv"
+)
+
+const (
+	defaultWindow    = 1
+	defaultOverFetch = 4
+)
+
+// Expansion is what a child hit is expanded to
+type Expansion int
+
+const (
+	// ExpandPassage expands a hit to the parent passage it was split from, e.g. a section
+	ExpandPassage Expansion = iota
+	// ExpandWindow expands a hit to its parent passage and Config.Window passages on either side
+	ExpandWindow
+	// ExpandDocument expands a hit to every passage of its parent
+	ExpandDocument
+)
+
+// Config configures a Retriever. Zero values fall back to defaults.
+type Config struct {
+	Expansion Expansion
+	// Window is how many neighboring parent passages on each side ExpandWindow adds, default 1
+	Window int
+	// OverFetch is how many children are retrieved per parent returned, default 4, as several hits often share a
+	// parent
+	OverFetch int
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = defaultWindow
+	}
+	if c.OverFetch <= 0 {
+		c.OverFetch = defaultOverFetch
+	}
+	return c
+}
+
+// Retriever implements the retrieval.Retriever interface with small-to-big retrieval. Small child passages, as made
+// by Split, embed precisely, but give the Answerer too little context, so each child hit from the underlying
+// retriever is expanded to its parent's passage, a window of neighboring passages or the whole parent document.
+// Hits sharing a parent are merged into one document holding the union of their passages in the parent's order.
+type Retriever struct {
+	children retrieval.Retriever
+	store    Store
+	config   Config
+}
+
+// group is a parent being assembled from its child hits, in the order of its best hit
+type group struct {
+	parentID string
+	// passages are the indexes of the parent's passages hit, with the best score of their children
+	passages map[int]float64
+	// hits are the child documents themselves, returned if the parent can't be found
+	hits []document.Document
+}
+
+func (pr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	hits, err := pr.children.Query(ctx, query, topK*pr.config.OverFetch)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving child passages: %w", err)
+	}
+
+	var groups []*group
+	byParent := make(map[string]*group)
+	for _, hit := range hits {
+		var parentID string
+		index := -1
+		if len(hit.Passages) > 0 {
+			parentID = hit.Passages[0].Metadata[MetadataParentID]
+			if i, err := strconv.Atoi(hit.Passages[0].Metadata[MetadataParentPassage]); err == nil {
+				index = i
+			}
+		}
+		// Documents that weren't split from a parent pass through as they are
+		if parentID == "" || index < 0 {
+			groups = append(groups, &group{hits: []document.Document{hit}})
+			continue
+		}
+
+		g, ok := byParent[parentID]
+		if !ok {
+			g = &group{parentID: parentID, passages: make(map[int]float64)}
+			byParent[parentID] = g
+			groups = append(groups, g)
+		}
+		if score, ok := g.passages[index]; !ok || hit.Passages[0].Score > score {
+			g.passages[index] = hit.Passages[0].Score
+		}
+		g.hits = append(g.hits, hit)
+	}
+	if len(groups) > topK {
+		groups = groups[:topK]
+	}
+
+	ids := make([]string, 0, len(byParent))
+	for _, g := range groups {
+		if g.parentID != "" {
+			ids = append(ids, g.parentID)
+		}
+	}
+	parents, err := pr.store.Parents(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching parent documents: %w", err)
+	}
+
+	docs := make([]document.Document, 0, len(groups))
+	for _, g := range groups {
+		parent, ok := parents[g.parentID]
+		if !ok {
+			docs = append(docs, mergeHits(g.hits))
+			continue
+		}
+		// The parent may have changed since its children were indexed
+		if doc := pr.expand(parent, g.passages); len(doc.Passages) > 0 {
+			docs = append(docs, doc)
+		} else {
+			docs = append(docs, mergeHits(g.hits))
+		}
+	}
+	return docs, nil
+}
+
+// expand returns parent with only the passages its hits expand to, in the parent's order. Hit passages take the best
+// score of their children, when the child retriever scores them.
+func (pr Retriever) expand(parent document.Document, hits map[int]float64) document.Document {
+	keep := make(map[int]bool)
+	for index := range hits {
+		switch pr.config.Expansion {
+		case ExpandWindow:
+			for i := index - pr.config.Window; i <= index+pr.config.Window; i++ {
+				keep[i] = true
+			}
+		case ExpandDocument:
+			for i := range parent.Passages {
+				keep[i] = true
+			}
+		default:
+			keep[index] = true
+		}
+	}
+
+	passages := make([]document.Passage, 0, len(keep))
+	for i, p := range parent.Passages {
+		if !keep[i] {
+			continue
+		}
+		if score, ok := hits[i]; ok && score != 0 {
+			p.Score = score
+		}
+		passages = append(passages, p)
+	}
+
+	parent.Passages = passages
+	return parent
+}
+
+// mergeHits joins the child hits of a parent missing from the store into one document, so the Answerer still sees
+// what matched
+func mergeHits(hits []document.Document) document.Document {
+	merged := hits[0]
+	merged.Passages = nil
+	for _, hit := range hits {
+		merged.Passages = append(merged.Passages, hit.Passages...)
+	}
+	sort.SliceStable(merged.Passages, func(i, j int) bool {
+		a, _ := strconv.Atoi(merged.Passages[i].Metadata[MetadataParentPassage])
+		b, _ := strconv.Atoi(merged.Passages[j].Metadata[MetadataParentPassage])
+		return a < b
+	})
+	return merged
+}
+
+// NewRetriever wraps children, a retriever over child passages made by Split such as a qdrant.Retriever, expanding
+// its hits to the parents in store
+func NewRetriever(children retrieval.Retriever, store Store, config Config) Retriever {
+	return Retriever{children: children, store: store, config: config.withDefaults()}
+}
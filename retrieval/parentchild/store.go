@@ -0,0 +1,117 @@
+package parentchild
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"strconv"
+	"sync"
+)
+
+// Passage metadata keys linking a child passage to the parent it was split from. The retriever searching the children
+// must return them: pgvector and SQLite retrievers return all entry metadata, while a qdrant.Retriever only returns the
+// payload fields it was given, e.g. qdrant.Retriever.WithMetadata(MetadataParentID, MetadataParentPassage).
+const (
+	// MetadataParentID is the ID of the parent document in the Store
+	MetadataParentID = "parentID"
+	// MetadataParentPassage is the index of the parent's passage the child was split from
+	MetadataParentPassage = "parentPassage"
+)
+
+// Parent is a document children were split from, stored under ID
+type Parent struct {
+	ID       string
+	Document document.Document
+}
+
+// Store holds the parent documents that child passages point to
+type Store interface {
+	// Parents returns the parents with the given IDs, leaving out any it doesn't have
+	Parents(ctx context.Context, ids []string) (map[string]document.Document, error)
+}
+
+// MemoryStore is an in-process Store
+type MemoryStore struct {
+	mu      sync.RWMutex
+	parents map[string]document.Document
+}
+
+// Put adds parents, replacing any already stored under the same IDs
+func (s *MemoryStore) Put(parents ...Parent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range parents {
+		s.parents[p.ID] = p.Document
+	}
+}
+
+// Delete removes the parents with the given IDs
+func (s *MemoryStore) Delete(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.parents, id)
+	}
+}
+
+func (s *MemoryStore) Parents(_ context.Context, ids []string) (map[string]document.Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parents := make(map[string]document.Document, len(ids))
+	for _, id := range ids {
+		if doc, ok := s.parents[id]; ok {
+			parents[id] = doc
+		}
+	}
+	return parents, nil
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{parents: make(map[string]document.Document)}
+}
+
+// Split prepares documents for parent-child retrieval. Each document becomes a Parent to put in a Store, and each of
+// its passages is split into children of at most maxChildChars to embed and index in a vector store, one child per
+// document. Children keep their parent's title, corpus and references, and their passage's Metadata gains the
+// parent's ID and passage index alongside the metadata of the passage they came from. IDs hash the document's title
+// and text, so splitting the same document again gives the same ID.
+func Split(docs []document.Document, maxChildChars int) (parents []Parent, children []document.Document) {
+	for _, doc := range docs {
+		id := parentID(doc)
+		parents = append(parents, Parent{ID: id, Document: doc})
+
+		for i, p := range doc.Passages {
+			for _, child := range extract.Chunk([]extract.Section{{Text: p.Text}}, maxChildChars) {
+				metadata := make(map[string]string, len(p.Metadata)+2)
+				for k, v := range p.Metadata {
+					metadata[k] = v
+				}
+				metadata[MetadataParentID] = id
+				metadata[MetadataParentPassage] = strconv.Itoa(i)
+
+				children = append(children, document.Document{
+					Passages:      []document.Passage{{Text: child.Text, Metadata: metadata}},
+					Title:         doc.Title,
+					Corpus:        doc.Corpus,
+					WebReference:  doc.WebReference,
+					FileReference: doc.FileReference,
+				})
+			}
+		}
+	}
+	return parents, children
+}
+
+// parentID hashes a document's title and passages
+func parentID(doc document.Document) string {
+	h := sha256.New()
+	h.Write([]byte(doc.Title))
+	for _, p := range doc.Passages {
+		h.Write([]byte{0})
+		h.Write([]byte(p.Text))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
@@ -0,0 +1,109 @@
+package pgvector
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Filter restricts a search to rows whose JSONB metadata matches. Filters are rendered to SQL so Postgres applies
+// them, using the metadata column's GIN index where it can. A nil Filter matches every row.
+type Filter interface {
+	// where renders the filter as a SQL condition on column, appending its parameters to args
+	where(column string, args *[]any) string
+}
+
+type equals struct{ key, value string }
+
+// Equals matches rows whose metadata value for key is exactly value
+func Equals(key, value string) Filter {
+	return equals{key, value}
+}
+
+func (f equals) where(column string, args *[]any) string {
+	// Containment rather than ->> so the GIN index is used
+	doc, _ := json.Marshal(map[string]string{f.key: f.value})
+	return column + " @> " + param(args, string(doc)) + "::jsonb"
+}
+
+type in struct {
+	key    string
+	values []string
+}
+
+// In matches rows whose metadata value for key is any of values
+func In(key string, values ...string) Filter {
+	return in{key, values}
+}
+
+func (f in) where(column string, args *[]any) string {
+	if len(f.values) == 0 {
+		return "FALSE"
+	}
+	placeholders := make([]string, len(f.values))
+	for i, v := range f.values {
+		placeholders[i] = param(args, v)
+	}
+	return "(" + column + " ->> " + param(args, f.key) + ") IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+type exists struct{ key string }
+
+// Exists matches rows that have any value for key
+func Exists(key string) Filter {
+	return exists{key}
+}
+
+func (f exists) where(column string, args *[]any) string {
+	return column + " ? " + param(args, f.key)
+}
+
+type and []Filter
+
+// And matches rows that satisfy every one of filters
+func And(filters ...Filter) Filter {
+	return and(filters)
+}
+
+func (f and) where(column string, args *[]any) string {
+	return join(f, " AND ", "TRUE", column, args)
+}
+
+type or []Filter
+
+// Or matches rows that satisfy any of filters
+func Or(filters ...Filter) Filter {
+	return or(filters)
+}
+
+func (f or) where(column string, args *[]any) string {
+	return join(f, " OR ", "FALSE", column, args)
+}
+
+type not struct{ filter Filter }
+
+// Not matches rows that don't satisfy filter
+func Not(filter Filter) Filter {
+	return not{filter}
+}
+
+func (f not) where(column string, args *[]any) string {
+	return "NOT (" + f.filter.where(column, args) + ")"
+}
+
+func join(filters []Filter, op, empty, column string, args *[]any) string {
+	if len(filters) == 0 {
+		return empty
+	}
+	conditions := make([]string, len(filters))
+	for i, f := range filters {
+		conditions[i] = "(" + f.where(column, args) + ")"
+	}
+	return strings.Join(conditions, op)
+}
+
+// param appends value to args and returns its placeholder
+func param(args *[]any, value any) string {
+	*args = append(*args, value)
+	return "$" + strconv.Itoa(len(*args))
+}
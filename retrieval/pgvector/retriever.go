@@ -0,0 +1,93 @@
+package pgvector
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/metadata"
+)
+
+// Retriever implements the retrieval.Retriever interface over a Postgres table with a pgvector column. It retrieves
+// non-web documents via query embeddings, like qdrant.Retriever, for deployments that already run Postgres.
+type Retriever struct {
+	store    *Store
+	embedder retrieval.Embedder
+	filter   Filter
+	// metadataKeys narrows the entry metadata copied to each returned passage, all of it is copied when empty
+	metadataKeys []string
+}
+
+func (pr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	vectors, err := retrieval.Embed(ctx, pr.embedder, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error creating query embedding: %v", err)
+	}
+
+	results, err := pr.store.Search(ctx, vectors[0], topK, pr.filter)
+	if err != nil {
+		return nil, fmt.Errorf("error searching pgvector table: %w", err)
+	}
+
+	docs := make([]document.Document, len(results))
+	for i, r := range results {
+		md := r.Entry.Metadata
+		if len(pr.metadataKeys) > 0 {
+			md = metadata.Select(md, pr.metadataKeys)
+		}
+		docs[i] = document.Document{
+			Passages: []document.Passage{{Text: r.Entry.Text, Metadata: md}},
+			Corpus:   document.Personal,
+			Title:    r.Entry.Title,
+		}
+	}
+	return docs, nil
+}
+
+// Add embeds the Text of any entries that don't have a Vector yet and upserts them into the table
+func (pr Retriever) Add(ctx context.Context, entries ...Entry) error {
+	var texts []string
+	var missing []int
+	for i, e := range entries {
+		if len(e.Vector) == 0 {
+			texts = append(texts, e.Text)
+			missing = append(missing, i)
+		}
+	}
+
+	if len(texts) > 0 {
+		vectors, err := retrieval.Embed(ctx, pr.embedder, texts)
+		if err != nil {
+			return fmt.Errorf("error embedding entries: %v", err)
+		}
+
+		entries = append([]Entry(nil), entries...)
+		for j, i := range missing {
+			entries[i].Vector = vectors[j]
+		}
+	}
+
+	return pr.store.Upsert(ctx, entries...)
+}
+
+// WithMetadata returns a copy of the retriever whose passages only carry the entry metadata under keys
+func (pr Retriever) WithMetadata(keys ...string) Retriever {
+	pr.metadataKeys = keys
+	return pr
+}
+
+// WithFilter returns a copy of the retriever that only returns entries whose metadata matches filter
+func (pr Retriever) WithFilter(filter Filter) Retriever {
+	pr.filter = filter
+	return pr
+}
+
+// NewRetriever creates a Retriever over store. The embedder must be the same model that was used to embed the
+// entries in the table.
+func NewRetriever(store *Store, embedder retrieval.Embedder) Retriever {
+	return Retriever{store: store, embedder: embedder}
+}
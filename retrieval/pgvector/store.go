@@ -0,0 +1,402 @@
+package pgvector
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Distance selects the pgvector operator searches order by, and the operator class indexes are built with
+type Distance int
+
+const (
+	Cosine Distance = iota
+	L2
+	InnerProduct
+)
+
+func (d Distance) operator() string {
+	return [...]string{"<=>", "<->", "<#>"}[d]
+}
+
+// String names the distance in index names
+func (d Distance) String() string {
+	return [...]string{"cosine", "l2", "ip"}[d]
+}
+
+func (d Distance) opClass() string {
+	return [...]string{"vector_cosine_ops", "vector_l2_ops", "vector_ip_ops"}[d]
+}
+
+// score converts a distance returned by the operator into a similarity, where higher is more similar
+func (d Distance) score(distance float64) float32 {
+	if d == Cosine {
+		return float32(1 - distance)
+	}
+	// <-> is a distance so it's negated, and <#> returns the negative inner product so that ascending order puts the
+	// most similar first
+	return float32(-distance)
+}
+
+// IndexType is the kind of approximate nearest neighbor index CreateIndex builds
+type IndexType int
+
+const (
+	// HNSW builds a graph index, slower to build and larger than IVFFlat but with better recall, and it can be
+	// created on an empty table
+	HNSW IndexType = iota
+	// IVFFlat clusters vectors into lists. Create it after the table is loaded, since the lists are chosen from the
+	// rows present when it's built.
+	IVFFlat
+)
+
+// Columns names the columns of the table. Empty names fall back to the defaults in parentheses.
+type Columns struct {
+	// ID (id) is the primary key, a text column
+	ID string
+	// Title (title) is an optional text column
+	Title string
+	// Text (text) is the text the embedding was made from
+	Text string
+	// Metadata (metadata) is a JSONB object of string values
+	Metadata string
+	// Embedding (embedding) is the vector column
+	Embedding string
+}
+
+// Config describes the table a Store reads and writes
+type Config struct {
+	// Table is the table's name, optionally qualified by its schema, e.g. "public.chunks"
+	Table    string
+	Columns  Columns
+	Distance Distance
+	// Dimensions is the length of the vectors, needed by CreateTable
+	Dimensions int
+	// EfSearch sets hnsw.ef_search for searches, the size of the HNSW candidate list. Higher improves recall at the
+	// cost of speed. 0 keeps the server's setting.
+	EfSearch int
+	// Probes sets ivfflat.probes for searches, the number of IVFFlat lists scanned. 0 keeps the server's setting.
+	Probes int
+}
+
+func (c Config) withDefaults() Config {
+	defaults := []struct {
+		column *string
+		name   string
+	}{
+		{&c.Columns.ID, "id"},
+		{&c.Columns.Title, "title"},
+		{&c.Columns.Text, "text"},
+		{&c.Columns.Metadata, "metadata"},
+		{&c.Columns.Embedding, "embedding"},
+	}
+	for _, d := range defaults {
+		if *d.column == "" {
+			*d.column = d.name
+		}
+	}
+	return c
+}
+
+// IndexConfig controls the index built by CreateIndex. Zero values fall back to pgvector's defaults.
+type IndexConfig struct {
+	Type IndexType
+	// M is the maximum number of connections per HNSW node (default 16)
+	M int
+	// EfConstruction is the size of the HNSW candidate list while building (default 64)
+	EfConstruction int
+	// Lists is the number of IVFFlat lists, pgvector suggests rows / 1000 for up to a million rows
+	Lists int
+}
+
+// Entry is a single row of the table
+type Entry struct {
+	// ID uniquely identifies the entry, upserting an entry with an existing ID replaces it. When empty a random one
+	// is assigned.
+	ID       string
+	Title    string
+	Text     string
+	Metadata map[string]string
+	// Vector is the embedding of Text. It's left empty on entries returned by Search.
+	Vector []float32
+}
+
+// Result is an Entry matched by a search, along with its similarity to the query. Higher scores are more similar.
+type Result struct {
+	Entry Entry
+	Score float32
+}
+
+// Store reads and writes entries in a Postgres table with a pgvector column. It works with any database/sql driver
+// for Postgres, e.g. github.com/lib/pq or github.com/jackc/pgx/v5/stdlib, and is safe for concurrent use.
+type Store struct {
+	db     *sql.DB
+	config Config
+	table  string
+	// quoted column names
+	id, title, text, metadata, embedding string
+}
+
+func NewStore(db *sql.DB, config Config) (*Store, error) {
+	config = config.withDefaults()
+	if config.Table == "" {
+		return nil, fmt.Errorf("pgvector store needs a table name")
+	}
+	if config.Distance < Cosine || config.Distance > InnerProduct {
+		return nil, fmt.Errorf("unknown distance %d", config.Distance)
+	}
+
+	return &Store{
+		db:        db,
+		config:    config,
+		table:     quoteIdentifier(config.Table),
+		id:        quoteIdentifier(config.Columns.ID),
+		title:     quoteIdentifier(config.Columns.Title),
+		text:      quoteIdentifier(config.Columns.Text),
+		metadata:  quoteIdentifier(config.Columns.Metadata),
+		embedding: quoteIdentifier(config.Columns.Embedding),
+	}, nil
+}
+
+// CreateTable creates the vector extension, the table and a GIN index on its metadata if they don't exist yet
+func (s *Store) CreateTable(ctx context.Context) error {
+	if s.config.Dimensions <= 0 {
+		return fmt.Errorf("creating a pgvector table needs the vector dimensions")
+	}
+
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s TEXT PRIMARY KEY,
+	%s TEXT NOT NULL DEFAULT '',
+	%s TEXT NOT NULL,
+	%s JSONB NOT NULL DEFAULT '{}',
+	%s vector(%d) NOT NULL
+)`, s.table, s.id, s.title, s.text, s.metadata, s.embedding, s.config.Dimensions),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING gin (%s)",
+			quoteIdentifier(s.indexName(s.config.Columns.Metadata, "gin")), s.table, s.metadata),
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("error creating pgvector table: %v", err)
+		}
+	}
+	return nil
+}
+
+// CreateIndex builds an approximate nearest neighbor index on the vector column for the store's Distance, if it
+// doesn't exist yet. Without one, searches scan the whole table, which is exact but slow on large tables.
+func (s *Store) CreateIndex(ctx context.Context, config IndexConfig) error {
+	var method string
+	var options []string
+	switch config.Type {
+	case HNSW:
+		method = "hnsw"
+		if config.M > 0 {
+			options = append(options, "m = "+strconv.Itoa(config.M))
+		}
+		if config.EfConstruction > 0 {
+			options = append(options, "ef_construction = "+strconv.Itoa(config.EfConstruction))
+		}
+	case IVFFlat:
+		method = "ivfflat"
+		if config.Lists > 0 {
+			options = append(options, "lists = "+strconv.Itoa(config.Lists))
+		}
+	default:
+		return fmt.Errorf("unknown index type %d", config.Type)
+	}
+
+	stmt := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING %s (%s %s)",
+		quoteIdentifier(s.indexName(s.config.Columns.Embedding, method+"_"+s.config.Distance.String())), s.table, method, s.embedding, s.config.Distance.opClass())
+	if len(options) > 0 {
+		stmt += " WITH (" + strings.Join(options, ", ") + ")"
+	}
+
+	if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("error creating %s index: %v", method, err)
+	}
+	return nil
+}
+
+// Upsert inserts entries, replacing any existing entries with the same IDs, in a single transaction
+func (s *Store) Upsert(ctx context.Context, entries ...Entry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (%s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4::jsonb, $5::vector)
+ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s`,
+		s.table, s.id, s.title, s.text, s.metadata, s.embedding,
+		s.id, s.title, s.title, s.text, s.text, s.metadata, s.metadata, s.embedding, s.embedding))
+	if err != nil {
+		return fmt.Errorf("error preparing upsert: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if len(e.Vector) == 0 {
+			return fmt.Errorf("entry %q has no vector", e.ID)
+		}
+		if s.config.Dimensions > 0 && len(e.Vector) != s.config.Dimensions {
+			return fmt.Errorf("entry %q has %d dimensions, want %d", e.ID, len(e.Vector), s.config.Dimensions)
+		}
+		if e.ID == "" {
+			if e.ID, err = randomID(); err != nil {
+				return err
+			}
+		}
+
+		metadata := e.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadataJSON, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("error encoding metadata of entry %q: %v", e.ID, err)
+		}
+
+		if _, err = stmt.ExecContext(ctx, e.ID, e.Title, e.Text, string(metadataJSON), vectorLiteral(e.Vector)); err != nil {
+			return fmt.Errorf("error upserting entry %q: %v", e.ID, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing upsert: %v", err)
+	}
+	return nil
+}
+
+// Delete removes the entries with the given IDs
+func (s *Store) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var args []any
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		placeholders[i] = param(&args, id)
+	}
+
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", s.table, s.id, strings.Join(placeholders, ", "))
+	if _, err := s.db.ExecContext(ctx, stmt, args...); err != nil {
+		return fmt.Errorf("error deleting entries: %v", err)
+	}
+	return nil
+}
+
+// Search returns up to topK entries matching filter, ordered by their similarity to vector
+func (s *Store) Search(ctx context.Context, vector []float32, topK int, filter Filter) ([]Result, error) {
+	if topK <= 0 {
+		return nil, nil
+	}
+	query, args := s.searchQuery(vector, topK, filter)
+
+	// Index tuning settings only last for the transaction, so they can't leak to other users of the pool
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if s.config.EfSearch > 0 {
+		if _, err = tx.ExecContext(ctx, "SET LOCAL hnsw.ef_search = "+strconv.Itoa(s.config.EfSearch)); err != nil {
+			return nil, fmt.Errorf("error setting hnsw.ef_search: %v", err)
+		}
+	}
+	if s.config.Probes > 0 {
+		if _, err = tx.ExecContext(ctx, "SET LOCAL ivfflat.probes = "+strconv.Itoa(s.config.Probes)); err != nil {
+			return nil, fmt.Errorf("error setting ivfflat.probes: %v", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error searching %s: %v", s.config.Table, err)
+	}
+	defer rows.Close()
+
+	var results []Result
+	for rows.Next() {
+		var r Result
+		var metadata []byte
+		var distance float64
+		if err = rows.Scan(&r.Entry.ID, &r.Entry.Title, &r.Entry.Text, &metadata, &distance); err != nil {
+			return nil, fmt.Errorf("error reading search result: %v", err)
+		}
+		if len(metadata) > 0 {
+			if err = json.Unmarshal(metadata, &r.Entry.Metadata); err != nil {
+				return nil, fmt.Errorf("error decoding metadata of entry %q: %v", r.Entry.ID, err)
+			}
+		}
+		r.Score = s.config.Distance.score(distance)
+		results = append(results, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading search results: %v", err)
+	}
+	return results, nil
+}
+
+func (s *Store) searchQuery(vector []float32, topK int, filter Filter) (string, []any) {
+	var args []any
+	distance := s.embedding + " " + s.config.Distance.operator() + " " + param(&args, vectorLiteral(vector)) + "::vector"
+
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s, %s AS distance FROM %s",
+		s.id, s.title, s.text, s.metadata, distance, s.table)
+	if filter != nil {
+		query += " WHERE " + filter.where(s.metadata, &args)
+	}
+	query += " ORDER BY distance LIMIT " + param(&args, topK)
+	return query, args
+}
+
+// indexName names an index of kind on column of the table, e.g. "chunks_embedding_hnsw_cosine_idx" for an HNSW
+// cosine index on the embedding column of public.chunks. Vector indexes include their distance in kind, since each
+// distance needs its own index.
+func (s *Store) indexName(column, kind string) string {
+	table := s.config.Table
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		table = table[i+1:]
+	}
+	return table + "_" + column + "_" + kind + "_idx"
+}
+
+// quoteIdentifier quotes a possibly schema-qualified name for use in SQL
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// vectorLiteral formats v in pgvector's text representation, e.g. [1,2.5,3]
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating entry id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
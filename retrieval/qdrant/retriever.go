@@ -4,29 +4,27 @@ import (
 	"context"
 	"fmt"
 	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
 	qdrant "github.com/qdrant/go-client/qdrant"
-	"github.com/sashabaranov/go-openai"
+	"strconv"
 )
 
 // Retriever implements the retrieval.Retriever interface. It retrieves non-web documents via query embeddings.
 type Retriever struct {
 	pointsClient   qdrant.PointsClient
-	openaiClient   *openai.Client
+	embedder       retrieval.Embedder
 	collectionName string
+	// metadataKeys are the payload fields copied to each returned passage's Metadata
+	metadataKeys []string
 }
 
 func (qr Retriever) toQueryEmbedding(ctx context.Context, query string) ([]float32, error) {
-	req := openai.EmbeddingRequest{
-		Input: []string{query},
-		Model: openai.AdaEmbeddingV2,
-	}
-
-	resp, err := qr.openaiClient.CreateEmbeddings(ctx, req)
+	vectors, err := retrieval.Embed(ctx, qr.embedder, []string{query})
 	if err != nil {
 		return nil, fmt.Errorf("error creating vector from query: %v", err)
 	}
 
-	return resp.Data[0].Embedding, nil
+	return vectors[0], nil
 }
 
 func (qr Retriever) Query(ctx context.Context, query string, maxTopK int) ([]document.Document, error) {
@@ -54,13 +52,47 @@ func (qr Retriever) Query(ctx context.Context, query string, maxTopK int) ([]doc
 			Passages: []document.Passage{
 				// TODO: maybe setup Query to accept a kind of parser as an argument to
 				//   handle different search results types
-				{Text: r.Payload["text"].GetStringValue()},
+				{Text: r.Payload["text"].GetStringValue(), Metadata: payloadMetadata(r.Payload, qr.metadataKeys)},
 			},
 		}
 	}
 	return docs, nil
 }
 
-func NewRetriever(pointsClient qdrant.PointsClient, openaiClient *openai.Client, collectionName string) Retriever {
-	return Retriever{pointsClient, openaiClient, collectionName}
+// payloadMetadata returns the scalar payload fields under keys as strings, like the parent a child passage was split
+// from, or nil if there are none
+func payloadMetadata(payload map[string]*qdrant.Value, keys []string) map[string]string {
+	var metadata map[string]string
+	for _, key := range keys {
+		var s string
+		switch v := payload[key].GetKind().(type) {
+		case *qdrant.Value_StringValue:
+			s = v.StringValue
+		case *qdrant.Value_IntegerValue:
+			s = strconv.FormatInt(v.IntegerValue, 10)
+		case *qdrant.Value_DoubleValue:
+			s = strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+		case *qdrant.Value_BoolValue:
+			s = strconv.FormatBool(v.BoolValue)
+		default:
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[key] = s
+	}
+	return metadata
+}
+
+// WithMetadata returns a copy of the retriever that copies the scalar payload fields under keys to passage Metadata
+func (qr Retriever) WithMetadata(keys ...string) Retriever {
+	qr.metadataKeys = keys
+	return qr
+}
+
+// NewRetriever creates a Retriever over collectionName. The embedder must be the same model that was used to embed
+// the points in the collection, e.g. modelproviders.NewOpenAIEmbedder(client, openai.AdaEmbeddingV2).
+func NewRetriever(pointsClient qdrant.PointsClient, embedder retrieval.Embedder, collectionName string) Retriever {
+	return Retriever{pointsClient: pointsClient, embedder: embedder, collectionName: collectionName}
 }
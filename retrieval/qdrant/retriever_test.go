@@ -0,0 +1,28 @@
+package qdrant
+
+import (
+	qdrant "github.com/qdrant/go-client/qdrant"
+	"reflect"
+	"testing"
+)
+
+func TestPayloadMetadata(t *testing.T) {
+	payload := map[string]*qdrant.Value{
+		"text":          {Kind: &qdrant.Value_StringValue{StringValue: "A child passage."}},
+		"parentID":      {Kind: &qdrant.Value_StringValue{StringValue: "p1"}},
+		"parentPassage": {Kind: &qdrant.Value_IntegerValue{IntegerValue: 3}},
+		"score":         {Kind: &qdrant.Value_DoubleValue{DoubleValue: 0.5}},
+		"draft":         {Kind: &qdrant.Value_BoolValue{BoolValue: true}},
+		"tags":          {Kind: &qdrant.Value_ListValue{ListValue: &qdrant.ListValue{}}},
+	}
+
+	got := payloadMetadata(payload, []string{"parentID", "parentPassage", "score", "draft", "tags", "missing"})
+	want := map[string]string{"parentID": "p1", "parentPassage": "3", "score": "0.5", "draft": "true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payloadMetadata() = %v, want %v", got, want)
+	}
+
+	if got := payloadMetadata(payload, nil); got != nil {
+		t.Errorf("payloadMetadata() without keys = %v, want nil", got)
+	}
+}
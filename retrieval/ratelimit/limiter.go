@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// Config controls a Limiter. Zero values disable the corresponding limit, except for the retry settings which have
+// defaults.
+type Config struct {
+	// RequestsPerSecond is the steady rate requests are allowed at, which may be fractional, e.g. 0.5 for one
+	// request every two seconds
+	RequestsPerSecond float64
+	// Burst is how many requests may be sent at once after a quiet period, defaults to 1 when RequestsPerSecond is set
+	Burst int
+	// MaxConcurrent caps the number of requests in flight at once
+	MaxConcurrent int
+	// MaxRetries is how many times a request rejected with a retryable error, i.e. a 429 or 5xx status, is retried.
+	// Defaults to 3, a negative value turns retries off.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry, doubling for each retry after it up to MaxBackoff. A
+	// provider's Retry-After takes precedence. Default 1s and 30s.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// QuotaPeriod is the period usage is counted over, matching how the provider meters the plan
+	QuotaPeriod Period
+}
+
+// Limiter throttles requests to a search API, retries the ones it rejects for going too fast, and counts how many
+// succeed. A nil *Limiter is valid and runs requests unthrottled. It is safe for concurrent use.
+type Limiter struct {
+	config Config
+	bucket *bucket
+	slots  chan struct{}
+	usage  *counter
+}
+
+// Do waits until the request may be sent, then runs it, retrying it with backoff while it fails with a retryable
+// apierror.Error. Successful requests count towards usage.
+func (l *Limiter) Do(ctx context.Context, request func(ctx context.Context) error) error {
+	if l == nil {
+		return request(ctx)
+	}
+
+	backoff := l.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := l.attempt(ctx, request)
+		if err == nil {
+			l.usage.record(time.Now())
+			return nil
+		}
+
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) || !apiErr.Retryable() {
+			return err
+		}
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			l.usage.throttled(time.Now())
+		} else {
+			l.usage.serverError(time.Now())
+		}
+		if attempt >= l.config.MaxRetries {
+			return err
+		}
+
+		wait := apiErr.RetryAfter
+		if wait == 0 {
+			// Full jitter keeps concurrent callers that were rejected together from retrying together
+			wait = time.Duration(rand.Int63n(int64(backoff)) + 1)
+			backoff = min(2*backoff, l.config.MaxBackoff)
+		}
+		if err = sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *Limiter) attempt(ctx context.Context, request func(ctx context.Context) error) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			defer func() { <-l.slots }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	return request(ctx)
+}
+
+// Usage returns how many requests have succeeded in the current quota period and overall
+func (l *Limiter) Usage() Usage {
+	if l == nil {
+		return Usage{}
+	}
+	return l.usage.snapshot(time.Now())
+}
+
+// Publish exports the limiter's usage as an expvar variable under name, served at /debug/vars alongside other
+// expvar metrics. Like expvar.Publish, it panics if name is already in use.
+func (l *Limiter) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return l.Usage()
+	}))
+}
+
+func New(config Config) *Limiter {
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+
+	l := &Limiter{
+		config: config,
+		usage:  &counter{period: config.QuotaPeriod},
+	}
+	if config.RequestsPerSecond > 0 {
+		l.bucket = newBucket(config.RequestsPerSecond, max(config.Burst, 1))
+	}
+	if config.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	return l
+}
+
+// bucket is a token bucket holding up to burst tokens, refilled at rate tokens per second
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a token is available and takes it
+func (b *bucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"testing"
+	"time"
+)
+
+func TestLimiter_RetriesRetryableErrors(t *testing.T) {
+	l := New(Config{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+
+	calls := 0
+	err := l.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &apierror.Error{Provider: "test", StatusCode: 429}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+
+	usage := l.Usage()
+	if usage.Searches != 1 || usage.TotalSearches != 1 || usage.Throttled != 2 {
+		t.Errorf("Usage() = %+v, want 1 search and 2 throttled", usage)
+	}
+}
+
+func TestLimiter_GivesUp(t *testing.T) {
+	l := New(Config{MaxRetries: 1, InitialBackoff: time.Millisecond})
+
+	calls := 0
+	err := l.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return &apierror.Error{Provider: "test", StatusCode: 503}
+	})
+	if !apierror.IsRetryable(err) {
+		t.Errorf("Do() error = %v, want the retryable API error", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if usage := l.Usage(); usage.ServerErrors != 2 || usage.Throttled != 0 {
+		t.Errorf("Usage() = %+v, want 2 server errors and 0 throttled", usage)
+	}
+
+	permanent := errors.New("bad request")
+	calls = 0
+	err = l.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return permanent
+	})
+	if !errors.Is(err, permanent) || calls != 1 {
+		t.Errorf("Do() error = %v after %d calls, want %v after 1", err, calls, permanent)
+	}
+	if usage := l.Usage(); usage.Searches != 0 {
+		t.Errorf("Usage().Searches = %d, want 0", usage.Searches)
+	}
+}
+
+func TestLimiter_DefaultRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantCalls  int
+	}{
+		{name: "default", maxRetries: 0, wantCalls: defaultMaxRetries + 1},
+		{name: "disabled", maxRetries: -1, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(Config{MaxRetries: tt.maxRetries, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+			calls := 0
+			_ = l.Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				return &apierror.Error{Provider: "test", StatusCode: 429}
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestLimiter_HonorsRetryAfter(t *testing.T) {
+	l := New(Config{MaxRetries: 1, InitialBackoff: time.Hour})
+
+	calls := 0
+	start := time.Now()
+	err := l.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &apierror.Error{Provider: "test", StatusCode: 429, RetryAfter: 10 * time.Millisecond}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Do() took %v, want about 10ms", elapsed)
+	}
+}
+
+func TestLimiter_RateLimits(t *testing.T) {
+	l := New(Config{RequestsPerSecond: 100, Burst: 2})
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := l.Do(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+	}
+	// Two requests go out immediately and the other three wait 10ms each
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("5 requests took %v, want at least 25ms", elapsed)
+	}
+}
+
+func TestLimiter_ContextCancelled(t *testing.T) {
+	l := New(Config{RequestsPerSecond: 0.001})
+	_ = l.Do(context.Background(), func(ctx context.Context) error { return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := l.Do(ctx, func(ctx context.Context) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	var l *Limiter
+	called := false
+	if err := l.Do(context.Background(), func(ctx context.Context) error { called = true; return nil }); err != nil || !called {
+		t.Errorf("nil Limiter Do() = %v, called %v", err, called)
+	}
+	if usage := l.Usage(); usage != (Usage{}) {
+		t.Errorf("nil Limiter Usage() = %+v, want zero", usage)
+	}
+}
+
+func TestCounter_Rollover(t *testing.T) {
+	c := &counter{period: Daily}
+	day := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+
+	c.record(day)
+	c.record(day.Add(30 * time.Minute))
+	c.throttled(day)
+	if usage := c.snapshot(day.Add(59 * time.Minute)); usage.Searches != 2 || usage.Throttled != 1 {
+		t.Errorf("snapshot() = %+v, want 2 searches and 1 throttled", usage)
+	}
+
+	next := day.Add(2 * time.Hour)
+	c.record(next)
+	usage := c.snapshot(next)
+	if usage.Searches != 1 || usage.Throttled != 0 || usage.TotalSearches != 3 {
+		t.Errorf("snapshot() = %+v, want 1 search, 0 throttled and 3 total", usage)
+	}
+	if want := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC); !usage.PeriodStart.Equal(want) {
+		t.Errorf("PeriodStart = %v, want %v", usage.PeriodStart, want)
+	}
+}
+
+func TestPeriod_Start(t *testing.T) {
+	tm := time.Date(2024, 3, 15, 13, 45, 0, 0, time.UTC)
+	tests := []struct {
+		period Period
+		want   time.Time
+	}{
+		{Monthly, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Daily, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{Hourly, time.Date(2024, 3, 15, 13, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := tt.period.start(tm); !got.Equal(tt.want) {
+			t.Errorf("%v.start() = %v, want %v", tt.period, got, tt.want)
+		}
+	}
+}
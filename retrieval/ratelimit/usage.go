@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Period is the length of time a provider's quota is metered over. Periods follow the UTC calendar.
+type Period int
+
+const (
+	Monthly Period = iota
+	Daily
+	Hourly
+)
+
+func (p Period) String() string {
+	switch p {
+	case Monthly:
+		return "monthly"
+	case Daily:
+		return "daily"
+	case Hourly:
+		return "hourly"
+	default:
+		return fmt.Sprintf("Period(%d)", int(p))
+	}
+}
+
+// start returns the beginning of the period containing t
+func (p Period) start(t time.Time) time.Time {
+	t = t.UTC()
+	switch p {
+	case Hourly:
+		return t.Truncate(time.Hour)
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Usage is a snapshot of how much of a provider's quota has been consumed
+type Usage struct {
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"periodStart"`
+	// Searches is the number of successful requests in the current period
+	Searches int64 `json:"searches"`
+	// Throttled is the number of requests rejected with a 429 status for going too fast in the current period
+	Throttled int64 `json:"throttled"`
+	// ServerErrors is the number of requests that failed with a 5xx status in the current period
+	ServerErrors int64 `json:"serverErrors"`
+	// TotalSearches is the number of successful requests since the limiter was created
+	TotalSearches int64 `json:"totalSearches"`
+}
+
+// counter tracks usage, starting a new count whenever a new period begins
+type counter struct {
+	mu          sync.Mutex
+	period      Period
+	periodStart time.Time
+	searches    int64
+	throttles   int64
+	errors      int64
+	total       int64
+}
+
+func (c *counter) roll(now time.Time) {
+	if start := c.period.start(now); start.After(c.periodStart) {
+		c.periodStart = start
+		c.searches = 0
+		c.throttles = 0
+		c.errors = 0
+	}
+}
+
+func (c *counter) record(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll(now)
+	c.searches++
+	c.total++
+}
+
+func (c *counter) throttled(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll(now)
+	c.throttles++
+}
+
+func (c *counter) serverError(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll(now)
+	c.errors++
+}
+
+func (c *counter) snapshot(now time.Time) Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll(now)
+	return Usage{
+		Period:        c.period.String(),
+		PeriodStart:   c.periodStart,
+		Searches:      c.searches,
+		Throttled:     c.throttles,
+		ServerErrors:  c.errors,
+		TotalSearches: c.total,
+	}
+}
@@ -0,0 +1,43 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/doctext"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/vecmath"
+)
+
+// maxEmbeddingDocumentChars caps how much of each document is embedded
+const maxEmbeddingDocumentChars = 8000
+
+// EmbeddingReranker implements the Reranker interface by scoring each document with the cosine similarity between
+// its embedding and the query's. It is cheaper than a cross-encoder or LLM, but still puts results from different
+// backends on a single scale.
+type EmbeddingReranker struct {
+	embedder retrieval.Embedder
+}
+
+func (er EmbeddingReranker) Rerank(ctx context.Context, query string, docs []document.Document) ([]float64, error) {
+	texts := make([]string, len(docs)+1)
+	texts[0] = query
+	for i, d := range docs {
+		texts[i+1] = doctext.Text(d, maxEmbeddingDocumentChars)
+	}
+
+	vectors, err := retrieval.Embed(ctx, er.embedder, texts)
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query and documents: %v", err)
+	}
+
+	scores := make([]float64, len(docs))
+	for i := range docs {
+		scores[i] = vecmath.Cosine(vectors[0], vectors[i+1])
+	}
+	return scores, nil
+}
+
+func NewEmbeddingReranker(embedder retrieval.Embedder) EmbeddingReranker {
+	return EmbeddingReranker{embedder}
+}
@@ -0,0 +1,152 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/doctext"
+	"io"
+	"net/http"
+)
+
+// APIStyle is the request and response shape a rerank endpoint speaks
+type APIStyle int
+
+const (
+	// CohereStyle is Cohere's /v1/rerank API, which Jina's /v1/rerank also implements
+	CohereStyle APIStyle = iota
+	// TEIStyle is the /rerank API of Hugging Face's text-embeddings-inference server
+	TEIStyle
+)
+
+// maxHTTPDocumentChars caps how much of each document is sent to the rerank endpoint
+const maxHTTPDocumentChars = 4000
+
+type cohereRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n"`
+}
+
+type cohereResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+type teiRequest struct {
+	Query    string   `json:"query"`
+	Texts    []string `json:"texts"`
+	Truncate bool     `json:"truncate"`
+}
+
+type teiResponse []struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// HTTPReranker implements the Reranker interface with a hosted or self-hosted cross-encoder rerank endpoint
+type HTTPReranker struct {
+	endpoint string
+	apiKey   string
+	model    string
+	style    APIStyle
+	client   *http.Client
+}
+
+func (hr HTTPReranker) Rerank(ctx context.Context, query string, docs []document.Document) ([]float64, error) {
+	texts := make([]string, len(docs))
+	for i, d := range docs {
+		texts[i] = doctext.Text(d, maxHTTPDocumentChars)
+	}
+
+	var request any
+	if hr.style == TEIStyle {
+		request = teiRequest{Query: query, Texts: texts, Truncate: true}
+	} else {
+		request = cohereRequest{Model: hr.model, Query: query, Documents: texts, TopN: len(texts)}
+	}
+
+	body, err := hr.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(docs))
+	assign := func(index int, score float64) error {
+		if index < 0 || index >= len(scores) {
+			return fmt.Errorf("rerank endpoint returned out of range index %d", index)
+		}
+		scores[index] = score
+		return nil
+	}
+
+	if hr.style == TEIStyle {
+		var result teiResponse
+		if err = json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("error parsing rerank response: %v", err)
+		}
+		for _, r := range result {
+			if err = assign(r.Index, r.Score); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		var result cohereResponse
+		if err = json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("error parsing rerank response: %v", err)
+		}
+		for _, r := range result.Results {
+			if err = assign(r.Index, r.RelevanceScore); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return scores, nil
+}
+
+func (hr HTTPReranker) post(ctx context.Context, request any) ([]byte, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request body: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hr.endpoint, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("error constructing rerank request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if hr.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+hr.apiKey)
+	}
+
+	resp, err := hr.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while executing rerank request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rerank response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response status code is not OK; recieved code: %v, body: %s", resp.StatusCode, body)
+	}
+
+	return body, nil
+}
+
+// NewHTTPReranker creates a reranker for the full endpoint URL, e.g. "https://api.cohere.com/v1/rerank" or
+// "http://localhost:8080/rerank" for a local text-embeddings-inference server. apiKey is sent as a bearer token when
+// set, and model is ignored by TEIStyle endpoints, which serve a single model.
+func NewHTTPReranker(endpoint, apiKey, model string, style APIStyle, client *http.Client) HTTPReranker {
+	return HTTPReranker{endpoint, apiKey, model, style, client}
+}
@@ -0,0 +1,110 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/modelproviders"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/doctext"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	listwisePromptTemplate = `You are an expert search relevance judge. Rank the following documents by how useful they are for answering the user's query, most useful first.
+
+<documents>
+%s
+</documents>
+
+<user_query>
+%s
+</user_query>
+
+Respond with only the document numbers in ranked order, separated by commas, e.g. "2,0,1". Include every document number exactly once.`
+
+	documentNumberPattern = regexp.MustCompile(`\d+`)
+)
+
+// maxLLMDocumentChars caps how much of each document is put in the ranking prompt
+const maxLLMDocumentChars = 1500
+
+// LLMReranker implements the Reranker interface by asking a language model to order all documents at once (listwise
+// reranking).
+type LLMReranker struct {
+	modelProvider *modelproviders.Facade
+	provider      modelproviders.ModelProvider
+	model         string
+}
+
+func (lr LLMReranker) Rerank(ctx context.Context, query string, docs []document.Document) ([]float64, error) {
+	formatted := make([]string, len(docs))
+	for i, d := range docs {
+		formatted[i] = fmt.Sprintf("Document [%d] <document>%s</document>", i, doctext.Text(d, maxLLMDocumentChars))
+	}
+	prompt := fmt.Sprintf(listwisePromptTemplate, strings.Join(formatted, "\n\n"), query)
+
+	response, err := lr.generate(ctx, modelproviders.GenerateRequest{
+		Provider: lr.provider,
+		Model:    lr.model,
+		Prompt:   prompt,
+		// Each number is a token or two plus a comma
+		MaxTokens: 4*len(docs) + 16,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error generating ranking: %v", err)
+	}
+
+	return rankingToScores(parseRanking(response, len(docs))), nil
+}
+
+func (lr LLMReranker) generate(ctx context.Context, req modelproviders.GenerateRequest) (string, error) {
+	chunks := make(chan string)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- lr.modelProvider.Generate(ctx, req, chunks)
+		close(chunks)
+	}()
+
+	var sb strings.Builder
+	for chunk := range chunks {
+		sb.WriteString(chunk)
+	}
+	return sb.String(), <-errChan
+}
+
+// parseRanking reads the document order out of a model response. Numbers that are out of range or repeated are
+// ignored, and any documents the model left out are appended in their original order.
+func parseRanking(response string, n int) []int {
+	seen := make([]bool, n)
+	ranking := make([]int, 0, n)
+	for _, match := range documentNumberPattern.FindAllString(response, -1) {
+		i, err := strconv.Atoi(match)
+		if err != nil || i >= n || seen[i] {
+			continue
+		}
+		seen[i] = true
+		ranking = append(ranking, i)
+	}
+
+	for i := range seen {
+		if !seen[i] {
+			ranking = append(ranking, i)
+		}
+	}
+	return ranking
+}
+
+// rankingToScores converts an ordering of document indexes into per-document scores
+func rankingToScores(ranking []int) []float64 {
+	scores := make([]float64, len(ranking))
+	for position, i := range ranking {
+		scores[i] = float64(len(ranking) - position)
+	}
+	return scores
+}
+
+func NewLLMReranker(modelProvider *modelproviders.Facade, provider modelproviders.ModelProvider, model string) LLMReranker {
+	return LLMReranker{modelProvider, provider, model}
+}
@@ -0,0 +1,186 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/coopslarhette/raglib/lib/document"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func docs(titles ...string) []document.Document {
+	out := make([]document.Document, len(titles))
+	for i, t := range titles {
+		out[i] = document.Document{Title: t, Passages: []document.Passage{{Text: "about " + t}}}
+	}
+	return out
+}
+
+func titles(docs []document.Document) []string {
+	out := make([]string, len(docs))
+	for i, d := range docs {
+		out[i] = d.Title
+	}
+	return out
+}
+
+type staticRetriever []document.Document
+
+func (sr staticRetriever) Query(_ context.Context, _ string, topK int) ([]document.Document, error) {
+	if topK < len(sr) {
+		return sr[:topK], nil
+	}
+	return sr, nil
+}
+
+// lengthReranker prefers documents with longer titles
+type lengthReranker struct{}
+
+func (lengthReranker) Rerank(_ context.Context, _ string, docs []document.Document) ([]float64, error) {
+	scores := make([]float64, len(docs))
+	for i, d := range docs {
+		scores[i] = float64(len(d.Title))
+	}
+	return scores, nil
+}
+
+func TestRetriever_Query(t *testing.T) {
+	r := NewRetriever(staticRetriever(docs("a", "bbb", "cc", "dddd")), lengthReranker{}, 3)
+
+	got, err := r.Query(context.Background(), "q", 2)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	// Over-fetching brings "dddd" into the candidate set even though only 2 results are requested
+	if want := []string{"dddd", "bbb"}; !reflect.DeepEqual(titles(got), want) {
+		t.Errorf("Query() = %v, want %v", titles(got), want)
+	}
+}
+
+func TestHTTPReranker(t *testing.T) {
+	tests := []struct {
+		name    string
+		style   APIStyle
+		respond func(query string, texts []string) any
+	}{
+		{
+			name:  "cohere",
+			style: CohereStyle,
+			respond: func(_ string, texts []string) any {
+				type result struct {
+					Index          int     `json:"index"`
+					RelevanceScore float64 `json:"relevance_score"`
+				}
+				results := make([]result, len(texts))
+				for i, text := range texts {
+					results[i] = result{i, float64(len(text))}
+				}
+				return map[string]any{"results": results}
+			},
+		},
+		{
+			name:  "text-embeddings-inference",
+			style: TEIStyle,
+			respond: func(_ string, texts []string) any {
+				type result struct {
+					Index int     `json:"index"`
+					Score float64 `json:"score"`
+				}
+				results := make([]result, len(texts))
+				for i, text := range texts {
+					results[len(texts)-1-i] = result{i, float64(len(text))}
+				}
+				return results
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer key" {
+					t.Errorf("Authorization header = %q", got)
+				}
+
+				var body struct {
+					Query     string   `json:"query"`
+					Documents []string `json:"documents"`
+					Texts     []string `json:"texts"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Fatalf("error decoding request: %v", err)
+				}
+				texts := body.Documents
+				if tt.style == TEIStyle {
+					texts = body.Texts
+				}
+				json.NewEncoder(w).Encode(tt.respond(body.Query, texts))
+			}))
+			defer server.Close()
+
+			reranker := NewHTTPReranker(server.URL, "key", "rerank-model", tt.style, server.Client())
+			scores, err := reranker.Rerank(context.Background(), "q", docs("a", "bbb", "cc"))
+			if err != nil {
+				t.Fatalf("Rerank() error = %v", err)
+			}
+			if scores[1] <= scores[2] || scores[2] <= scores[0] {
+				t.Errorf("Rerank() = %v, want scores ordered by text length", scores)
+			}
+		})
+	}
+}
+
+func TestHTTPReranker_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"invalid api token"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	reranker := NewHTTPReranker(server.URL, "", "", CohereStyle, server.Client())
+	_, err := reranker.Rerank(context.Background(), "q", docs("a"))
+	if err == nil || !strings.Contains(err.Error(), "invalid api token") {
+		t.Errorf("Rerank() error = %v, want error containing response body", err)
+	}
+}
+
+type axisEmbedder struct{}
+
+// Embed maps text to a 2D vector of its counts of "x" and "y"
+func (axisEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(strings.Count(text, "x")), float32(strings.Count(text, "y"))}
+	}
+	return vectors, nil
+}
+
+func TestEmbeddingReranker(t *testing.T) {
+	scores, err := NewEmbeddingReranker(axisEmbedder{}).Rerank(context.Background(), "xx", docs("yyy", "xxx", "xy"))
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if scores[1] <= scores[2] || scores[2] <= scores[0] {
+		t.Errorf("Rerank() = %v, want x heavy documents first", scores)
+	}
+}
+
+func TestParseRanking(t *testing.T) {
+	tests := []struct {
+		response string
+		want     []int
+	}{
+		{response: "2,0,1", want: []int{2, 0, 1}},
+		{response: "Ranking: [1] > [2]", want: []int{1, 2, 0}},
+		{response: "1, 1, 7, 0", want: []int{1, 0, 2}},
+		{response: "", want: []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		if got := parseRanking(tt.response, 3); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRanking(%q) = %v, want %v", tt.response, got, tt.want)
+		}
+	}
+}
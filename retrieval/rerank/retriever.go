@@ -0,0 +1,69 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"sort"
+)
+
+// Reranker scores documents by their relevance to query. Implementations must return one score per document, in the
+// same order as docs, where higher scores mean more relevant.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, docs []document.Document) ([]float64, error)
+}
+
+// Retriever implements the retrieval.Retriever interface by over-fetching candidates from another retriever and
+// reordering them with a Reranker. This makes results from backends with incomparable rankings, e.g. Google's
+// ordering from SERP and Exa's scores, consistent before they reach the Answerer.
+type Retriever struct {
+	retriever retrieval.Retriever
+	reranker  Reranker
+	overFetch int
+}
+
+func (rr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	docs, err := rr.retriever.Query(ctx, query, topK*rr.overFetch)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving candidates: %v", err)
+	}
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	scores, err := rr.reranker.Rerank(ctx, query, docs)
+	if err != nil {
+		return nil, fmt.Errorf("error reranking documents: %v", err)
+	}
+	if len(scores) != len(docs) {
+		return nil, fmt.Errorf("reranker returned %d scores for %d documents", len(scores), len(docs))
+	}
+
+	order := make([]int, len(docs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	if len(order) > topK {
+		order = order[:topK]
+	}
+	reranked := make([]document.Document, len(order))
+	for i, o := range order {
+		reranked[i] = docs[o]
+	}
+	return reranked, nil
+}
+
+// NewRetriever wraps retriever so that each query fetches overFetch times as many candidates as requested, which are
+// then reranked and cut down to topK. An overFetch below 1 is treated as 1.
+func NewRetriever(retriever retrieval.Retriever, reranker Reranker, overFetch int) Retriever {
+	return Retriever{retriever, reranker, max(overFetch, 1)}
+}
@@ -5,6 +5,9 @@ import (
 	"github.com/coopslarhette/raglib/lib/document"
 )
 
+// DefaultUserAgent identifies raglib to the sites and APIs it fetches from when no other user agent is configured
+const DefaultUserAgent = "raglib (+https://github.com/coopslarhette/raglib)"
+
 type Retriever interface {
 	Query(ctx context.Context, query string, topK int) ([]document.Document, error)
 }
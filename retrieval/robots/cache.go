@@ -0,0 +1,146 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxRobotsBytes is how much of a robots.txt file is read, RFC 9309 requires parsing at least the first 500 KiB
+const maxRobotsBytes = 512 * 1024
+
+// Cache fetches and caches robots.txt files per origin, so a batch of requests to the same site only fetches it once.
+// It is safe for concurrent use.
+type Cache struct {
+	client    *http.Client
+	userAgent string
+	ttl       time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	ready     chan struct{}
+	robots    *Robots
+	fetchedAt time.Time
+	// discarded is set when the fetching caller's ctx ended first, so waiters fetch it again with their own ctx
+	discarded bool
+}
+
+// Get returns the robots.txt policy for the origin of rawURL
+func (c *Cache) Get(ctx context.Context, rawURL string) (*Robots, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	for {
+		c.mu.Lock()
+		entry, ok := c.entries[origin]
+		if ok && c.ttl > 0 {
+			select {
+			case <-entry.ready:
+				ok = time.Since(entry.fetchedAt) <= c.ttl
+			default:
+				// Another caller is still fetching it
+			}
+		}
+		if !ok {
+			entry = &cacheEntry{ready: make(chan struct{})}
+			c.entries[origin] = entry
+			c.mu.Unlock()
+
+			entry.robots = c.fetch(ctx, origin)
+			entry.fetchedAt = time.Now()
+			if err = ctx.Err(); err != nil {
+				// The fetch was cut short by the caller rather than the site, so don't remember its outcome
+				c.mu.Lock()
+				if c.entries[origin] == entry {
+					delete(c.entries, origin)
+				}
+				c.mu.Unlock()
+				entry.discarded = true
+			}
+			close(entry.ready)
+			if err != nil {
+				return nil, err
+			}
+			return entry.robots, nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-entry.ready:
+			if !entry.discarded {
+				return entry.robots, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Allowed reports whether the cache's user agent may fetch rawURL
+func (c *Cache) Allowed(ctx context.Context, rawURL string) (bool, error) {
+	r, err := c.Get(ctx, rawURL)
+	if err != nil {
+		return false, err
+	}
+
+	u, _ := url.Parse(rawURL)
+	return r.Allowed(c.userAgent, u.RequestURI()), nil
+}
+
+// CrawlDelay returns the delay the origin of rawURL asks the cache's user agent to wait between requests
+func (c *Cache) CrawlDelay(ctx context.Context, rawURL string) (time.Duration, error) {
+	r, err := c.Get(ctx, rawURL)
+	if err != nil {
+		return 0, err
+	}
+	return r.CrawlDelay(c.userAgent), nil
+}
+
+// fetch downloads and parses the robots.txt at origin. Per RFC 9309 a missing file allows everything, while a server
+// error or unreachable host disallows everything until it's fetched again.
+func (c *Cache) fetch(ctx context.Context, origin string) *Robots {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return DisallowAll
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return DisallowAll
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return DisallowAll
+	case resp.StatusCode != http.StatusOK:
+		return AllowAll
+	}
+
+	return Parse(io.LimitReader(resp.Body, maxRobotsBytes))
+}
+
+// NewCache creates a Cache that checks rules for userAgent and refetches files older than ttl, a ttl of 0 keeps them
+// forever
+func NewCache(client *http.Client, userAgent string, ttl time.Duration) *Cache {
+	return &Cache{
+		client:    client,
+		userAgent: userAgent,
+		ttl:       ttl,
+		entries:   make(map[string]*cacheEntry),
+	}
+}
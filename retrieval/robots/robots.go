@@ -0,0 +1,174 @@
+package robots
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Robots is a parsed robots.txt file, following RFC 9309 (https://www.rfc-editor.org/rfc/rfc9309) plus the widely
+// supported Crawl-delay and Sitemap extensions
+type Robots struct {
+	groups []group
+	// Sitemaps lists the sitemap URLs the file advertises
+	Sitemaps []string
+}
+
+type group struct {
+	userAgents []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// AllowAll is the policy for sites without a robots.txt
+var AllowAll = &Robots{}
+
+// DisallowAll is the policy for sites whose robots.txt can't be fetched because of a server error
+var DisallowAll = &Robots{groups: []group{{userAgents: []string{"*"}, rules: []rule{{allow: false, pattern: "/"}}}}}
+
+// Parse reads a robots.txt file. Malformed lines are skipped, as crawlers are expected to be lenient.
+func Parse(r io.Reader) *Robots {
+	robots := &Robots{}
+	var current *group
+	// Consecutive User-agent lines share the rules that follow them
+	inUserAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inUserAgents {
+				robots.groups = append(robots.groups, group{})
+				current = &robots.groups[len(robots.groups)-1]
+			}
+			current.userAgents = append(current.userAgents, strings.ToLower(value))
+			inUserAgents = true
+		case "allow", "disallow":
+			inUserAgents = false
+			// An empty Disallow allows everything, which is the same as having no rule
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, rule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			inUserAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			if value != "" {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+		}
+	}
+
+	return robots
+}
+
+// Allowed reports whether userAgent may fetch path, which should include any query string
+func (r *Robots) Allowed(userAgent, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+
+	// The most specific matching rule wins, and allow wins ties
+	allowed, longest := true, -1
+	for _, g := range r.groupsFor(userAgent) {
+		for _, rl := range g.rules {
+			if !matches(rl.pattern, path) {
+				continue
+			}
+			if len(rl.pattern) > longest || len(rl.pattern) == longest && rl.allow {
+				allowed, longest = rl.allow, len(rl.pattern)
+			}
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns how long userAgent should wait between requests to the site, zero if the file doesn't say
+func (r *Robots) CrawlDelay(userAgent string) time.Duration {
+	var delay time.Duration
+	for _, g := range r.groupsFor(userAgent) {
+		delay = max(delay, g.crawlDelay)
+	}
+	return delay
+}
+
+// groupsFor returns the groups that apply to userAgent. Groups naming the agent take precedence over the * group,
+// and all groups naming it are combined.
+func (r *Robots) groupsFor(userAgent string) []group {
+	product := strings.ToLower(userAgent)
+	if i := strings.IndexAny(product, "/ "); i >= 0 {
+		product = product[:i]
+	}
+
+	var named, wildcard []group
+	for _, g := range r.groups {
+		for _, ua := range g.userAgents {
+			if ua == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+			if ua == product {
+				named = append(named, g)
+				break
+			}
+		}
+	}
+
+	if len(named) > 0 {
+		return named
+	}
+	return wildcard
+}
+
+// matches reports whether path matches pattern, where * matches any run of characters and a trailing $ anchors the
+// pattern to the end of the path
+func matches(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		// The last part of an anchored pattern has to match the very end of the path
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		j := strings.Index(rest, part)
+		if j < 0 {
+			return false
+		}
+		rest = rest[j+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
@@ -0,0 +1,115 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const robotsTxt = `# example
+User-agent: *
+Disallow: /private/
+Allow: /private/public-page
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: raglib
+User-agent: otherbot
+Disallow: /no-raglib
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestRobots_Allowed(t *testing.T) {
+	r := Parse(strings.NewReader(robotsTxt))
+
+	tests := []struct {
+		name      string
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{name: "no matching rule", userAgent: "somebot", path: "/blog/post", want: true},
+		{name: "disallowed prefix", userAgent: "somebot", path: "/private/notes", want: false},
+		{name: "more specific allow wins", userAgent: "somebot", path: "/private/public-page", want: true},
+		{name: "wildcard with end anchor", userAgent: "somebot", path: "/files/report.pdf", want: false},
+		{name: "end anchor requires end", userAgent: "somebot", path: "/files/report.pdf?download=1", want: true},
+		{name: "named group replaces wildcard group", userAgent: "raglib/1.0", path: "/private/notes", want: true},
+		{name: "named group rule", userAgent: "raglib (+https://example.com)", path: "/no-raglib/page", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Allowed(tt.userAgent, tt.path); got != tt.want {
+				t.Errorf("Allowed(%q, %q) = %v, want %v", tt.userAgent, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobots_CrawlDelayAndSitemaps(t *testing.T) {
+	r := Parse(strings.NewReader(robotsTxt))
+
+	if got := r.CrawlDelay("somebot"); got != 2*time.Second {
+		t.Errorf("CrawlDelay(somebot) = %v, want 2s", got)
+	}
+	if got := r.CrawlDelay("raglib"); got != 500*time.Millisecond {
+		t.Errorf("CrawlDelay(raglib) = %v, want 500ms", got)
+	}
+	if len(r.Sitemaps) != 1 || r.Sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Errorf("Sitemaps = %v", r.Sitemaps)
+	}
+}
+
+func TestCache_GetAfterFetcherCancelled(t *testing.T) {
+	started := make(chan struct{})
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// Hold the first fetch open until its caller gives up
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte(robotsTxt))
+	}))
+	defer server.Close()
+
+	cache := NewCache(server.Client(), "raglib", 0)
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cache.Get(first, server.URL+"/page")
+		firstErr <- err
+	}()
+	<-started
+
+	type result struct {
+		allowed bool
+		err     error
+	}
+	second := make(chan result, 1)
+	go func() {
+		allowed, err := cache.Allowed(context.Background(), server.URL+"/page")
+		second <- result{allowed, err}
+	}()
+	// Give the second caller time to start waiting on the first's fetch
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-firstErr; err == nil {
+		t.Error("Get() with cancelled ctx error = nil, want context.Canceled")
+	}
+	got := <-second
+	if got.err != nil || !got.allowed {
+		t.Errorf("Allowed() for waiting caller = %v, %v, want it to fetch robots.txt again and allow /page", got.allowed, got.err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("robots.txt fetched %d times, want 2", n)
+	}
+}
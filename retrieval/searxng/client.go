@@ -0,0 +1,162 @@
+package searxng
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"github.com/coopslarhette/raglib/lib/retrieval/ratelimit"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Time ranges for SearchRequest.TimeRange
+const (
+	PastDay   = "day"
+	PastMonth = "month"
+	PastYear  = "year"
+)
+
+// Client is the HTTP client for the JSON API of a SearXNG instance. The instance must list "json" under search.formats
+// in its settings.yml.
+type Client struct {
+	baseURL string
+	client  *http.Client
+	limiter *ratelimit.Limiter
+}
+
+// SearchRequest is a search through a SearXNG instance. Empty fields fall back to the instance's defaults.
+type SearchRequest struct {
+	Query string
+	// Categories are the tabs to search, e.g. "general", "news" or "science"
+	Categories []string
+	// Engines restricts the search to these engines, e.g. "duckduckgo" or "wikipedia"
+	Engines []string
+	// Language is the language code results are written in, e.g. "en" or "de-CH"
+	Language string
+	// TimeRange restricts results by date, e.g. PastMonth. Only engines supporting it are queried.
+	TimeRange string
+	// SafeSearch is 0 for off, 1 for moderate and 2 for strict. nil uses the instance's default.
+	SafeSearch *int
+	// Page is the one-based page of results to return
+	Page int
+}
+
+func (r SearchRequest) values() url.Values {
+	params := url.Values{}
+	params.Set("q", r.Query)
+	params.Set("format", "json")
+	if len(r.Categories) > 0 {
+		params.Set("categories", strings.Join(r.Categories, ","))
+	}
+	if len(r.Engines) > 0 {
+		params.Set("engines", strings.Join(r.Engines, ","))
+	}
+	if r.Language != "" {
+		params.Set("language", r.Language)
+	}
+	if r.TimeRange != "" {
+		params.Set("time_range", r.TimeRange)
+	}
+	if r.SafeSearch != nil {
+		params.Set("safesearch", strconv.Itoa(*r.SafeSearch))
+	}
+	if r.Page > 1 {
+		params.Set("pageno", strconv.Itoa(r.Page))
+	}
+	return params
+}
+
+// Result is a single result, merged by SearXNG from every engine that returned its URL
+type Result struct {
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	Content       string   `json:"content"`
+	Engine        string   `json:"engine"`
+	Engines       []string `json:"engines"`
+	Score         float64  `json:"score"`
+	Category      string   `json:"category"`
+	PublishedDate string   `json:"publishedDate"`
+	Author        string   `json:"author"`
+	Thumbnail     string   `json:"thumbnail"`
+	ImgSrc        string   `json:"img_src"`
+}
+
+// SearchResponse represents the JSON response of a SearXNG search. Like serp.SearchResult, it only holds the fields
+// that are interesting to us currently.
+type SearchResponse struct {
+	Query           string   `json:"query"`
+	NumberOfResults int      `json:"number_of_results"`
+	Results         []Result `json:"results"`
+	// UnresponsiveEngines lists the engines that failed, each as a pair of the engine's name and the reason
+	UnresponsiveEngines [][]string `json:"unresponsive_engines"`
+}
+
+// NewClient creates a Client for the SearXNG instance at baseURL, e.g. "http://searxng.internal:8080"
+func NewClient(baseURL string, client *http.Client) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// SetLimiter throttles and retries the client's searches with limiter, e.g. to stay under the instance's own
+// limiter plugin
+func (c *Client) SetLimiter(limiter *ratelimit.Limiter) {
+	c.limiter = limiter
+}
+
+// Search returns one page of results for request
+func (c *Client) Search(ctx context.Context, request SearchRequest) (*SearchResponse, error) {
+	apiURL := c.baseURL + "/search?" + request.values().Encode()
+
+	var result *SearchResponse
+	err := c.limiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.get(ctx, apiURL)
+		return err
+	})
+	return result, err
+}
+
+func (c *Client) get(ctx context.Context, apiURL string) (*SearchResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing request for SearXNG: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while executing request to SearXNG: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SearXNG response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// SearXNG responds 403 when the json format isn't enabled in settings.yml
+		return nil, apierror.FromResponse("searxng", resp, body)
+	}
+
+	var result SearchResponse
+	if err = json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error parsing SearXNG response: %v", err)
+	}
+
+	if len(result.Results) == 0 && len(result.UnresponsiveEngines) > 0 {
+		failures := make([]string, len(result.UnresponsiveEngines))
+		for i, engine := range result.UnresponsiveEngines {
+			failures[i] = strings.Join(engine, ": ")
+		}
+		return nil, fmt.Errorf("no SearXNG engines responded: %s", strings.Join(failures, ", "))
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,144 @@
+package searxng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL+"/", server.Client())
+}
+
+func TestRetriever_Query(t *testing.T) {
+	var params url.Values
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			t.Errorf("request path = %s, want /search", r.URL.Path)
+		}
+		params = r.URL.Query()
+		w.Write([]byte(`{
+			"query": "golang",
+			"results": [
+				{
+					"url": "https://go.dev/",
+					"title": "The Go Programming Language",
+					"content": "Build simple, secure, scalable systems with Go.",
+					"engine": "duckduckgo",
+					"engines": ["duckduckgo", "brave"],
+					"score": 4.5,
+					"thumbnail": "https://go.dev/images/go-logo-blue.svg"
+				},
+				{
+					"url": "https://en.wikipedia.org/wiki/Go_(programming_language)",
+					"title": "Go (programming language)",
+					"content": "Go is a statically typed, compiled language.",
+					"publishedDate": "2024-02-01T00:00:00",
+					"score": 2
+				}
+			],
+			"unresponsive_engines": [["google", "CAPTCHA"]]
+		}`))
+	})
+
+	safe := 2
+	retriever := NewRetrieverWithRequest(c, SearchRequest{
+		Categories: []string{"general", "it"},
+		Engines:    []string{"duckduckgo", "brave"},
+		Language:   "en",
+		TimeRange:  PastYear,
+		SafeSearch: &safe,
+	})
+	docs, err := retriever.Query(context.Background(), "golang", 2)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	want := url.Values{
+		"q":          {"golang"},
+		"format":     {"json"},
+		"categories": {"general,it"},
+		"engines":    {"duckduckgo,brave"},
+		"language":   {"en"},
+		"time_range": {"year"},
+		"safesearch": {"2"},
+	}
+	if params.Encode() != want.Encode() {
+		t.Errorf("request parameters = %s, want %s", params.Encode(), want.Encode())
+	}
+
+	if len(docs) != 2 {
+		t.Fatalf("Query() returned %d documents, want 2", len(docs))
+	}
+	ref := docs[0].WebReference
+	if ref.Link != "https://go.dev/" || ref.DisplayedLink != "go.dev" || ref.Thumbnail != "https://go.dev/images/go-logo-blue.svg" || ref.APISource != "searxng" {
+		t.Errorf("WebReference = %+v", ref)
+	}
+	if p := docs[0].Passages[0]; p.Text != "Build simple, secure, scalable systems with Go." || p.Score != 4.5 {
+		t.Errorf("passage = %+v", p)
+	}
+	if date := docs[1].WebReference.Date; date != "2024-02-01T00:00:00" {
+		t.Errorf("Date = %q, want the published date", date)
+	}
+}
+
+func TestRetriever_Paging(t *testing.T) {
+	var pages []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("pageno")
+		pages = append(pages, page)
+		n, _ := strconv.Atoi(page)
+		n = max(n, 1)
+		// The third page repeats the second, like an engine that doesn't support paging
+		n = min(n, 2)
+
+		var results []string
+		for i := (n - 1) * 3; i < n*3; i++ {
+			results = append(results, fmt.Sprintf(`{"url": "https://example.com/%d", "title": "result %d"}`, i, i))
+		}
+		fmt.Fprintf(w, `{"results": [%s]}`, strings.Join(results, ","))
+	})
+
+	docs, err := NewRetriever(c).Query(context.Background(), "q", 10)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 6 {
+		t.Errorf("Query() returned %d documents, want 6", len(docs))
+	}
+	if strings.Join(pages, ",") != ",2,3" {
+		t.Errorf("requested pages %q, want the first page then 2 and 3", pages)
+	}
+
+	if _, err = NewRetriever(c).Query(context.Background(), "q", -1); err == nil || len(pages) != 3 {
+		t.Errorf("Query() with negative topK error = %v, want an error without sending a request", err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	})
+	_, err := NewRetriever(c).Query(context.Background(), "q", 10)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || apiErr.Provider != "searxng" {
+		t.Errorf("Query() error = %v, want a 403 *apierror.Error", err)
+	}
+
+	c = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results": [], "unresponsive_engines": [["google", "timeout"], ["bing", "CAPTCHA"]]}`))
+	})
+	_, err = NewRetriever(c).Query(context.Background(), "q", 10)
+	if err == nil || !strings.Contains(err.Error(), "google: timeout, bing: CAPTCHA") {
+		t.Errorf("Query() error = %v, want the unresponsive engines", err)
+	}
+}
@@ -0,0 +1,98 @@
+package searxng
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/urls"
+)
+
+// maxPages bounds how many pages a Query fetches to fill topK, since each page fans out to every engine
+const maxPages = 5
+
+// Retriever implements the retrieval.Retriever interface for a self-hosted SearXNG metasearch instance
+type Retriever struct {
+	client *Client
+	// request is the template for each search, with its Query and Page filled in per page
+	request SearchRequest
+}
+
+func (sr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	var docs []document.Document
+	seen := make(map[string]bool)
+
+	request := sr.request
+	request.Query = query
+	for request.Page = 1; len(docs) < topK && request.Page <= maxPages; request.Page++ {
+		result, err := sr.client.Search(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("error querying SearXNG: %w", err)
+		}
+		if len(result.Results) == 0 {
+			break
+		}
+
+		added := 0
+		for _, r := range result.Results {
+			if seen[r.URL] {
+				continue
+			}
+			seen[r.URL] = true
+			docs = append(docs, toDocument(r))
+			added++
+		}
+		// Engines that don't page return their first page again
+		if added == 0 {
+			break
+		}
+	}
+
+	if len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs, nil
+}
+
+// toDocument maps a result to a web document. SearXNG doesn't return favicons, so only the thumbnail is set, from
+// the result's thumbnail or, for image results, the image itself.
+func toDocument(r Result) document.Document {
+	displayed := r.URL
+	if u, err := urls.Parse(r.URL); err == nil {
+		displayed = u.FullDomain()
+	}
+
+	thumbnail := r.Thumbnail
+	if thumbnail == "" {
+		thumbnail = r.ImgSrc
+	}
+
+	return document.Document{
+		Passages: []document.Passage{{Text: r.Content, Score: r.Score}},
+		Corpus:   document.Web,
+		WebReference: &document.WebReference{
+			Title:         r.Title,
+			Link:          r.URL,
+			DisplayedLink: displayed,
+			Blurb:         r.Content,
+			Date:          r.PublishedDate,
+			Author:        r.Author,
+			Thumbnail:     thumbnail,
+			APISource:     "searxng",
+		},
+		Title: r.Title,
+	}
+}
+
+func NewRetriever(client *Client) Retriever {
+	return Retriever{client: client}
+}
+
+// NewRetrieverWithRequest creates a Retriever whose searches use the categories, engines, language and filters of
+// request. Its Query and Page are ignored.
+func NewRetrieverWithRequest(client *Client, request SearchRequest) Retriever {
+	return Retriever{client: client, request: request}
+}
@@ -4,17 +4,131 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"github.com/coopslarhette/raglib/lib/retrieval/ratelimit"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
+// noResultsError is the error SerpApi responds with when the search engine found nothing for the query
+const noResultsError = "hasn't returned any results for this query"
+
+const defaultBaseURL = "https://serpapi.com"
+
 // Client is the HTTP client for querying SerpApi API endpoints, mainly the
 // Google Search endpoint.
 type Client struct {
-	apiKey string
-	client *http.Client
+	apiKey  string
+	client  *http.Client
+	baseURL string
+	limiter *ratelimit.Limiter
+}
+
+// Engines supported by SearchRequest. SerpApi supports many more, which can be used by setting Engine and passing
+// their parameters in SearchRequest.Extra.
+const (
+	Google        = "google"
+	GoogleNews    = "google_news"
+	GoogleScholar = "google_scholar"
+	Bing          = "bing"
+)
+
+// Time ranges for SearchRequest.TimeRange. Google also accepts custom ranges such as "cdr:1,cd_min:1/1/2024,cd_max:6/30/2024".
+const (
+	PastHour  = "qdr:h"
+	PastDay   = "qdr:d"
+	PastWeek  = "qdr:w"
+	PastMonth = "qdr:m"
+	PastYear  = "qdr:y"
+)
+
+// SearchRequest is a search through SerpApi. Fields are mapped to the parameter names of the chosen engine, and ones
+// the engine doesn't support are left out.
+type SearchRequest struct {
+	Query string
+	// Engine is the search engine to scrape, Google when empty
+	Engine string
+	// Country is the two-letter country code to search from, e.g. "us" or "fr"
+	Country string
+	// Language is the two-letter interface language code, e.g. "en" or "fr"
+	Language string
+	// Location is where the search should originate from, e.g. "Austin, Texas, United States"
+	Location string
+	// TimeRange restricts Google results by date, e.g. PastWeek
+	TimeRange string
+	// SafeSearch filters adult content from results
+	SafeSearch bool
+	// Num is the number of results on the page
+	Num int
+	// Start is the offset of the first result, used for paging
+	Start int
+	// Extra holds any other parameters to send, overriding those derived from the fields above
+	Extra url.Values
+}
+
+// values returns the query parameters for the request in the naming of its engine
+func (r SearchRequest) values() url.Values {
+	params := url.Values{}
+	params.Set("q", r.Query)
+
+	engine := r.Engine
+	if engine == "" {
+		engine = Google
+	}
+	params.Set("engine", engine)
+	if r.Location != "" {
+		params.Set("location", r.Location)
+	}
+
+	switch engine {
+	case Bing:
+		if r.Country != "" {
+			params.Set("cc", r.Country)
+		}
+		if r.Language != "" && r.Country != "" {
+			params.Set("mkt", r.Language+"-"+strings.ToUpper(r.Country))
+		}
+		if r.SafeSearch {
+			params.Set("safeSearch", "Strict")
+		}
+		if r.Num > 0 {
+			params.Set("count", strconv.Itoa(r.Num))
+		}
+		if r.Start > 0 {
+			// Bing's offset is 1-based
+			params.Set("first", strconv.Itoa(r.Start+1))
+		}
+	default:
+		if r.Country != "" {
+			params.Set("gl", r.Country)
+		}
+		if r.Language != "" {
+			params.Set("hl", r.Language)
+		}
+		if r.TimeRange != "" {
+			params.Set("tbs", r.TimeRange)
+		}
+		if r.SafeSearch {
+			params.Set("safe", "active")
+		}
+		// Google News returns all its results on a single page
+		if engine != GoogleNews {
+			if r.Num > 0 {
+				params.Set("num", strconv.Itoa(r.Num))
+			}
+			if r.Start > 0 {
+				params.Set("start", strconv.Itoa(r.Start))
+			}
+		}
+	}
+
+	for k, v := range r.Extra {
+		params[k] = v
+	}
+	return params
 }
 
 type OrganicResult struct {
@@ -32,26 +146,173 @@ type OrganicResult struct {
 	Favicon          string `json:"favicon"`
 }
 
+// NewsSource is the publisher of a NewsResult
+type NewsSource struct {
+	Name    string   `json:"name"`
+	Icon    string   `json:"icon"`
+	Authors []string `json:"authors"`
+}
+
+// NewsResult is a story returned by the Google News engine
+type NewsResult struct {
+	Position  int        `json:"position"`
+	Title     string     `json:"title"`
+	Link      string     `json:"link"`
+	Snippet   string     `json:"snippet"`
+	Source    NewsSource `json:"source"`
+	Date      string     `json:"date"`
+	Thumbnail string     `json:"thumbnail"`
+}
+
+// AnswerBox is Google's featured snippet or direct answer for the query. Which fields are set depends on Type, e.g.
+// "organic_result" has a Snippet or Answer from Link, while "calculator_result" only has a Result.
+type AnswerBox struct {
+	Type                    string   `json:"type"`
+	Title                   string   `json:"title"`
+	Link                    string   `json:"link"`
+	DisplayedLink           string   `json:"displayed_link"`
+	Answer                  string   `json:"answer"`
+	Snippet                 string   `json:"snippet"`
+	SnippetHighlightedWords []string `json:"snippet_highlighted_words"`
+	List                    []string `json:"list"`
+	Result                  string   `json:"result"`
+	Date                    string   `json:"date"`
+	Thumbnail               string   `json:"thumbnail"`
+	Favicon                 string   `json:"favicon"`
+}
+
+// KnowledgeGraphSource is where a KnowledgeGraph's description was taken from, often Wikipedia
+type KnowledgeGraphSource struct {
+	Name string `json:"name"`
+	Link string `json:"link"`
+}
+
+// KnowledgeGraph is the panel Google shows about the entity a query is about
+type KnowledgeGraph struct {
+	Title       string               `json:"title"`
+	Type        string               `json:"type"`
+	Description string               `json:"description"`
+	Source      KnowledgeGraphSource `json:"source"`
+	Website     string               `json:"website"`
+	// Attributes holds the panel's facts, e.g. "born" or "headquarters", which SerpApi returns as top-level fields
+	// named after the fact
+	Attributes map[string]string `json:"-"`
+}
+
+func (kg *KnowledgeGraph) UnmarshalJSON(data []byte) error {
+	type knowledgeGraph KnowledgeGraph
+	if err := json.Unmarshal(data, (*knowledgeGraph)(kg)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for k, raw := range fields {
+		var value string
+		if knowledgeGraphFields[k] || strings.Contains(k, "link") || json.Unmarshal(raw, &value) != nil {
+			continue
+		}
+		if value == "" || strings.HasPrefix(value, "http") {
+			continue
+		}
+		if kg.Attributes == nil {
+			kg.Attributes = make(map[string]string)
+		}
+		kg.Attributes[k] = value
+	}
+	return nil
+}
+
+// knowledgeGraphFields are the knowledge graph fields that aren't facts about the entity
+var knowledgeGraphFields = map[string]bool{
+	"title": true, "type": true, "description": true, "website": true, "kgmid": true, "entity_type": true,
+	"image": true, "thumbnail": true,
+}
+
+// RelatedQuestion is one of Google's "People also ask" questions, answered with a snippet from Link
+type RelatedQuestion struct {
+	Question      string   `json:"question"`
+	Snippet       string   `json:"snippet"`
+	List          []string `json:"list"`
+	Title         string   `json:"title"`
+	Link          string   `json:"link"`
+	DisplayedLink string   `json:"displayed_link"`
+	Date          string   `json:"date"`
+}
+
+// TopStory is a news story from the "Top stories" carousel of a Google search
+type TopStory struct {
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	Source    string `json:"source"`
+	Date      string `json:"date"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+// Pagination links to the neighbouring pages of results. Next is empty on the last page.
+type Pagination struct {
+	Current int    `json:"current"`
+	Next    string `json:"next"`
+}
+
+type SearchMetadata struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
 // SearchResult represents the JSON response from SerpApi's API response. The fields
 // here don't represent all the fields SerpApi returns, just the ones that might be
 // interesting to us currently.
 type SearchResult struct {
+	SearchMetadata SearchMetadata  `json:"search_metadata"`
 	OrganicResults []OrganicResult `json:"organic_results"`
+	NewsResults    []NewsResult    `json:"news_results"`
+	// AnswerBox and KnowledgeGraph are nil when Google didn't show them
+	AnswerBox        *AnswerBox        `json:"answer_box"`
+	KnowledgeGraph   *KnowledgeGraph   `json:"knowledge_graph"`
+	RelatedQuestions []RelatedQuestion `json:"related_questions"`
+	TopStories       []TopStory        `json:"top_stories"`
+	Pagination       Pagination        `json:"serpapi_pagination"`
+	// Error is set when SerpApi couldn't complete the search, sometimes with a 200 status code
+	Error string `json:"error"`
 }
 
 func NewClient(apiKey string, client *http.Client) *Client {
 	return &Client{
-		apiKey: apiKey,
-		client: client,
+		apiKey:  apiKey,
+		client:  client,
+		baseURL: defaultBaseURL,
 	}
 }
 
+// SetLimiter throttles, retries and meters the client's searches with limiter
+func (c *Client) SetLimiter(limiter *ratelimit.Limiter) {
+	c.limiter = limiter
+}
+
+// Query searches Google for query, returning the first page of topK results
 func (c *Client) Query(ctx context.Context, query string, topK int) (*SearchResult, error) {
-	apiURL, err := c.makeURL(query, topK)
+	return c.Search(ctx, SearchRequest{Query: query, Num: topK})
+}
+
+// Search returns one page of results for request
+func (c *Client) Search(ctx context.Context, request SearchRequest) (*SearchResult, error) {
+	apiURL, err := c.makeURL(request)
 	if err != nil {
 		return nil, fmt.Errorf("error constructing URL for SERP API request: %v", err)
 	}
 
+	var result *SearchResult
+	err = c.limiter.Do(ctx, func(ctx context.Context) error {
+		result, err = c.get(ctx, apiURL)
+		return err
+	})
+	return result, err
+}
+
+func (c *Client) get(ctx context.Context, apiURL *url.URL) (*SearchResult, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error constructing request for SERP API: %v", err)
@@ -68,26 +329,40 @@ func (c *Client) Query(ctx context.Context, query string, topK int) (*SearchResu
 		return nil, fmt.Errorf("error reading SERP API response body: %v", err)
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse("serp", resp, body)
+	}
+
 	var result SearchResult
 	if err = json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("error parsing SERP API respone")
 	}
 
+	if result.Error != "" {
+		// SerpApi reports a search with no results as an error, but to us it's just an empty result set
+		if strings.Contains(result.Error, noResultsError) {
+			return &result, nil
+		}
+		return nil, &apierror.Error{
+			Provider:   "serp",
+			StatusCode: resp.StatusCode,
+			Message:    result.Error,
+			RequestID:  result.SearchMetadata.ID,
+		}
+	}
+
 	return &result, nil
 }
 
-func (c *Client) makeURL(query string, topK int) (*url.URL, error) {
-	apiUrl, err := url.Parse("https://serpapi.com/search")
+func (c *Client) makeURL(request SearchRequest) (*url.URL, error) {
+	apiUrl, err := url.Parse(c.baseURL + "/search")
 	if err != nil {
 		return nil, fmt.Errorf("error parsing SERP API url: %v", err)
 	}
 
 	// Set the query parameters
-	params := url.Values{}
-	params.Set("q", query)
+	params := request.values()
 	params.Set("api_key", c.apiKey)
-	params.Set("engine", "google")
-	params.Set("num", strconv.Itoa(topK))
 	apiUrl.RawQuery = params.Encode()
 
 	return apiUrl, nil
@@ -0,0 +1,328 @@
+package serp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newTestClient(t *testing.T, status int, body string) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient("key", server.Client())
+	c.baseURL = server.URL
+	return c
+}
+
+func TestClient_QueryErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantErr       bool
+		wantStatus    int
+		wantMessage   string
+		wantRequestID string
+		wantRetryable bool
+	}{
+		{
+			name:        "invalid api key",
+			status:      http.StatusUnauthorized,
+			body:        `{"error": "Invalid API key. Your API key should be here: https://serpapi.com/manage-api-key"}`,
+			wantErr:     true,
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "Invalid API key. Your API key should be here: https://serpapi.com/manage-api-key",
+		},
+		{
+			name:          "rate limited",
+			status:        http.StatusTooManyRequests,
+			body:          `{"error": "Your account has run out of searches."}`,
+			wantErr:       true,
+			wantStatus:    http.StatusTooManyRequests,
+			wantMessage:   "Your account has run out of searches.",
+			wantRetryable: true,
+		},
+		{
+			name:          "error with ok status",
+			status:        http.StatusOK,
+			body:          `{"search_metadata": {"id": "abc123", "status": "Error"}, "error": "Unsupported engine."}`,
+			wantErr:       true,
+			wantStatus:    http.StatusOK,
+			wantMessage:   "Unsupported engine.",
+			wantRequestID: "abc123",
+		},
+		{
+			name:   "no results is not an error",
+			status: http.StatusOK,
+			body:   `{"search_metadata": {"id": "abc123", "status": "Success"}, "error": "Google hasn't returned any results for this query."}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := NewRetriever(newTestClient(t, tt.status, tt.body)).Query(context.Background(), "q", 10)
+			if !tt.wantErr {
+				if err != nil || len(docs) != 0 {
+					t.Errorf("Query() = %v, %v, want no documents and no error", docs, err)
+				}
+				return
+			}
+
+			var apiErr *apierror.Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Query() error = %v, want *apierror.Error", err)
+			}
+			if apiErr.StatusCode != tt.wantStatus || apiErr.Message != tt.wantMessage || apiErr.RequestID != tt.wantRequestID {
+				t.Errorf("got error %+v", apiErr)
+			}
+			if apiErr.Retryable() != tt.wantRetryable || apierror.IsRetryable(err) != tt.wantRetryable {
+				t.Errorf("Retryable() = %v, want %v", apiErr.Retryable(), tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestSearchRequest_values(t *testing.T) {
+	tests := []struct {
+		name    string
+		request SearchRequest
+		want    string
+	}{
+		{
+			name:    "google default",
+			request: SearchRequest{Query: "golang", Num: 10},
+			want:    "engine=google&num=10&q=golang",
+		},
+		{
+			name: "google localized",
+			request: SearchRequest{
+				Query:      "golang",
+				Country:    "fr",
+				Language:   "fr",
+				Location:   "Paris, France",
+				TimeRange:  PastWeek,
+				SafeSearch: true,
+				Num:        10,
+				Start:      20,
+			},
+			want: "engine=google&gl=fr&hl=fr&location=Paris%2C+France&num=10&q=golang&safe=active&start=20&tbs=qdr%3Aw",
+		},
+		{
+			name:    "bing",
+			request: SearchRequest{Query: "golang", Engine: Bing, Country: "us", Language: "en", SafeSearch: true, Num: 10, Start: 10},
+			want:    "cc=us&count=10&engine=bing&first=11&mkt=en-US&q=golang&safeSearch=Strict",
+		},
+		{
+			name:    "google news has no paging",
+			request: SearchRequest{Query: "golang", Engine: GoogleNews, Language: "en", Num: 10, Start: 10},
+			want:    "engine=google_news&hl=en&q=golang",
+		},
+		{
+			name:    "extra parameters",
+			request: SearchRequest{Query: "golang", Engine: GoogleScholar, Extra: url.Values{"as_ylo": {"2020"}}},
+			want:    "as_ylo=2020&engine=google_scholar&q=golang",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.request.values().Encode(); got != tt.want {
+				t.Errorf("values() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetriever_Paging(t *testing.T) {
+	const total = 25
+	var requests []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		requests = append(requests, params)
+		start, _ := strconv.Atoi(params.Get("start"))
+		num, _ := strconv.Atoi(params.Get("num"))
+
+		first := start
+		// Each page repeats the last result of the page before it, like Google sometimes does
+		if first > 0 {
+			first--
+		}
+		var results []string
+		for i := first; i < min(start+num, total); i++ {
+			results = append(results, fmt.Sprintf(`{"position": %d, "title": "result %d", "link": "https://example.com/%d"}`, i+1, i, i))
+		}
+		next := ""
+		if start+num < total {
+			next = "https://serpapi.com/search?start=next"
+		}
+		fmt.Fprintf(w, `{"organic_results": [%s], "serpapi_pagination": {"next": %q}}`, strings.Join(results, ","), next)
+	}))
+	defer server.Close()
+
+	c := NewClient("key", server.Client())
+	c.baseURL = server.URL
+	retriever := NewRetrieverWithRequest(c, SearchRequest{Country: "de", Language: "de"})
+
+	docs, err := retriever.Query(context.Background(), "golang", 24)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 24 {
+		t.Fatalf("Query() returned %d documents, want 24", len(docs))
+	}
+	for i, doc := range docs {
+		if want := fmt.Sprintf("https://example.com/%d", i); doc.WebReference.Link != want {
+			t.Errorf("docs[%d] link = %s, want %s", i, doc.WebReference.Link, want)
+		}
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("sent %d requests, want 3", len(requests))
+	}
+	for i, want := range []struct{ start, num string }{{"", "10"}, {"10", "10"}, {"20", "4"}} {
+		if got := requests[i].Get("start"); got != want.start {
+			t.Errorf("request %d start = %q, want %q", i, got, want.start)
+		}
+		if got := requests[i].Get("num"); got != want.num {
+			t.Errorf("request %d num = %q, want %q", i, got, want.num)
+		}
+		if requests[i].Get("gl") != "de" || requests[i].Get("q") != "golang" {
+			t.Errorf("request %d = %v, want gl=de and q=golang", i, requests[i])
+		}
+	}
+}
+
+func TestRetriever_PagingStops(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Always the same page, with a next link, like an engine that ignores start
+		fmt.Fprint(w, `{
+			"organic_results": [{"position": 1, "title": "result", "link": "https://example.com/"}],
+			"news_results": [{"position": 1, "title": "story", "link": "https://example.com/story"}],
+			"serpapi_pagination": {"next": "https://serpapi.com/search?start=next"}
+		}`)
+	}))
+	defer server.Close()
+
+	c := NewClient("key", server.Client())
+	c.baseURL = server.URL
+
+	tests := []struct {
+		name         string
+		request      SearchRequest
+		wantRequests int
+	}{
+		{name: "repeated page", request: SearchRequest{}, wantRequests: 2},
+		{name: "google news", request: SearchRequest{Engine: GoogleNews}, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			docs, err := NewRetrieverWithRequest(c, tt.request).Query(context.Background(), "golang", 50)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(docs) != 2 {
+				t.Errorf("Query() returned %d documents, want 2", len(docs))
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+
+	if _, err := NewRetriever(c).Query(context.Background(), "golang", -1); err == nil {
+		t.Errorf("Query() with negative topK error = nil, want error")
+	}
+}
+
+func TestRetriever_Features(t *testing.T) {
+	body := `{
+		"answer_box": {
+			"type": "organic_result",
+			"title": "Go (programming language) - Wikipedia",
+			"link": "https://en.wikipedia.org/wiki/Go_(programming_language)",
+			"answer": "2009",
+			"snippet": "Go was publicly announced in November 2009."
+		},
+		"knowledge_graph": {
+			"title": "Go",
+			"type": "Programming language",
+			"description": "Go is a statically typed, compiled high-level programming language.",
+			"source": {"name": "Wikipedia", "link": "https://en.wikipedia.org/wiki/Go_(programming_language)"},
+			"designed_by": "Robert Griesemer, Rob Pike, Ken Thompson",
+			"first_appeared": "November 10, 2009",
+			"kgmid": "/m/09gbxjr",
+			"knowledge_graph_search_link": "https://www.google.com/search?kgmid=/m/09gbxjr",
+			"header_images": [{"image": "https://example.com/go.png"}]
+		},
+		"related_questions": [
+			{"question": "Who created Go?", "snippet": "Go was designed at Google.", "link": "https://go.dev/doc/faq"},
+			{"question": "Is Go hard?", "snippet": ""}
+		],
+		"top_stories": [{"title": "Go 1.23 released", "link": "https://go.dev/blog/go1.23", "source": "The Go Blog", "date": "1 day ago"}],
+		"organic_results": [{"position": 1, "title": "The Go Programming Language", "link": "https://go.dev/", "snippet": "Build simple, secure, scalable systems with Go."}]
+	}`
+
+	retriever := NewRetriever(newTestClient(t, http.StatusOK, body))
+	docs, err := retriever.Query(context.Background(), "when was go released", 10)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 1 || docs[0].WebReference.ResultType != ResultOrganic {
+		t.Fatalf("Query() without WithFeatures() = %+v, want only the organic result", docs)
+	}
+
+	docs, err = retriever.WithFeatures().Query(context.Background(), "when was go released", 10)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	want := []struct {
+		resultType string
+		title      string
+		text       string
+	}{
+		{ResultAnswerBox, "Go (programming language) - Wikipedia", "2009\nGo was publicly announced in November 2009."},
+		{ResultKnowledgeGraph, "Go", "Go (Programming language)\nGo is a statically typed, compiled high-level programming language.\ndesigned by: Robert Griesemer, Rob Pike, Ken Thompson\nfirst appeared: November 10, 2009"},
+		{ResultTopStory, "Go 1.23 released", "Go 1.23 released"},
+		{ResultRelatedQuestion, "Who created Go?", "Who created Go?\nGo was designed at Google."},
+		{ResultOrganic, "The Go Programming Language", "Build simple, secure, scalable systems with Go."},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("Query() returned %d documents, want %d", len(docs), len(want))
+	}
+	for i, w := range want {
+		doc := docs[i]
+		if doc.WebReference.ResultType != w.resultType || doc.Title != w.title || doc.Passages[0].Text != w.text {
+			t.Errorf("docs[%d] = %s %q %q, want %s %q %q", i, doc.WebReference.ResultType, doc.Title, doc.Passages[0].Text, w.resultType, w.title, w.text)
+		}
+		if doc.WebReference.APISource != "serp" {
+			t.Errorf("docs[%d] APISource = %q, want serp", i, doc.WebReference.APISource)
+		}
+	}
+	if link := docs[1].WebReference.Link; link != "https://en.wikipedia.org/wiki/Go_(programming_language)" {
+		t.Errorf("knowledge graph link = %s, want its source", link)
+	}
+
+	docs, err = retriever.WithFeatures().Query(context.Background(), "when was go released", 3)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 3 || docs[2].WebReference.ResultType != ResultTopStory {
+		t.Errorf("Query() with topK 3 = %+v, want the first 3 features", docs)
+	}
+}
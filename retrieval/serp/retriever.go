@@ -4,23 +4,193 @@ import (
 	"context"
 	"fmt"
 	"github.com/coopslarhette/raglib/lib/document"
+	"sort"
+	"strings"
 )
 
+// Result types set on WebReference.ResultType of the documents a Retriever returns
+const (
+	ResultAnswerBox       = "answer_box"
+	ResultKnowledgeGraph  = "knowledge_graph"
+	ResultTopStory        = "top_story"
+	ResultRelatedQuestion = "related_question"
+	ResultOrganic         = "organic"
+	ResultNews            = "news"
+)
+
+// pageSize is the number of results Google returns per page, so larger topKs are fetched over several pages
+const pageSize = 10
+
+// maxPages bounds how many pages a Query fetches to fill topK, since each page costs a search
+const maxPages = 10
+
 // Retriever implements the Retriever interface for the SERP API. SERP obtains documents and web ranking by scraping the relevant Google
 // Search results page for a given query.
 type Retriever struct {
 	client *Client
+	// request is the template for each search, with its Query, Num and Start filled in per page
+	request SearchRequest
+	// features is whether Google's answer box, knowledge graph, top stories and related questions are returned
+	features bool
 }
 
+// Query returns up to topK results for query. Normally they're all organic or news results. With WithFeatures, the
+// answer box, knowledge graph, top stories and related questions Google shows for the query come first, marked with
+// their ResultType, and the organic results fill the rest of topK.
 func (sr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
-	result, err := sr.client.Query(ctx, query, topK)
-	if err != nil {
-		return nil, fmt.Errorf("error querying SERP API: %v", err)
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	var features, docs []document.Document
+	seen := make(map[string]bool)
+
+	request := sr.request
+	request.Query = query
+	for page := 0; len(features)+len(docs) < topK && page < maxPages; page++ {
+		request.Num = min(topK-len(features)-len(docs), pageSize)
+		result, err := sr.client.Search(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("error querying SERP API: %w", err)
+		}
+
+		if sr.features && request.Start == 0 {
+			features = featureDocuments(result)
+			if len(features) > topK {
+				features = features[:topK]
+			}
+		}
+
+		added := 0
+		for _, doc := range toDocuments(result) {
+			// Google sometimes repeats a result on the following page
+			if seen[doc.WebReference.Link] {
+				continue
+			}
+			seen[doc.WebReference.Link] = true
+			docs = append(docs, doc)
+			added++
+		}
+
+		// Google News returns all its results on a single page, and an engine that ignores start returns the same page
+		if added == 0 || result.Pagination.Next == "" || request.Engine == GoogleNews {
+			break
+		}
+		request.Start += request.Num
 	}
 
-	docs := make([]document.Document, len(result.OrganicResults))
-	for i, r := range result.OrganicResults {
-		docs[i] = document.Document{
+	if len(features)+len(docs) > topK {
+		docs = docs[:topK-len(features)]
+	}
+	return append(features, docs...), nil
+}
+
+// WithFeatures returns a copy of the retriever that also returns Google's answer box, knowledge graph, top stories and
+// related questions, so answers can cite a featured snippet. They count towards topK.
+func (sr Retriever) WithFeatures() Retriever {
+	sr.features = true
+	return sr
+}
+
+// featureDocuments converts the answer box, knowledge graph, top stories and related questions of a page to documents,
+// in that order
+func featureDocuments(result *SearchResult) []document.Document {
+	var docs []document.Document
+
+	if box := result.AnswerBox; box != nil {
+		var texts []string
+		for _, text := range append([]string{box.Answer, box.Result, box.Snippet}, box.List...) {
+			if text != "" {
+				texts = append(texts, text)
+			}
+		}
+		if len(texts) > 0 {
+			title := box.Title
+			if title == "" {
+				title = "Featured snippet"
+			}
+			docs = append(docs, webDocument(title, strings.Join(texts, "\n"), ResultAnswerBox, &document.WebReference{
+				Link:          box.Link,
+				DisplayedLink: box.DisplayedLink,
+				Blurb:         texts[0],
+				Date:          box.Date,
+				Favicon:       box.Favicon,
+				Thumbnail:     box.Thumbnail,
+			}))
+		}
+	}
+
+	if kg := result.KnowledgeGraph; kg != nil && kg.Title != "" {
+		lines := []string{kg.Title}
+		if kg.Type != "" {
+			lines[0] += " (" + kg.Type + ")"
+		}
+		if kg.Description != "" {
+			lines = append(lines, kg.Description)
+		}
+		keys := make([]string, 0, len(kg.Attributes))
+		for k := range kg.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			lines = append(lines, strings.ReplaceAll(k, "_", " ")+": "+kg.Attributes[k])
+		}
+
+		link, displayed := kg.Website, kg.Website
+		if link == "" {
+			link, displayed = kg.Source.Link, kg.Source.Name
+		}
+		docs = append(docs, webDocument(kg.Title, strings.Join(lines, "\n"), ResultKnowledgeGraph, &document.WebReference{
+			Link:          link,
+			DisplayedLink: displayed,
+			Blurb:         kg.Description,
+		}))
+	}
+
+	for _, story := range result.TopStories {
+		docs = append(docs, webDocument(story.Title, story.Title, ResultTopStory, &document.WebReference{
+			Link:          story.Link,
+			DisplayedLink: story.Source,
+			Date:          story.Date,
+			Thumbnail:     story.Thumbnail,
+		}))
+	}
+
+	for _, q := range result.RelatedQuestions {
+		answer := strings.Join(append([]string{q.Snippet}, q.List...), "\n")
+		if strings.TrimSpace(answer) == "" {
+			continue
+		}
+		docs = append(docs, webDocument(q.Question, q.Question+"\n"+strings.TrimSpace(answer), ResultRelatedQuestion, &document.WebReference{
+			Link:          q.Link,
+			DisplayedLink: q.DisplayedLink,
+			Blurb:         q.Snippet,
+			Date:          q.Date,
+		}))
+	}
+
+	return docs
+}
+
+// webDocument creates a single passage document from a SerpApi result, filling in the reference's title and source
+func webDocument(title, text, resultType string, ref *document.WebReference) document.Document {
+	ref.Title = title
+	ref.APISource = "serp"
+	ref.ResultType = resultType
+	return document.Document{
+		Passages:     []document.Passage{{Text: text}},
+		Corpus:       document.Web,
+		WebReference: ref,
+		Title:        title,
+	}
+}
+
+// toDocuments converts the organic and news results of a page to documents, in the order they were ranked
+func toDocuments(result *SearchResult) []document.Document {
+	docs := make([]document.Document, 0, len(result.OrganicResults)+len(result.NewsResults))
+	for _, r := range result.OrganicResults {
+		docs = append(docs, document.Document{
 			Passages: []document.Passage{
 				// TODO: maybe setup Query to accept a kind of parser as an argument to
 				//   handle different search results types
@@ -37,14 +207,46 @@ func (sr Retriever) Query(ctx context.Context, query string, topK int) ([]docume
 				Author:        r.Author,
 				Thumbnail:     r.Thumbnail,
 				APISource:     "serp",
+				ResultType:    ResultOrganic,
 			},
 			Title: r.Title,
+		})
+	}
+
+	for _, r := range result.NewsResults {
+		author := r.Source.Name
+		if len(r.Source.Authors) > 0 {
+			author = r.Source.Authors[0]
 		}
+		docs = append(docs, document.Document{
+			Passages: []document.Passage{{Text: r.Snippet}},
+			Corpus:   document.Web,
+			WebReference: &document.WebReference{
+				Title:         r.Title,
+				Link:          r.Link,
+				DisplayedLink: r.Source.Name,
+				Blurb:         r.Snippet,
+				Date:          r.Date,
+				Favicon:       r.Source.Icon,
+				Author:        author,
+				Thumbnail:     r.Thumbnail,
+				APISource:     "serp",
+				ResultType:    ResultNews,
+			},
+			Title: r.Title,
+		})
 	}
 
-	return docs, nil
+	return docs
 }
 
 func NewRetriever(client *Client) Retriever {
-	return Retriever{client}
+	return Retriever{client: client}
+}
+
+// NewRetrieverWithRequest creates a Retriever whose searches use the engine, locale and filters of request. Its Query,
+// Num and Start are ignored.
+func NewRetrieverWithRequest(client *Client, request SearchRequest) Retriever {
+	request.Start = 0
+	return Retriever{client: client, request: request}
 }
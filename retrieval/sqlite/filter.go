@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"strings"
+)
+
+// Filter restricts a search to passages whose metadata matches. Filters are rendered to SQL over the JSON metadata
+// column, so SQLite applies them before any vectors are read. A nil Filter matches every passage.
+type Filter interface {
+	// where renders the filter as a SQL condition on column, appending its parameters to args
+	where(column string, args *[]any) string
+}
+
+type equals struct{ key, value string }
+
+// Equals matches passages whose metadata value for key is exactly value
+func Equals(key, value string) Filter {
+	return equals{key, value}
+}
+
+func (f equals) where(column string, args *[]any) string {
+	*args = append(*args, jsonPath(f.key), f.value)
+	return "json_extract(" + column + ", ?) = ?"
+}
+
+type in struct {
+	key    string
+	values []string
+}
+
+// In matches passages whose metadata value for key is any of values
+func In(key string, values ...string) Filter {
+	return in{key, values}
+}
+
+func (f in) where(column string, args *[]any) string {
+	if len(f.values) == 0 {
+		return "0"
+	}
+	*args = append(*args, jsonPath(f.key))
+	for _, v := range f.values {
+		*args = append(*args, v)
+	}
+	return "json_extract(" + column + ", ?) IN (?" + strings.Repeat(", ?", len(f.values)-1) + ")"
+}
+
+type exists struct{ key string }
+
+// Exists matches passages that have any value for key
+func Exists(key string) Filter {
+	return exists{key}
+}
+
+func (f exists) where(column string, args *[]any) string {
+	*args = append(*args, jsonPath(f.key))
+	return "json_type(" + column + ", ?) IS NOT NULL"
+}
+
+type and []Filter
+
+// And matches passages that satisfy every one of filters
+func And(filters ...Filter) Filter {
+	return and(filters)
+}
+
+func (f and) where(column string, args *[]any) string {
+	return join(f, " AND ", "1", column, args)
+}
+
+type or []Filter
+
+// Or matches passages that satisfy any of filters
+func Or(filters ...Filter) Filter {
+	return or(filters)
+}
+
+func (f or) where(column string, args *[]any) string {
+	return join(f, " OR ", "0", column, args)
+}
+
+type not struct{ filter Filter }
+
+// Not matches passages that don't satisfy filter
+func Not(filter Filter) Filter {
+	return not{filter}
+}
+
+func (f not) where(column string, args *[]any) string {
+	return "NOT (" + f.filter.where(column, args) + ")"
+}
+
+func join(filters []Filter, op, empty, column string, args *[]any) string {
+	if len(filters) == 0 {
+		return empty
+	}
+	conditions := make([]string, len(filters))
+	for i, f := range filters {
+		conditions[i] = "(" + f.where(column, args) + ")"
+	}
+	return strings.Join(conditions, op)
+}
+
+// jsonPath is the JSON path of a top level key, quoted so keys containing dots or brackets aren't parsed as paths
+func jsonPath(key string) string {
+	return `$."` + key + `"`
+}
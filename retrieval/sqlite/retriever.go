@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval"
+	"github.com/coopslarhette/raglib/lib/retrieval/fusion"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/metadata"
+)
+
+// Mode is the kind of search a Retriever runs
+type Mode int
+
+const (
+	// Hybrid runs a keyword and a vector search and merges their rankings with reciprocal rank fusion. Without an
+	// embedder it falls back to Keyword.
+	Hybrid Mode = iota
+	// Keyword runs a full text search ranked by BM25
+	Keyword
+	// Vector embeds the query and ranks entries by the cosine similarity of their embeddings
+	Vector
+)
+
+// Retriever implements the retrieval.Retriever interface over a Store, so a desktop app or a single binary can
+// retrieve from one SQLite file without any other services
+type Retriever struct {
+	store    *Store
+	embedder retrieval.Embedder
+	mode     Mode
+	filter   Filter
+	// metadataKeys narrows the entry metadata copied to each returned passage, all of it is copied when empty
+	metadataKeys []string
+}
+
+func (sr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+
+	mode := sr.mode
+	if mode == Hybrid && sr.embedder == nil {
+		mode = Keyword
+	}
+
+	var results []Result
+	var err error
+	switch mode {
+	case Keyword:
+		results, err = sr.store.KeywordSearch(ctx, query, topK, sr.filter)
+	case Vector:
+		results, err = sr.vectorSearch(ctx, query, topK)
+	case Hybrid:
+		results, err = sr.hybrid(ctx, query, topK)
+	default:
+		err = fmt.Errorf("unknown mode %d", mode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error searching SQLite store: %w", err)
+	}
+
+	docs := make([]document.Document, len(results))
+	for i, r := range results {
+		md := r.Entry.Metadata
+		if len(sr.metadataKeys) > 0 {
+			md = metadata.Select(md, sr.metadataKeys)
+		}
+		docs[i] = document.Document{
+			Passages: []document.Passage{{Text: r.Entry.Text, Score: float64(r.Score), Metadata: md}},
+			Title:    r.Entry.Title,
+			Corpus:   document.Personal,
+		}
+	}
+	return docs, nil
+}
+
+func (sr Retriever) vectorSearch(ctx context.Context, query string, topK int) ([]Result, error) {
+	if sr.embedder == nil {
+		return nil, fmt.Errorf("vector search needs an embedder")
+	}
+	vectors, err := retrieval.Embed(ctx, sr.embedder, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error creating query embedding: %v", err)
+	}
+	return sr.store.VectorSearch(ctx, vectors[0], topK, sr.filter)
+}
+
+// hybrid fuses the rankings of a keyword and a vector search, replacing each result's Score with its fused score
+func (sr Retriever) hybrid(ctx context.Context, query string, topK int) ([]Result, error) {
+	keyword, err := sr.store.KeywordSearch(ctx, query, topK, sr.filter)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := sr.vectorSearch(ctx, query, topK)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Entry)
+	var keys [][]string
+	for _, ranking := range [][]Result{keyword, vector} {
+		var ranked []string
+		for _, r := range ranking {
+			if _, ok := byID[r.Entry.ID]; !ok {
+				byID[r.Entry.ID] = r.Entry
+			}
+			ranked = append(ranked, r.Entry.ID)
+		}
+		keys = append(keys, ranked)
+	}
+
+	fused := fusion.Fuse(keys, fusion.K)
+	if len(fused) > topK {
+		fused = fused[:topK]
+	}
+	results := make([]Result, len(fused))
+	for i, f := range fused {
+		results[i] = Result{Entry: byID[f.Key], Score: float32(f.Score)}
+	}
+	return results, nil
+}
+
+// Add embeds any entries that don't have a Vector yet, when the retriever has an embedder, and upserts them into the
+// store
+func (sr Retriever) Add(ctx context.Context, entries ...Entry) error {
+	if sr.embedder == nil {
+		return sr.store.Upsert(ctx, entries...)
+	}
+
+	var texts []string
+	var missing []int
+	for i, e := range entries {
+		if len(e.Vector) == 0 {
+			texts = append(texts, e.Text)
+			missing = append(missing, i)
+		}
+	}
+
+	if len(texts) > 0 {
+		vectors, err := retrieval.Embed(ctx, sr.embedder, texts)
+		if err != nil {
+			return fmt.Errorf("error embedding entries: %v", err)
+		}
+
+		entries = append([]Entry(nil), entries...)
+		for j, i := range missing {
+			entries[i].Vector = vectors[j]
+		}
+	}
+
+	return sr.store.Upsert(ctx, entries...)
+}
+
+// WithMetadata returns a copy of the retriever whose passages only carry the entry metadata under keys
+func (sr Retriever) WithMetadata(keys ...string) Retriever {
+	sr.metadataKeys = keys
+	return sr
+}
+
+// WithFilter returns a copy of the retriever that only returns entries whose metadata matches filter
+func (sr Retriever) WithFilter(filter Filter) Retriever {
+	sr.filter = filter
+	return sr
+}
+
+// WithMode returns a copy of the retriever that searches with mode
+func (sr Retriever) WithMode(mode Mode) Retriever {
+	sr.mode = mode
+	return sr
+}
+
+// NewRetriever creates a Hybrid Retriever over store. embedder may be nil for a keyword only store, otherwise it must
+// be the same model that was used to embed the entries.
+func NewRetriever(store *Store, embedder retrieval.Embedder) Retriever {
+	return Retriever{store: store, embedder: embedder}
+}
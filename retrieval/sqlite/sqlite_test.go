@@ -0,0 +1,219 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"github.com/coopslarhette/raglib/lib/document"
+	_ "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openStore creates a store in a fresh database file. FTS5 is optional in go-sqlite3, so the test is skipped unless
+// it was built with the sqlite_fts5 tag.
+func openStore(t *testing.T) *Store {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "rag.db"))
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err = db.Exec("CREATE VIRTUAL TABLE probe USING fts5(text)"); err != nil {
+		t.Skipf("SQLite built without FTS5, run with -tags sqlite_fts5: %v", err)
+	}
+
+	store := NewStore(db, Config{})
+	if err = store.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	return store
+}
+
+// letterEmbedder embeds text as its counts of the letters x, y and z, so the test entries each lean towards one axis
+type letterEmbedder struct{}
+
+func (letterEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{
+			float32(strings.Count(text, "x")),
+			float32(strings.Count(text, "y")),
+			float32(strings.Count(text, "z")),
+		}
+	}
+	return vectors, nil
+}
+
+func resultIDs(results []Result) string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Entry.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestFtsQuery(t *testing.T) {
+	got := ftsQuery(`rotate "keys" NOT-now, café`)
+	if want := `"rotate" OR "keys" OR "NOT" OR "now" OR "café"`; got != want {
+		t.Errorf("ftsQuery() = %s, want %s", got, want)
+	}
+}
+
+func TestFilter_where(t *testing.T) {
+	var args []any
+	got := And(Equals("lang", "en"), Or(In("team", "a", "b"), Not(Exists("archived")))).where("metadata", &args)
+	want := `(json_extract(metadata, ?) = ?) AND ((json_extract(metadata, ?) IN (?, ?)) OR (NOT (json_type(metadata, ?) IS NOT NULL)))`
+	if got != want {
+		t.Errorf("where() =\n%s\nwant\n%s", got, want)
+	}
+	wantArgs := []any{`$."lang"`, "en", `$."team"`, "a", "b", `$."archived"`}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("where() args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := openStore(t)
+	ctx := context.Background()
+
+	err := store.Upsert(ctx,
+		Entry{ID: "keys", Title: "Keys", Text: "Rotating the signing keys every quarter", Metadata: map[string]string{"team": "infra"}, Vector: []float32{1, 0, 0}},
+		Entry{ID: "oncall", Title: "On call", Text: "Who to page when keys expire", Metadata: map[string]string{"team": "sre"}, Vector: []float32{0.6, 0.8, 0}},
+		Entry{ID: "lunch", Title: "Lunch", Text: "The cafeteria menu"},
+	)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	results, err := store.KeywordSearch(ctx, "key rotation", 5, nil)
+	if err != nil {
+		t.Fatalf("KeywordSearch() error = %v", err)
+	}
+	// Porter stemming matches "Rotating" and "keys"
+	if got := resultIDs(results); got != "keys,oncall" {
+		t.Errorf("KeywordSearch() = %s, want keys,oncall", got)
+	}
+	if results[0].Entry.Metadata["team"] != "infra" || results[0].Entry.Title != "Keys" {
+		t.Errorf("KeywordSearch() entry = %+v", results[0].Entry)
+	}
+
+	results, err = store.KeywordSearch(ctx, "keys", 5, Equals("team", "sre"))
+	if err != nil {
+		t.Fatalf("KeywordSearch() error = %v", err)
+	}
+	if got := resultIDs(results); got != "oncall" {
+		t.Errorf("filtered KeywordSearch() = %s, want oncall", got)
+	}
+
+	results, err = store.VectorSearch(ctx, []float32{0, 2, 0}, 5, nil)
+	if err != nil {
+		t.Fatalf("VectorSearch() error = %v", err)
+	}
+	if got := resultIDs(results); got != "oncall,keys" {
+		t.Errorf("VectorSearch() = %s, want oncall,keys since lunch has no vector", got)
+	}
+	if results[0].Score < 0.79 || results[0].Score > 0.81 {
+		t.Errorf("VectorSearch() score = %v, want 0.8", results[0].Score)
+	}
+
+	// Replacing an entry updates the full text index
+	if err = store.Upsert(ctx, Entry{ID: "lunch", Title: "Lunch", Text: "Keys to the cafeteria"}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if err = store.Delete(ctx, "keys"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	results, err = store.KeywordSearch(ctx, "keys", 5, nil)
+	if err != nil {
+		t.Fatalf("KeywordSearch() error = %v", err)
+	}
+	if got := resultIDs(results); got != "oncall,lunch" && got != "lunch,oncall" {
+		t.Errorf("KeywordSearch() after update = %s, want oncall and lunch", got)
+	}
+	if results, _ = store.KeywordSearch(ctx, "menu", 5, nil); len(results) != 0 {
+		t.Errorf("KeywordSearch() found the replaced text")
+	}
+}
+
+func TestRetriever(t *testing.T) {
+	store := openStore(t)
+	ctx := context.Background()
+
+	r := NewRetriever(store, letterEmbedder{})
+	err := r.Add(ctx,
+		Entry{ID: "a", Title: "A", Text: "x marks the spot"},
+		Entry{ID: "b", Title: "B", Text: "y is for yak and spot"},
+		Entry{ID: "c", Title: "C", Text: "z is unrelated", Metadata: map[string]string{"draft": "true"}},
+	)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		r     Retriever
+		query string
+		want  string
+	}{
+		{name: "keyword", r: r.WithMode(Keyword), query: "yak", want: "B"},
+		{name: "vector", r: r.WithMode(Vector), query: "z", want: "C,A,B"},
+		// B is nearest and has both words, A only has "spot" and C is only found by the vector search
+		{name: "hybrid", r: r, query: "y spot", want: "B,A,C"},
+		{name: "filter", r: r.WithMode(Vector).WithFilter(Not(Exists("draft"))), query: "z", want: "A,B"},
+		{name: "no embedder", r: NewRetriever(store, nil), query: "unrelated", want: "C"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := tt.r.Query(ctx, tt.query, 3)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			got := make([]string, len(docs))
+			for i, d := range docs {
+				got[i] = d.Title
+				if d.Corpus != document.Personal || len(d.Passages) != 1 {
+					t.Errorf("Query() document = %+v, want one personal passage", d)
+				}
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("Query() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetriever_WithMetadata(t *testing.T) {
+	store := openStore(t)
+	ctx := context.Background()
+
+	r := NewRetriever(store, nil)
+	err := r.Add(ctx, Entry{ID: "a", Title: "A", Text: "x marks the spot", Metadata: map[string]string{"lang": "en", "team": "maps"}})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		r    Retriever
+		want map[string]string
+	}{
+		{name: "all", r: r, want: map[string]string{"lang": "en", "team": "maps"}},
+		{name: "narrowed", r: r.WithMetadata("team", "missing"), want: map[string]string{"team": "maps"}},
+		{name: "none", r: r.WithMetadata("missing"), want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := tt.r.Query(ctx, "spot", 1)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(docs) != 1 || !reflect.DeepEqual(docs[0].Passages[0].Metadata, tt.want) {
+				t.Errorf("Query() = %+v, want metadata %v", docs, tt.want)
+			}
+		})
+	}
+}
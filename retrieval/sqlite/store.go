@@ -0,0 +1,386 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/internal/vecmath"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultTable     = "passages"
+	defaultTokenizer = "porter unicode61"
+)
+
+// Config describes the tables a Store keeps in the database
+type Config struct {
+	// Table is the name of the table passages are stored in, default "passages". Its full text index is the table
+	// "<Table>_fts".
+	Table string
+	// Tokenizer is the FTS5 tokenizer of the full text index, default "porter unicode61" which folds case and
+	// diacritics and stems English words. It only takes effect when the index is created.
+	Tokenizer string
+}
+
+func (c Config) withDefaults() Config {
+	if c.Table == "" {
+		c.Table = defaultTable
+	}
+	if c.Tokenizer == "" {
+		c.Tokenizer = defaultTokenizer
+	}
+	return c
+}
+
+// Entry is a single passage in the store
+type Entry struct {
+	// ID uniquely identifies the entry, upserting an entry with an existing ID replaces it. When empty a random one
+	// is assigned.
+	ID       string
+	Title    string
+	Text     string
+	Metadata map[string]string
+	// Vector is the embedding of Text. Entries without one are only found by keyword searches. It's left empty on
+	// entries returned by searches.
+	Vector []float32
+}
+
+// Result is an Entry matched by a search, along with its score. Higher scores are better, but keyword (BM25) and
+// vector (cosine similarity) scores aren't comparable.
+type Result struct {
+	Entry Entry
+	Score float32
+}
+
+// Store keeps passages, their metadata and embeddings in a single SQLite database, with an FTS5 index for keyword
+// search. It works with any database/sql driver for SQLite that includes FTS5 and the JSON functions, e.g.
+// modernc.org/sqlite, or github.com/mattn/go-sqlite3 built with the sqlite_fts5 tag.
+//
+// Vector search is brute force: every embedding that passes the filter is compared with the query. Embeddings are
+// stored as little endian float32 blobs, the format sqlite-vec uses, so they can be indexed by a vec0 table later
+// without being rewritten.
+type Store struct {
+	db     *sql.DB
+	config Config
+	// quoted table names
+	table, fts string
+}
+
+func NewStore(db *sql.DB, config Config) *Store {
+	config = config.withDefaults()
+	return &Store{
+		db:     db,
+		config: config,
+		table:  quoteIdentifier(config.Table),
+		fts:    quoteIdentifier(config.Table + "_fts"),
+	}
+}
+
+// CreateTable creates the passages table, its full text index and the triggers keeping the index in sync, if they
+// don't exist yet
+func (s *Store) CreateTable(ctx context.Context) error {
+	trigger := func(suffix string) string {
+		return quoteIdentifier(s.config.Table + "_" + suffix)
+	}
+	deleteFTS := fmt.Sprintf("INSERT INTO %s (%s, rowid, title, text) VALUES ('delete', old.id, old.title, old.text);",
+		s.fts, s.fts)
+	insertFTS := fmt.Sprintf("INSERT INTO %s (rowid, title, text) VALUES (new.id, new.title, new.text);", s.fts)
+
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY,
+	key TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL DEFAULT '',
+	text TEXT NOT NULL,
+	metadata TEXT NOT NULL DEFAULT '{}',
+	embedding BLOB
+)`, s.table),
+		// An external content index, so the text is only stored once
+		fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(title, text, content=%s, content_rowid='id', tokenize=%s)",
+			s.fts, quoteString(s.config.Table), quoteString(s.config.Tokenizer)),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER INSERT ON %s BEGIN %s END",
+			trigger("ai"), s.table, insertFTS),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER DELETE ON %s BEGIN %s END",
+			trigger("ad"), s.table, deleteFTS),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER UPDATE ON %s BEGIN %s %s END",
+			trigger("au"), s.table, deleteFTS, insertFTS),
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("error creating SQLite tables: %v", err)
+		}
+	}
+	return nil
+}
+
+// Upsert inserts entries, replacing any existing entries with the same IDs, in a single transaction
+func (s *Store) Upsert(ctx context.Context, entries ...Entry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (key, title, text, metadata, embedding) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (key) DO UPDATE SET title = excluded.title, text = excluded.text, metadata = excluded.metadata,
+	embedding = excluded.embedding`, s.table))
+	if err != nil {
+		return fmt.Errorf("error preparing upsert: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if e.ID == "" {
+			if e.ID, err = randomID(); err != nil {
+				return err
+			}
+		}
+
+		metadata := e.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadataJSON, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("error encoding metadata of entry %q: %v", e.ID, err)
+		}
+
+		var embedding []byte
+		if len(e.Vector) > 0 {
+			embedding = encodeVector(e.Vector)
+		}
+
+		if _, err = stmt.ExecContext(ctx, e.ID, e.Title, e.Text, string(metadataJSON), embedding); err != nil {
+			return fmt.Errorf("error upserting entry %q: %v", e.ID, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing upsert: %v", err)
+	}
+	return nil
+}
+
+// Delete removes the entries with the given IDs
+func (s *Store) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE key IN (?%s)", s.table, strings.Repeat(", ?", len(ids)-1))
+	if _, err := s.db.ExecContext(ctx, stmt, args...); err != nil {
+		return fmt.Errorf("error deleting entries: %v", err)
+	}
+	return nil
+}
+
+// KeywordSearch returns up to topK entries matching filter that contain any of the words of query, ranked by BM25
+func (s *Store) KeywordSearch(ctx context.Context, query string, topK int, filter Filter) ([]Result, error) {
+	match := ftsQuery(query)
+	if match == "" || topK <= 0 {
+		return nil, nil
+	}
+
+	args := []any{match}
+	where := fmt.Sprintf("%s MATCH ?", s.fts)
+	if filter != nil {
+		where += " AND " + filter.where("p.metadata", &args)
+	}
+	args = append(args, topK)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT p.key, p.title, p.text, p.metadata, bm25(%s) AS rank
+FROM %s JOIN %s p ON p.id = %s.rowid
+WHERE %s ORDER BY rank LIMIT ?`, s.fts, s.fts, s.table, s.fts, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error searching %s: %v", s.config.Table, err)
+	}
+	defer rows.Close()
+
+	var results []Result
+	for rows.Next() {
+		var r Result
+		var metadata string
+		var rank float64
+		if err = rows.Scan(&r.Entry.ID, &r.Entry.Title, &r.Entry.Text, &metadata, &rank); err != nil {
+			return nil, fmt.Errorf("error reading search result: %v", err)
+		}
+		if err = decodeMetadata(metadata, &r.Entry); err != nil {
+			return nil, err
+		}
+		// bm25() is negated so that ascending order puts the best match first
+		r.Score = float32(-rank)
+		results = append(results, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading search results: %v", err)
+	}
+	return results, nil
+}
+
+// VectorSearch returns up to topK entries matching filter, ordered by the cosine similarity of their embeddings to
+// vector. Entries without an embedding, or with one of a different length, are skipped.
+func (s *Store) VectorSearch(ctx context.Context, vector []float32, topK int, filter Filter) ([]Result, error) {
+	if topK <= 0 {
+		return nil, nil
+	}
+
+	type candidate struct {
+		id    int64
+		score float32
+	}
+	var candidates []candidate
+	query := vecmath.Normalize(vector)
+
+	// Only the embeddings are scanned, the text of the best entries is read afterward
+	var args []any
+	stmt := fmt.Sprintf("SELECT id, embedding FROM %s WHERE embedding IS NOT NULL", s.table)
+	if filter != nil {
+		stmt += " AND " + filter.where("metadata", &args)
+	}
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error searching %s: %v", s.config.Table, err)
+	}
+	for rows.Next() {
+		var c candidate
+		var embedding []byte
+		if err = rows.Scan(&c.id, &embedding); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error reading embedding: %v", err)
+		}
+		if len(embedding) != 4*len(query) {
+			continue
+		}
+		c.score = cosine(query, embedding)
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading embeddings: %v", err)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > topK {
+		candidates = candidates[:topK]
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]any, len(candidates))
+	position := make(map[int64]int, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.id
+		position[c.id] = i
+	}
+	rows, err = s.db.QueryContext(ctx, fmt.Sprintf("SELECT id, key, title, text, metadata FROM %s WHERE id IN (?%s)",
+		s.table, strings.Repeat(", ?", len(ids)-1)), ids...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading search results: %v", err)
+	}
+	defer rows.Close()
+
+	results := make([]Result, len(candidates))
+	found := 0
+	for rows.Next() {
+		var id int64
+		var e Entry
+		var metadata string
+		if err = rows.Scan(&id, &e.ID, &e.Title, &e.Text, &metadata); err != nil {
+			return nil, fmt.Errorf("error reading search result: %v", err)
+		}
+		if err = decodeMetadata(metadata, &e); err != nil {
+			return nil, err
+		}
+		i := position[id]
+		results[i] = Result{Entry: e, Score: candidates[i].score}
+		found++
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading search results: %v", err)
+	}
+
+	// Entries deleted between the two queries leave gaps
+	if found < len(results) {
+		kept := results[:0]
+		for _, r := range results {
+			if r.Entry.ID != "" {
+				kept = append(kept, r)
+			}
+		}
+		results = kept
+	}
+	return results, nil
+}
+
+// ftsQuery turns free text into an FTS5 query matching any of its words. Each word is quoted, so punctuation and
+// FTS5 operators like NOT or NEAR in the text can't make the query invalid.
+func ftsQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = `"` + w + `"`
+	}
+	return strings.Join(words, " OR ")
+}
+
+func decodeMetadata(metadata string, e *Entry) error {
+	if err := json.Unmarshal([]byte(metadata), &e.Metadata); err != nil {
+		return fmt.Errorf("error decoding metadata of entry %q: %v", e.ID, err)
+	}
+	return nil
+}
+
+// encodeVector encodes v as consecutive little endian float32s
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+// cosine is the cosine similarity of the unit vector query and the encoded vector b
+func cosine(query []float32, b []byte) float32 {
+	var dot, norm float64
+	for i, q := range query {
+		x := float64(math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:])))
+		dot += float64(q) * x
+		norm += x * x
+	}
+	if norm == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(norm))
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating entry id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
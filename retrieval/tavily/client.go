@@ -0,0 +1,131 @@
+package tavily
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"github.com/coopslarhette/raglib/lib/retrieval/ratelimit"
+	"io"
+	"net/http"
+)
+
+const defaultBaseURL = "https://api.tavily.com"
+
+// Search depths for SearchRequest.SearchDepth. Advanced searches cost two credits and return more relevant content.
+const (
+	Basic    = "basic"
+	Advanced = "advanced"
+)
+
+// Client is the HTTP client for querying Tavily API endpoints
+type Client struct {
+	apiKey  string
+	client  *http.Client
+	baseURL string
+	limiter *ratelimit.Limiter
+}
+
+// SearchRequest represents the request structure for the Tavily API search endpoint
+type SearchRequest struct {
+	Query string `json:"query"`
+	// SearchDepth is Basic or Advanced
+	SearchDepth string `json:"search_depth,omitempty"`
+	// Topic is "general" or "news"
+	Topic string `json:"topic,omitempty"`
+	// TimeRange is one of "day", "week", "month" or "year", counted back from today
+	TimeRange  string `json:"time_range,omitempty"`
+	MaxResults int    `json:"max_results,omitempty"`
+	// IncludeAnswer asks for an LLM generated answer to the query, grounded in the results
+	IncludeAnswer bool `json:"include_answer,omitempty"`
+	// IncludeRawContent returns the cleaned full text of each page alongside its relevant content
+	IncludeRawContent bool     `json:"include_raw_content,omitempty"`
+	IncludeFavicon    bool     `json:"include_favicon,omitempty"`
+	IncludeDomains    []string `json:"include_domains,omitempty"`
+	ExcludeDomains    []string `json:"exclude_domains,omitempty"`
+}
+
+type SearchResult struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Content is the part of the page most relevant to the query
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+	// RawContent is the page's full text when SearchRequest.IncludeRawContent was set
+	RawContent string `json:"raw_content"`
+	// PublishedDate is only set for the news topic
+	PublishedDate string `json:"published_date"`
+	Favicon       string `json:"favicon"`
+}
+
+type SearchResponse struct {
+	Query        string         `json:"query"`
+	Answer       string         `json:"answer"`
+	Results      []SearchResult `json:"results"`
+	ResponseTime float64        `json:"response_time"`
+	RequestID    string         `json:"request_id"`
+}
+
+func NewClient(apiKey string, client *http.Client) *Client {
+	return &Client{
+		apiKey:  apiKey,
+		client:  client,
+		baseURL: defaultBaseURL,
+	}
+}
+
+// SetLimiter throttles, retries and meters the client's requests with limiter
+func (c *Client) SetLimiter(limiter *ratelimit.Limiter) {
+	c.limiter = limiter
+}
+
+func (c *Client) Search(ctx context.Context, request SearchRequest) (*SearchResponse, error) {
+	var result SearchResponse
+	if err := c.post(ctx, "/search", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, request any, result any) error {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshaling request body: %v", err)
+	}
+
+	return c.limiter.Do(ctx, func(ctx context.Context) error {
+		return c.send(ctx, path, requestBody, result)
+	})
+}
+
+func (c *Client) send(ctx context.Context, path string, requestBody []byte, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return fmt.Errorf("error constructing request for Tavily API: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while executing request to Tavily API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading Tavily API response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return apierror.FromResponse("tavily", resp, body)
+	}
+
+	if err = json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("error parsing Tavily API response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package tavily
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/coopslarhette/raglib/lib/retrieval/apierror"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c := NewClient("tvly-key", server.Client())
+	c.baseURL = server.URL
+	return c
+}
+
+func TestRetriever_Query(t *testing.T) {
+	var request SearchRequest
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.Header.Get("Authorization") != "Bearer tvly-key" {
+			t.Errorf("request to %s with Authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("decoding request: %v", err)
+		}
+		w.Write([]byte(`{
+			"query": "when was go released",
+			"answer": "Go was released in November 2009.",
+			"results": [{
+				"title": "Go (programming language) - Wikipedia",
+				"url": "https://en.wikipedia.org/wiki/Go_(programming_language)",
+				"content": "Go was publicly announced in November 2009.",
+				"score": 0.92,
+				"raw_content": "Go is a programming language.\n\nIt was designed at Google.\n\nVersion 1.0 was released in March 2012.",
+				"favicon": "https://en.wikipedia.org/favicon.ico"
+			}],
+			"response_time": 1.2
+		}`))
+	})
+
+	retriever := NewRetrieverWithOptions(c, Options{
+		SearchDepth:       Advanced,
+		IncludeDomains:    []string{"wikipedia.org"},
+		IncludeAnswer:     true,
+		IncludeRawContent: true,
+		MaxPassageChars:   40,
+		MaxRawPassages:    2,
+	})
+	docs, err := retriever.Query(context.Background(), "when was go released", 50)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	if request.Query != "when was go released" || request.SearchDepth != Advanced || request.MaxResults != maxResults ||
+		!request.IncludeAnswer || !request.IncludeRawContent || len(request.IncludeDomains) != 1 {
+		t.Errorf("request = %+v", request)
+	}
+
+	if len(docs) != 2 {
+		t.Fatalf("Query() returned %d documents, want 2", len(docs))
+	}
+	if answer := docs[0]; answer.WebReference.ResultType != ResultAnswer || answer.Passages[0].Text != "Go was released in November 2009." {
+		t.Errorf("answer document = %+v", answer)
+	}
+
+	doc := docs[1]
+	wantPassages := []string{
+		"Go was publicly announced in November 2009.",
+		"Go is a programming language.",
+		"It was designed at Google.",
+	}
+	if len(doc.Passages) != len(wantPassages) {
+		t.Fatalf("got %d passages, want %d: %v", len(doc.Passages), len(wantPassages), doc.Passages)
+	}
+	for i, want := range wantPassages {
+		if doc.Passages[i].Text != want {
+			t.Errorf("passage %d = %q, want %q", i, doc.Passages[i].Text, want)
+		}
+	}
+	if doc.Passages[0].Score != 0.92 {
+		t.Errorf("Score = %v, want 0.92", doc.Passages[0].Score)
+	}
+	ref := doc.WebReference
+	if ref.DisplayedLink != "wikipedia.org" || ref.Favicon != "https://en.wikipedia.org/favicon.ico" || ref.APISource != "tavily" {
+		t.Errorf("WebReference = %+v", ref)
+	}
+}
+
+func TestRetriever_QueryTopK(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{
+			"answer": "Go was released in November 2009.",
+			"results": [
+				{"title": "Go", "url": "https://go.dev/", "content": "Build simple, secure, scalable systems with Go."},
+				{"title": "Go (programming language)", "url": "https://en.wikipedia.org/wiki/Go", "content": "Go was announced in 2009."}
+			]
+		}`))
+	})
+	retriever := NewRetrieverWithOptions(c, Options{IncludeAnswer: true})
+
+	docs, err := retriever.Query(context.Background(), "when was go released", 2)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 2 || docs[0].WebReference.ResultType != ResultAnswer || docs[1].Title != "Go" {
+		t.Errorf("Query() = %+v, want the answer and the first result", docs)
+	}
+
+	if docs, err = retriever.Query(context.Background(), "when was go released", 0); err != nil || len(docs) != 0 {
+		t.Errorf("Query() with topK 0 = %v, %v, want no documents and no error", docs, err)
+	}
+	if _, err = retriever.Query(context.Background(), "when was go released", -1); err == nil {
+		t.Errorf("Query() with negative topK error = nil, want error")
+	}
+	if requests != 1 {
+		t.Errorf("sent %d requests, want 1", requests)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"detail": {"error": "Unauthorized: missing or invalid API key."}}`))
+	})
+
+	_, err := NewRetriever(c).Query(context.Background(), "q", 5)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Query() error = %v, want *apierror.Error", err)
+	}
+	if apiErr.Retryable() || !strings.HasPrefix(apiErr.Message, "Unauthorized") {
+		t.Errorf("got error %+v", apiErr)
+	}
+}
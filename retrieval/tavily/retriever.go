@@ -0,0 +1,148 @@
+package tavily
+
+import (
+	"context"
+	"fmt"
+	"github.com/coopslarhette/raglib/lib/document"
+	"github.com/coopslarhette/raglib/lib/retrieval/extract"
+	"github.com/coopslarhette/raglib/lib/retrieval/urls"
+)
+
+const (
+	// maxResults is the most results Tavily returns for a search
+	maxResults = 20
+
+	defaultMaxPassageChars = 1500
+	defaultMaxRawPassages  = 5
+
+	// ResultAnswer is the WebReference.ResultType of the document holding Tavily's generated answer
+	ResultAnswer = "answer"
+)
+
+// Options configures the searches a Retriever makes
+type Options struct {
+	// SearchDepth is Basic or Advanced, Tavily defaults to Basic
+	SearchDepth string
+	// Topic is "general" or "news"
+	Topic string
+	// TimeRange is one of "day", "week", "month" or "year"
+	TimeRange      string
+	IncludeDomains []string
+	ExcludeDomains []string
+	// IncludeAnswer adds Tavily's generated answer as the first document, counting towards topK
+	IncludeAnswer bool
+	// IncludeRawContent adds each page's full text, split into passages, after its relevant content
+	IncludeRawContent bool
+	// MaxPassageChars is the size raw content is split into, default 1500
+	MaxPassageChars int
+	// MaxRawPassages caps the raw content passages kept from each page, default 5
+	MaxRawPassages int
+}
+
+// Retriever implements the retrieval.Retriever interface for the Tavily search API, which returns content extracted
+// for LLMs rather than search snippets
+type Retriever struct {
+	client  *Client
+	options Options
+}
+
+func (tr Retriever) Query(ctx context.Context, query string, topK int) ([]document.Document, error) {
+	if topK < 0 {
+		return nil, fmt.Errorf("topK cannot be negative")
+	}
+	// Tavily treats a max_results of 0 as its default of 5
+	if topK == 0 {
+		return nil, nil
+	}
+
+	result, err := tr.client.Search(ctx, SearchRequest{
+		Query:             query,
+		SearchDepth:       tr.options.SearchDepth,
+		Topic:             tr.options.Topic,
+		TimeRange:         tr.options.TimeRange,
+		MaxResults:        min(topK, maxResults),
+		IncludeAnswer:     tr.options.IncludeAnswer,
+		IncludeRawContent: tr.options.IncludeRawContent,
+		IncludeFavicon:    true,
+		IncludeDomains:    tr.options.IncludeDomains,
+		ExcludeDomains:    tr.options.ExcludeDomains,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying Tavily API: %w", err)
+	}
+
+	docs := make([]document.Document, 0, len(result.Results)+1)
+	if result.Answer != "" {
+		docs = append(docs, document.Document{
+			Passages: []document.Passage{{Text: result.Answer}},
+			Corpus:   document.Web,
+			WebReference: &document.WebReference{
+				Title:      "Tavily answer",
+				Blurb:      result.Answer,
+				APISource:  "tavily",
+				ResultType: ResultAnswer,
+			},
+			Title: "Tavily answer",
+		})
+	}
+
+	for _, r := range result.Results {
+		url, err := urls.Parse(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing web page url: %v", err)
+		}
+
+		passages := []document.Passage{{Text: r.Content, Score: r.Score}}
+		if r.RawContent != "" {
+			passages = append(passages, tr.rawPassages(r.RawContent)...)
+		}
+
+		docs = append(docs, document.Document{
+			Passages: passages,
+			Corpus:   document.Web,
+			WebReference: &document.WebReference{
+				Title:         r.Title,
+				Link:          r.URL,
+				DisplayedLink: url.FullDomain(),
+				Blurb:         r.Content,
+				Date:          r.PublishedDate,
+				Favicon:       r.Favicon,
+				APISource:     "tavily",
+			},
+			Title: r.Title,
+		})
+	}
+
+	// Tavily doesn't know the answer takes a place in topK, so the last result makes way for it
+	if len(docs) > topK {
+		docs = docs[:topK]
+	}
+	return docs, nil
+}
+
+// rawPassages splits a page's raw content into passages on paragraph boundaries
+func (tr Retriever) rawPassages(raw string) []document.Passage {
+	maxChars := tr.options.MaxPassageChars
+	if maxChars <= 0 {
+		maxChars = defaultMaxPassageChars
+	}
+	maxPassages := tr.options.MaxRawPassages
+	if maxPassages <= 0 {
+		maxPassages = defaultMaxRawPassages
+	}
+
+	passages := extract.Chunk([]extract.Section{{Text: raw}}, maxChars)
+	if len(passages) > maxPassages {
+		passages = passages[:maxPassages]
+	}
+	return passages
+}
+
+func NewRetriever(client *Client) Retriever {
+	return Retriever{client: client}
+}
+
+// NewRetrieverWithOptions creates a Retriever whose searches use options, e.g. an Advanced search depth or raw content
+func NewRetrieverWithOptions(client *Client, options Options) Retriever {
+	return Retriever{client: client, options: options}
+}